@@ -0,0 +1,23 @@
+package app
+
+import (
+	nativeaudio "github.com/mokiat/lacking-native/audio"
+	"github.com/mokiat/lacking/app"
+)
+
+// AudioDeviceHandler can optionally be implemented by an app.Controller
+// in order to be notified when the audio output device is lost (e.g. the
+// headphones get unplugged) and when playback has been recovered. This
+// allows the application to inform the user.
+type AudioDeviceHandler interface {
+
+	// OnAudioDeviceEvent is called on the loop thread when the
+	// availability of the audio output device changes.
+	OnAudioDeviceEvent(window app.Window, event nativeaudio.DeviceEvent) bool
+}
+
+func (l *loop) onAudioDeviceEvent(event nativeaudio.DeviceEvent) {
+	if handler, ok := l.controller.(AudioDeviceHandler); ok {
+		handler.OnAudioDeviceEvent(l, event)
+	}
+}
@@ -0,0 +1,259 @@
+package app
+
+import (
+	"fmt"
+
+	"github.com/mokiat/lacking-native/app/internal"
+	"github.com/mokiat/lacking/app"
+	"github.com/mokiat/lacking/audio"
+	"github.com/mokiat/lacking/render"
+)
+
+// OpenWindow opens an additional window that is driven by the same loop
+// as the specified parent window. It must be called from the loop thread,
+// for example from within a controller callback.
+//
+// The OpenGL context of the new window shares objects with the one of
+// the main window, so resources created through the RenderAPI can be
+// used in all windows.
+//
+// Only the window-related settings of the specified configuration are
+// taken into account (title, size, size limits, icon and cursor).
+//
+// The specified controller receives the notifications of the new window.
+// The window is destroyed once it is closed or when the application
+// exits, whichever happens first.
+func OpenWindow(parent app.Window, cfg *Config, controller app.Controller) (app.Window, error) {
+	var l *loop
+	switch parent := parent.(type) {
+	case *loop:
+		l = parent
+	case *childWindow:
+		l = parent.loop
+	default:
+		return nil, fmt.Errorf("unsupported parent window type %T", parent)
+	}
+	return l.openChild(cfg, controller)
+}
+
+// renderContextSwitcher is implemented by render APIs that need to be
+// notified when a different OpenGL context is made current.
+type renderContextSwitcher interface {
+	SetCurrentContext(index int)
+	ReleaseContext(index int)
+}
+
+var (
+	_ app.Window            = (*childWindow)(nil)
+	_ internal.EventHandler = (*childWindow)(nil)
+)
+
+// childWindow is an additional window that is driven by a loop.
+type childWindow struct {
+	loop          *loop
+	window        internal.Window
+	controller    app.Controller
+	contextIndex  int
+	title         string
+	cursor        app.Cursor
+	shouldClose   bool
+	shouldDraw    bool
+	cursorVisible bool
+	cursorLocked  bool
+}
+
+func (c *childWindow) Platform() app.Platform {
+	return c.loop.Platform()
+}
+
+func (c *childWindow) Title() string {
+	return c.title
+}
+
+func (c *childWindow) SetTitle(title string) {
+	c.title = title
+	c.window.SetTitle(title)
+}
+
+func (c *childWindow) Size() (int, int) {
+	return c.window.Size()
+}
+
+func (c *childWindow) SetSize(width, height int) {
+	c.window.SetSize(width, height)
+}
+
+func (c *childWindow) FramebufferSize() (int, int) {
+	return c.window.FramebufferSize()
+}
+
+// ContentScale returns the content scale of the window.
+func (c *childWindow) ContentScale() (float32, float32) {
+	return c.window.ContentScale()
+}
+
+func (c *childWindow) Gamepads() [4]app.Gamepad {
+	return c.loop.Gamepads()
+}
+
+func (c *childWindow) Schedule(fn func()) {
+	c.loop.Schedule(fn)
+}
+
+func (c *childWindow) Invalidate() {
+	if !c.shouldDraw {
+		c.shouldDraw = true
+		c.loop.wake()
+	}
+}
+
+func (c *childWindow) CreateCursor(definition app.CursorDefinition) app.Cursor {
+	return c.loop.CreateCursor(definition)
+}
+
+func (c *childWindow) UseCursor(cursor app.Cursor) {
+	if cursor, ok := cursor.(*customCursor); ok {
+		c.window.SetCursor(cursor.cursor)
+	} else {
+		c.window.SetCursor(nil)
+	}
+}
+
+func (c *childWindow) CursorVisible() bool {
+	return c.cursorVisible && !c.cursorLocked
+}
+
+func (c *childWindow) SetCursorVisible(visible bool) {
+	c.cursorVisible = visible
+	c.updateCursorMode()
+}
+
+func (c *childWindow) SetCursorLocked(locked bool) {
+	c.cursorLocked = locked
+	c.updateCursorMode()
+}
+
+func (c *childWindow) RequestCopy(text string) {
+	c.loop.RequestCopy(text)
+}
+
+func (c *childWindow) RequestPaste() {
+	text := c.loop.backend.ClipboardText()
+	c.Schedule(func() {
+		c.controller.OnClipboardEvent(c, app.ClipboardEvent{
+			Text: text,
+		})
+	})
+}
+
+func (c *childWindow) requestCopyData(item ClipboardItem) {
+	c.loop.copyClipboardData(item)
+}
+
+func (c *childWindow) requestPasteData(mimeTypes []string) {
+	c.loop.pasteClipboardData(mimeTypes, func(item ClipboardItem) {
+		deliverClipboardData(c, c.controller, item)
+	})
+}
+
+func (c *childWindow) setTextInputEnabled(enabled bool) bool {
+	return c.window.SetTextInputEnabled(enabled)
+}
+
+func (c *childWindow) setTextInputRect(x, y, width, height int) bool {
+	return c.window.SetTextInputRect(x, y, width, height)
+}
+
+func (c *childWindow) RenderAPI() render.API {
+	return c.loop.RenderAPI()
+}
+
+func (c *childWindow) AudioAPI() audio.API {
+	return c.loop.AudioAPI()
+}
+
+func (c *childWindow) Close() {
+	if !c.shouldClose {
+		c.shouldClose = true
+		c.loop.wake()
+	}
+}
+
+func (c *childWindow) OnRefresh() {
+	c.render()
+}
+
+func (c *childWindow) OnResize(width, height int) {
+	c.controller.OnResize(c, width, height)
+}
+
+func (c *childWindow) OnFramebufferResize(width, height int) {
+	c.controller.OnFramebufferResize(c, width, height)
+}
+
+func (c *childWindow) OnContentScaleChange(scaleX, scaleY float32) {
+	if handler, ok := c.controller.(ContentScaleHandler); ok {
+		handler.OnContentScaleChanged(c, ContentScaleEvent{
+			ScaleX: scaleX,
+			ScaleY: scaleY,
+		})
+	}
+}
+
+func (c *childWindow) OnCloseRequested() {
+	c.shouldClose = c.controller.OnCloseRequested(c)
+}
+
+func (c *childWindow) OnKeyboardEvent(event app.KeyboardEvent) {
+	c.controller.OnKeyboardEvent(c, event)
+}
+
+func (c *childWindow) OnCompositionEvent(action internal.CompositionAction, text string, cursor, selection int) {
+	if handler, ok := c.controller.(CompositionHandler); ok {
+		handler.OnCompositionEvent(c, newCompositionEvent(action, text, cursor, selection))
+	}
+}
+
+func (c *childWindow) OnMouseEvent(event app.MouseEvent) {
+	c.controller.OnMouseEvent(c, event)
+}
+
+// render draws the window using its own OpenGL context and then switches
+// back to the context of the main window.
+func (c *childWindow) render() {
+	c.makeCurrent()
+	c.controller.OnRender(c)
+	c.window.SwapBuffers()
+	c.loop.makeCurrent()
+}
+
+func (c *childWindow) makeCurrent() {
+	c.window.MakeContextCurrent()
+	if switcher, ok := c.loop.renderAPI.(renderContextSwitcher); ok {
+		switcher.SetCurrentContext(c.contextIndex)
+	}
+}
+
+func (c *childWindow) destroy() {
+	c.controller.OnDestroy(c)
+	c.window.SetEventHandler(nil)
+	if c.cursor != nil {
+		c.window.SetCursor(nil)
+		c.cursor.Destroy()
+	}
+	if switcher, ok := c.loop.renderAPI.(renderContextSwitcher); ok {
+		switcher.ReleaseContext(c.contextIndex)
+	}
+	c.window.Destroy()
+}
+
+func (c *childWindow) updateCursorMode() {
+	switch {
+	case c.cursorLocked:
+		c.window.SetCursorMode(internal.CursorModeDisabled)
+	case c.cursorVisible:
+		c.window.SetCursorMode(internal.CursorModeNormal)
+	default:
+		c.window.SetCursorMode(internal.CursorModeHidden)
+	}
+}
@@ -0,0 +1,236 @@
+package app
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/mokiat/lacking/app"
+	"github.com/mokiat/lacking/debug/log"
+)
+
+const (
+	// MIMETypeText is the MIME type of plain text clipboard payloads.
+	MIMETypeText = "text/plain"
+
+	// MIMETypePNG is the MIME type of PNG image clipboard payloads.
+	MIMETypePNG = "image/png"
+)
+
+// ClipboardItem represents a clipboard payload of a specific MIME type.
+type ClipboardItem struct {
+
+	// MIMEType specifies the format of the Data (e.g. "image/png"). Custom
+	// types (e.g. "application/x-editor+json") can be used to exchange
+	// structured data between application instances.
+	MIMEType string
+
+	// Data holds the payload.
+	Data []byte
+}
+
+// ClipboardDataHandler can optionally be implemented by an app.Controller
+// in order to receive the result of a RequestPasteData call.
+type ClipboardDataHandler interface {
+
+	// OnClipboardDataEvent is called on the loop thread with the pasted
+	// payload. It is called right before the OnClipboardEvent method of
+	// the app.Controller, which receives the textual form of the payload.
+	OnClipboardDataEvent(window app.Window, item ClipboardItem) bool
+}
+
+type clipboardWindow interface {
+	requestCopyData(item ClipboardItem)
+	requestPasteData(mimeTypes []string)
+}
+
+// RequestCopyData places the specified payload on the system clipboard.
+//
+// Arbitrary MIME types are supported on Linux when the wl-clipboard
+// (Wayland) or xclip (X11) tools are installed. Otherwise only textual
+// payloads can be copied and other payloads are dropped with a warning.
+func RequestCopyData(window app.Window, item ClipboardItem) {
+	if window, ok := window.(clipboardWindow); ok {
+		window.requestCopyData(item)
+	}
+}
+
+// RequestPasteData requests the contents of the system clipboard in the
+// first of the specified MIME types that is available. If no types are
+// specified, the first available type is used.
+//
+// The result is delivered asynchronously to controllers that implement
+// ClipboardDataHandler, followed by a regular OnClipboardEvent call. The
+// Text of the app.ClipboardEvent holds the payload when it is textual
+// and is empty otherwise. Nothing is delivered if none of the requested
+// types is available.
+func RequestPasteData(window app.Window, mimeTypes ...string) {
+	if window, ok := window.(clipboardWindow); ok {
+		window.requestPasteData(mimeTypes)
+	}
+}
+
+// clipboardProvider gives access to all formats of the system clipboard.
+type clipboardProvider interface {
+
+	// Types returns the MIME types of the current clipboard contents.
+	Types() ([]string, error)
+
+	// Read returns the clipboard contents in the specified MIME type.
+	Read(mimeType string) ([]byte, error)
+
+	// Write replaces the clipboard contents.
+	Write(item ClipboardItem) error
+}
+
+// copyClipboardData places the specified item on the clipboard, falling
+// back to the text clipboard of the windowing backend.
+func (l *loop) copyClipboardData(item ClipboardItem) {
+	if l.clipboard != nil {
+		// External tools may block until they have read all of the data,
+		// so the loop should not wait for them.
+		l.runClipboardJob(func() {
+			if err := l.clipboard.Write(item); err != nil {
+				log.Error("Failed to copy to clipboard: %v", err)
+			}
+		})
+		return
+	}
+	if !isTextMIMEType(item.MIMEType) {
+		log.Warn("Clipboard type %q is not supported on this platform", item.MIMEType)
+		return
+	}
+	l.backend.SetClipboardText(string(item.Data))
+}
+
+// pasteClipboardData reads the clipboard in the first available of the
+// specified MIME types and passes the result to the callback on the
+// loop thread.
+func (l *loop) pasteClipboardData(mimeTypes []string, callback func(item ClipboardItem)) {
+	if l.clipboard == nil {
+		if len(mimeTypes) > 0 && !slices.ContainsFunc(mimeTypes, isTextMIMEType) {
+			return
+		}
+		text := l.backend.ClipboardText()
+		l.Schedule(func() {
+			callback(ClipboardItem{
+				MIMEType: MIMETypeText,
+				Data:     []byte(text),
+			})
+		})
+		return
+	}
+	l.runClipboardJob(func() {
+		available, err := l.clipboard.Types()
+		if err != nil {
+			log.Error("Failed to list clipboard types: %v", err)
+			return
+		}
+		mimeType, ok := selectClipboardType(available, mimeTypes)
+		if !ok {
+			return
+		}
+		data, err := l.clipboard.Read(mimeType)
+		if err != nil {
+			log.Error("Failed to paste from clipboard: %v", err)
+			return
+		}
+		if !strings.Contains(mimeType, "/") {
+			mimeType = MIMETypeText
+		}
+		if !l.trySchedule(func() {
+			callback(ClipboardItem{
+				MIMEType: mimeType,
+				Data:     data,
+			})
+		}) {
+			log.Warn("Dropping clipboard data; task queue is full")
+		}
+	})
+}
+
+// runClipboardJob runs the specified function on the clipboard worker
+// goroutine, starting it if needed. Jobs run one at a time and in the
+// order they were requested, so that concurrent copies cannot race for
+// ownership of the clipboard and a paste observes any preceding copy.
+//
+// The job is dropped if too many jobs are pending, since the loop must
+// not wait for external tools.
+func (l *loop) runClipboardJob(job func()) {
+	if l.clipboardJobs == nil {
+		l.clipboardJobs = make(chan func(), clipboardQueueSize)
+		l.clipboardDone = make(chan struct{})
+		go func(jobs <-chan func(), done chan<- struct{}) {
+			defer close(done)
+			for job := range jobs {
+				job()
+			}
+		}(l.clipboardJobs, l.clipboardDone)
+	}
+	select {
+	case l.clipboardJobs <- job:
+	default:
+		log.Warn("Dropping clipboard request; too many are pending")
+	}
+}
+
+// stopClipboardWorker waits for the pending clipboard jobs to complete
+// and stops the worker goroutine, if it was started.
+func (l *loop) stopClipboardWorker() {
+	if l.clipboardJobs == nil {
+		return
+	}
+	close(l.clipboardJobs)
+	<-l.clipboardDone
+	l.clipboardJobs = nil
+	l.clipboardDone = nil
+}
+
+// deliverClipboardData notifies the controller about a pasted item.
+func deliverClipboardData(window app.Window, controller app.Controller, item ClipboardItem) {
+	if handler, ok := controller.(ClipboardDataHandler); ok {
+		handler.OnClipboardDataEvent(window, item)
+	}
+	var text string
+	if isTextMIMEType(item.MIMEType) {
+		text = string(item.Data)
+	}
+	controller.OnClipboardEvent(window, app.ClipboardEvent{
+		Text: text,
+	})
+}
+
+func selectClipboardType(available, preferred []string) (string, bool) {
+	if len(preferred) == 0 {
+		// Some clipboard owners advertise special targets (e.g. TARGETS)
+		// that do not represent content.
+		for _, mimeType := range available {
+			if strings.Contains(mimeType, "/") {
+				return mimeType, true
+			}
+		}
+		return "", false
+	}
+	for _, mimeType := range preferred {
+		if slices.Contains(available, mimeType) {
+			return mimeType, true
+		}
+		if isTextMIMEType(mimeType) {
+			// Text is often advertised only through legacy X11 targets.
+			for _, legacy := range []string{"UTF8_STRING", "STRING", "TEXT"} {
+				if slices.Contains(available, legacy) {
+					return legacy, true
+				}
+			}
+		}
+	}
+	return "", false
+}
+
+func isTextMIMEType(mimeType string) bool {
+	switch mimeType {
+	case "UTF8_STRING", "STRING", "TEXT":
+		return true
+	default:
+		return strings.HasPrefix(mimeType, "text/")
+	}
+}
@@ -0,0 +1,98 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// clipboardCommandTimeout limits the time that a clipboard tool can take,
+// for example when the owner of the clipboard does not respond.
+const clipboardCommandTimeout = 5 * time.Second
+
+// newClipboardProvider returns a clipboardProvider that uses the
+// wl-clipboard or xclip command-line tools, depending on the display
+// server. It returns nil if the required tools are not installed.
+func newClipboardProvider() clipboardProvider {
+	if os.Getenv("WAYLAND_DISPLAY") != "" {
+		if _, err := exec.LookPath("wl-paste"); err == nil {
+			return &waylandClipboard{}
+		}
+	}
+	if os.Getenv("DISPLAY") != "" {
+		if _, err := exec.LookPath("xclip"); err == nil {
+			return &x11Clipboard{}
+		}
+	}
+	return nil
+}
+
+type waylandClipboard struct{}
+
+func (c *waylandClipboard) Types() ([]string, error) {
+	output, err := runClipboardCommand("wl-paste", "--list-types")
+	if err != nil {
+		// An empty clipboard is reported as an error.
+		return nil, nil
+	}
+	return strings.Fields(string(output)), nil
+}
+
+func (c *waylandClipboard) Read(mimeType string) ([]byte, error) {
+	return runClipboardCommand("wl-paste", "--no-newline", "--type", mimeType)
+}
+
+func (c *waylandClipboard) Write(item ClipboardItem) error {
+	return writeClipboardCommand(item.Data, "wl-copy", "--type", item.MIMEType)
+}
+
+type x11Clipboard struct{}
+
+func (c *x11Clipboard) Types() ([]string, error) {
+	output, err := runClipboardCommand("xclip", "-selection", "clipboard", "-out", "-target", "TARGETS")
+	if err != nil {
+		// An empty clipboard is reported as an error.
+		return nil, nil
+	}
+	return strings.Fields(string(output)), nil
+}
+
+func (c *x11Clipboard) Read(mimeType string) ([]byte, error) {
+	return runClipboardCommand("xclip", "-selection", "clipboard", "-out", "-target", mimeType)
+}
+
+func (c *x11Clipboard) Write(item ClipboardItem) error {
+	return writeClipboardCommand(item.Data, "xclip", "-selection", "clipboard", "-in", "-target", item.MIMEType)
+}
+
+func runClipboardCommand(name string, args ...string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), clipboardCommandTimeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, name, args...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("%s failed: %w (%s)", name, err, strings.TrimSpace(stderr.String()))
+	}
+	return output, nil
+}
+
+// writeClipboardCommand runs a tool that takes ownership of the clipboard.
+// Such tools fork into the background in order to serve the clipboard
+// contents, which is why their output must not be captured; otherwise
+// waiting for it would block until the clipboard changes owners.
+func writeClipboardCommand(input []byte, name string, args ...string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), clipboardCommandTimeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Stdin = bytes.NewReader(input)
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%s failed: %w", name, err)
+	}
+	return nil
+}
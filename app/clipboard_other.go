@@ -0,0 +1,9 @@
+//go:build !linux
+
+package app
+
+// newClipboardProvider returns nil, since only the text clipboard of the
+// windowing backend is supported on this platform.
+func newClipboardProvider() clipboardProvider {
+	return nil
+}
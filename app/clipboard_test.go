@@ -0,0 +1,101 @@
+package app
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestLoopClipboardJobsRunInOrder(t *testing.T) {
+	l, _, _ := newTestLoop(t, &recordingController{})
+	clipboard := &recordingClipboard{}
+	l.clipboard = clipboard
+
+	l.copyClipboardData(ClipboardItem{MIMEType: MIMETypeText, Data: []byte("first")})
+	l.copyClipboardData(ClipboardItem{MIMEType: MIMETypeText, Data: []byte("second")})
+	pasted := make(chan ClipboardItem, 1)
+	l.pasteClipboardData([]string{MIMETypeText}, func(item ClipboardItem) {
+		pasted <- item
+	})
+
+	// The paste result is delivered through the loop tasks.
+	task := <-l.tasks
+	task()
+	item := <-pasted
+	if string(item.Data) != "second" {
+		t.Fatalf("expected paste to observe the last copy, got %q", item.Data)
+	}
+	if writes := clipboard.Writes(); len(writes) != 2 || writes[0] != "first" || writes[1] != "second" {
+		t.Fatalf("unexpected clipboard writes: %v", writes)
+	}
+}
+
+func TestLoopClipboardJobsAreDroppedWhenQueueIsFull(t *testing.T) {
+	l, _, _ := newTestLoop(t, &recordingController{})
+	clipboard := &blockingClipboard{
+		started: make(chan struct{}, 1),
+		release: make(chan struct{}),
+	}
+	l.clipboard = clipboard
+
+	l.copyClipboardData(ClipboardItem{MIMEType: MIMETypeText, Data: []byte("first")})
+	<-clipboard.started
+
+	// The worker is busy, so only clipboardQueueSize jobs can be pending.
+	for range clipboardQueueSize + 1 {
+		l.copyClipboardData(ClipboardItem{MIMEType: MIMETypeText, Data: []byte("next")})
+	}
+	close(clipboard.release)
+
+	l.stopClipboardWorker()
+	if writes := clipboard.Writes(); len(writes) != clipboardQueueSize+1 {
+		t.Fatalf("expected %d clipboard writes, got %d", clipboardQueueSize+1, len(writes))
+	}
+}
+
+type recordingClipboard struct {
+	mu     sync.Mutex
+	item   ClipboardItem
+	writes []string
+}
+
+func (c *recordingClipboard) Types() ([]string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return []string{c.item.MIMEType}, nil
+}
+
+func (c *recordingClipboard) Read(mimeType string) ([]byte, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.item.Data, nil
+}
+
+func (c *recordingClipboard) Write(item ClipboardItem) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.item = item
+	c.writes = append(c.writes, string(item.Data))
+	return nil
+}
+
+func (c *recordingClipboard) Writes() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.writes
+}
+
+// blockingClipboard blocks writes until it is released.
+type blockingClipboard struct {
+	recordingClipboard
+	started chan struct{}
+	release chan struct{}
+}
+
+func (c *blockingClipboard) Write(item ClipboardItem) error {
+	select {
+	case c.started <- struct{}{}:
+	default:
+	}
+	<-c.release
+	return c.recordingClipboard.Write(item)
+}
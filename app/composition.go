@@ -0,0 +1,118 @@
+package app
+
+import (
+	"github.com/mokiat/lacking-native/app/internal"
+	"github.com/mokiat/lacking/app"
+	"github.com/mokiat/lacking/debug/log"
+)
+
+// CompositionAction specifies the stage of an input method composition.
+type CompositionAction int
+
+const (
+	// CompositionActionStart indicates that the user has started
+	// composing text through an input method.
+	CompositionActionStart = CompositionAction(internal.CompositionActionStart)
+
+	// CompositionActionUpdate indicates that the preedit text or the
+	// cursor within it has changed.
+	CompositionActionUpdate = CompositionAction(internal.CompositionActionUpdate)
+
+	// CompositionActionCommit indicates that the composition has ended.
+	CompositionActionCommit = CompositionAction(internal.CompositionActionCommit)
+)
+
+// CompositionEvent represents a change in the text that is being composed
+// through an input method (IME), as is common for CJK languages.
+//
+// Composition is only reported by the SDL2 windowing backend, since GLFW
+// does not expose input method preedit text.
+type CompositionEvent struct {
+
+	// Action specifies the stage of the composition.
+	Action CompositionAction
+
+	// Text holds the preedit text for CompositionActionUpdate and the
+	// committed text for CompositionActionCommit. The committed text is
+	// empty if the composition was cancelled.
+	//
+	// Committed text is additionally reported as app.KeyboardActionType
+	// events, so text fields that do not display preedit text need not
+	// handle composition at all.
+	Text string
+
+	// Cursor holds the position of the cursor within the preedit text,
+	// in characters.
+	Cursor int
+
+	// Selection holds the number of characters after the cursor that are
+	// selected within the preedit text.
+	Selection int
+}
+
+// CompositionHandler can optionally be implemented by an app.Controller
+// in order to receive CompositionEvent notifications.
+//
+// Composition events are only reported by the SDL2 windowing backend,
+// which requires the application to be built with the sdl2 build tag and
+// to be configured with WindowingBackendSDL2. A warning is logged if a
+// controller that implements this interface is used with GLFW.
+type CompositionHandler interface {
+
+	// OnCompositionEvent is called on the loop thread when the text being
+	// composed through an input method changes.
+	OnCompositionEvent(window app.Window, event CompositionEvent) bool
+}
+
+// checkCompositionSupport logs a warning if the specified controller
+// expects composition events that the windowing backend does not report.
+func checkCompositionSupport(backend WindowingBackend, controller app.Controller) {
+	if _, ok := controller.(CompositionHandler); !ok {
+		return
+	}
+	if backend != WindowingBackendSDL2 {
+		log.Warn("Controller implements CompositionHandler but the %s windowing backend does not report composition events", backend)
+	}
+}
+
+type textInputWindow interface {
+	setTextInputEnabled(enabled bool) bool
+	setTextInputRect(x, y, width, height int) bool
+}
+
+// SetTextInputEnabled specifies whether the specified window should
+// receive text input. Applications should enable text input when a text
+// field gains focus and disable it afterwards, so that input methods do
+// not intercept key presses meant for other controls.
+//
+// Text input is enabled by default. It returns false if the window does
+// not support controlling text input, which is the case with the GLFW
+// windowing backend, where text input is always enabled.
+func SetTextInputEnabled(window app.Window, enabled bool) bool {
+	if window, ok := window.(textInputWindow); ok {
+		return window.setTextInputEnabled(enabled)
+	}
+	return false
+}
+
+// SetTextInputRect specifies the area of the focused text field, in
+// window coordinates. Input methods position their candidate window
+// next to this area.
+//
+// It returns false if the window does not support input methods, which
+// is the case with the GLFW windowing backend.
+func SetTextInputRect(window app.Window, x, y, width, height int) bool {
+	if window, ok := window.(textInputWindow); ok {
+		return window.setTextInputRect(x, y, width, height)
+	}
+	return false
+}
+
+func newCompositionEvent(action internal.CompositionAction, text string, cursor, selection int) CompositionEvent {
+	return CompositionEvent{
+		Action:    CompositionAction(action),
+		Text:      text,
+		Cursor:    cursor,
+		Selection: selection,
+	}
+}
@@ -1,6 +1,9 @@
 package app
 
 import (
+	"fmt"
+
+	nativeaudio "github.com/mokiat/lacking-native/audio"
 	"github.com/mokiat/lacking/app"
 	"github.com/mokiat/lacking/util/resource"
 )
@@ -10,18 +13,50 @@ import (
 func NewConfig(title string, width, height int) *Config {
 	return &Config{
 		locator:       resource.NewFileLocator("."),
+		locatorDir:    ".",
 		title:         title,
 		width:         width,
 		height:        height,
 		swapInterval:  1,
 		cursorVisible: true,
 		audioEnabled:  true,
+
+		audioDevice: nativeaudio.DefaultDeviceConfig(),
+
+		glDebugSettings: DefaultGLDebugSettings(),
+	}
+}
+
+// WindowingBackend specifies the library that is used to create the
+// application window and to receive input events.
+type WindowingBackend int
+
+const (
+	// WindowingBackendGLFW uses GLFW. This is the default.
+	WindowingBackendGLFW WindowingBackend = iota
+
+	// WindowingBackendSDL2 uses SDL2, which additionally supports gamepad
+	// haptics. The application needs to be built with the sdl2 build tag
+	// for this backend to be available.
+	WindowingBackendSDL2
+)
+
+// String returns the name of the windowing backend.
+func (b WindowingBackend) String() string {
+	switch b {
+	case WindowingBackendGLFW:
+		return "glfw"
+	case WindowingBackendSDL2:
+		return "sdl2"
+	default:
+		return fmt.Sprintf("unknown(%d)", int(b))
 	}
 }
 
 // Config represents an application window configuration.
 type Config struct {
 	locator       resource.ReadLocator
+	locatorDir    string
 	title         string
 	width         int
 	height        int
@@ -36,6 +71,19 @@ type Config struct {
 	cursor        *app.CursorDefinition
 	icon          string
 	audioEnabled  bool
+	instanceID    string
+
+	windowingBackend WindowingBackend
+	scaleToMonitor   bool
+
+	audioDevice nativeaudio.DeviceConfig
+
+	traceFile string
+	traceGPU  bool
+
+	resourceWatching bool
+
+	glDebugSettings GLDebugSettings
 }
 
 // Title returns the title of the application window.
@@ -48,6 +96,17 @@ func (c *Config) SetTitle(title string) {
 	c.title = title
 }
 
+// SetSize sets the initial size of the window.
+func (c *Config) SetSize(width, height int) {
+	c.width = width
+	c.height = height
+}
+
+// Size returns the initial size of the window.
+func (c *Config) Size() (int, int) {
+	return c.width, c.height
+}
+
 // SetMinSize sets a minimum size for the window.
 // Specifying a non-positive value for any dimension disables this setting.
 func (c *Config) SetMinSize(width, height int) {
@@ -128,6 +187,19 @@ func (c *Config) Fullscreen() bool {
 	return c.fullscreen
 }
 
+// SetScaleToMonitor specifies whether the initial window size should be
+// scaled by the content scale of the monitor, so that the window has the
+// same apparent size on high-DPI displays.
+func (c *Config) SetScaleToMonitor(scale bool) {
+	c.scaleToMonitor = scale
+}
+
+// ScaleToMonitor returns whether the initial window size will be scaled
+// by the content scale of the monitor.
+func (c *Config) ScaleToMonitor() bool {
+	return c.scaleToMonitor
+}
+
 // SetCursorVisible specifies whether the cursor should be
 // displayed when moved over the window.
 func (c *Config) SetCursorVisible(visible bool) {
@@ -167,8 +239,20 @@ func (c *Config) Icon() string {
 
 // SetLocator changes the resource locator that will be used to load
 // app-specific resources (e.g. icon).
+//
+// The directory of a custom locator is not known, which is why resource
+// watching is not available with it. Use SetFileLocator for locators that
+// read from a directory.
 func (c *Config) SetLocator(locator resource.ReadLocator) {
 	c.locator = locator
+	c.locatorDir = ""
+}
+
+// SetFileLocator changes the resource locator to one that loads
+// app-specific resources from the specified directory.
+func (c *Config) SetFileLocator(dir string) {
+	c.locator = resource.NewFileLocator(dir)
+	c.locatorDir = dir
 }
 
 // Locator returns the resource locator that will be used to load
@@ -186,3 +270,102 @@ func (c *Config) AudioEnabled() bool {
 func (c *Config) SetAudioEnabled(enabled bool) {
 	c.audioEnabled = enabled
 }
+
+// SetAudioDevice specifies the audio output device and the format in
+// which audio is sent to it. The devices of the system can be listed
+// with the ListDevices function of the native audio package.
+func (c *Config) SetAudioDevice(config nativeaudio.DeviceConfig) {
+	c.audioDevice = config
+}
+
+// AudioDevice returns the audio output device configuration.
+func (c *Config) AudioDevice() nativeaudio.DeviceConfig {
+	return c.audioDevice
+}
+
+// SetSingleInstance enables single-instance enforcement for the specified
+// id (e.g. a project directory). A second launch with the same id forwards
+// its command-line arguments to the running instance and exits.
+//
+// An empty string value disables single-instance enforcement.
+func (c *Config) SetSingleInstance(id string) {
+	c.instanceID = id
+}
+
+// SingleInstance returns the id that is used for single-instance
+// enforcement. An empty string indicates that the feature is disabled.
+func (c *Config) SingleInstance() string {
+	return c.instanceID
+}
+
+// SetWindowingBackend specifies the library that should be used to create
+// the application window and to receive input events.
+//
+// Input method composition (see CompositionHandler) is only supported by
+// WindowingBackendSDL2.
+func (c *Config) SetWindowingBackend(backend WindowingBackend) {
+	c.windowingBackend = backend
+}
+
+// WindowingBackend returns the library that will be used to create
+// the application window and to receive input events.
+func (c *Config) WindowingBackend() WindowingBackend {
+	return c.windowingBackend
+}
+
+// SetTraceFile specifies a file to which frame and region timings should be
+// written in the Chrome Trace Event format. The resulting file can be
+// opened with Perfetto or chrome://tracing.
+//
+// An empty string value disables tracing.
+func (c *Config) SetTraceFile(path string) {
+	c.traceFile = path
+}
+
+// TraceFile returns the file to which frame timings will be written.
+// An empty string indicates that tracing is disabled.
+func (c *Config) TraceFile() string {
+	return c.traceFile
+}
+
+// SetTraceGPU specifies whether GPU frame timings should be included in
+// the trace as a separate lane. This has an effect only when a trace file
+// is configured.
+func (c *Config) SetTraceGPU(enabled bool) {
+	c.traceGPU = enabled
+}
+
+// TraceGPU returns whether GPU frame timings will be included in the trace.
+func (c *Config) TraceGPU() bool {
+	return c.traceGPU
+}
+
+// SetResourceWatching specifies whether the directory of the file locator
+// should be watched for changes during development. Changes are reported
+// in batches to controllers that implement ResourceHandler, allowing
+// resources to be hot-reloaded.
+//
+// Watching requires the default locator or one that was configured
+// through SetFileLocator.
+func (c *Config) SetResourceWatching(enabled bool) {
+	c.resourceWatching = enabled
+}
+
+// ResourceWatching returns whether the directory of the file locator will
+// be watched for resource changes.
+func (c *Config) ResourceWatching() bool {
+	return c.resourceWatching
+}
+
+// SetGLDebugSettings configures how OpenGL debug messages are filtered
+// and reported. These settings have an effect only when debug logging is
+// enabled for the "opengl" log namespace.
+func (c *Config) SetGLDebugSettings(settings GLDebugSettings) {
+	c.glDebugSettings = settings
+}
+
+// GLDebugSettings returns the settings that will be used to handle
+// OpenGL debug messages.
+func (c *Config) GLDebugSettings() GLDebugSettings {
+	return c.glDebugSettings
+}
@@ -0,0 +1,498 @@
+package app
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	nativeaudio "github.com/mokiat/lacking-native/audio"
+)
+
+// configEnvPrefix is the prefix of all environment variables that are
+// considered by LoadEnv.
+const configEnvPrefix = "LACKING_"
+
+// configOption describes a Config setting that can be loaded from a file,
+// an environment variable or a command-line flag.
+type configOption struct {
+	name  string
+	usage string
+	get   func(c *Config) any
+	set   func(c *Config, value string) error
+}
+
+// envName returns the name of the environment variable for the option.
+func (o configOption) envName() string {
+	return configEnvPrefix + strings.ToUpper(o.name)
+}
+
+// flagName returns the name of the command-line flag for the option.
+func (o configOption) flagName() string {
+	return strings.ReplaceAll(o.name, "_", "-")
+}
+
+// isBool returns whether the option holds a boolean value.
+func (o configOption) isBool(c *Config) bool {
+	_, ok := o.get(c).(bool)
+	return ok
+}
+
+var configOptions = []configOption{
+	{
+		name:  "title",
+		usage: "window title",
+		get:   func(c *Config) any { return c.title },
+		set: func(c *Config, value string) error {
+			c.title = value
+			return nil
+		},
+	},
+	{
+		name:  "width",
+		usage: "initial window width",
+		get:   func(c *Config) any { return c.width },
+		set: func(c *Config, value string) error {
+			return parseConfigInt(value, &c.width)
+		},
+	},
+	{
+		name:  "height",
+		usage: "initial window height",
+		get:   func(c *Config) any { return c.height },
+		set: func(c *Config, value string) error {
+			return parseConfigInt(value, &c.height)
+		},
+	},
+	{
+		name:  "fullscreen",
+		usage: "start in fullscreen mode",
+		get:   func(c *Config) any { return c.fullscreen },
+		set: func(c *Config, value string) error {
+			return parseConfigBool(value, &c.fullscreen)
+		},
+	},
+	{
+		name:  "maximized",
+		usage: "start with a maximized window",
+		get:   func(c *Config) any { return c.maximized },
+		set: func(c *Config, value string) error {
+			return parseConfigBool(value, &c.maximized)
+		},
+	},
+	{
+		name:  "vsync",
+		usage: "enable vertical synchronization",
+		get:   func(c *Config) any { return c.VSync() },
+		set: func(c *Config, value string) error {
+			var vsync bool
+			if err := parseConfigBool(value, &vsync); err != nil {
+				return err
+			}
+			c.SetVSync(vsync)
+			return nil
+		},
+	},
+	{
+		name:  "scale_to_monitor",
+		usage: "scale the initial window size by the monitor content scale",
+		get:   func(c *Config) any { return c.scaleToMonitor },
+		set: func(c *Config, value string) error {
+			return parseConfigBool(value, &c.scaleToMonitor)
+		},
+	},
+	{
+		name:  "cursor_visible",
+		usage: "show the cursor over the window",
+		get:   func(c *Config) any { return c.cursorVisible },
+		set: func(c *Config, value string) error {
+			return parseConfigBool(value, &c.cursorVisible)
+		},
+	},
+	{
+		name:  "audio",
+		usage: "enable audio",
+		get:   func(c *Config) any { return c.audioEnabled },
+		set: func(c *Config, value string) error {
+			return parseConfigBool(value, &c.audioEnabled)
+		},
+	},
+	{
+		name:  "audio_device",
+		usage: "ID of the audio output device (empty for the system default)",
+		get:   func(c *Config) any { return c.audioDevice.DeviceID },
+		set: func(c *Config, value string) error {
+			c.audioDevice.DeviceID = value
+			return nil
+		},
+	},
+	{
+		name:  "audio_sample_rate",
+		usage: "sample rate at which audio is mixed",
+		get:   func(c *Config) any { return c.audioDevice.SampleRate },
+		set: func(c *Config, value string) error {
+			return parseConfigInt(value, &c.audioDevice.SampleRate)
+		},
+	},
+	{
+		name:  "audio_channels",
+		usage: "number of audio output channels",
+		get:   func(c *Config) any { return c.audioDevice.Channels },
+		set: func(c *Config, value string) error {
+			return parseConfigInt(value, &c.audioDevice.Channels)
+		},
+	},
+	{
+		name:  "audio_format",
+		usage: "audio sample format (s16, s32 or f32)",
+		get:   func(c *Config) any { return c.audioDevice.Format.String() },
+		set: func(c *Config, value string) error {
+			format, err := nativeaudio.ParseSampleFormat(value)
+			if err != nil {
+				return err
+			}
+			c.audioDevice.Format = format
+			return nil
+		},
+	},
+	{
+		name:  "audio_period_size",
+		usage: "number of audio frames mixed at once (0 for the backend default)",
+		get:   func(c *Config) any { return c.audioDevice.PeriodSize },
+		set: func(c *Config, value string) error {
+			return parseConfigInt(value, &c.audioDevice.PeriodSize)
+		},
+	},
+	{
+		name:  "windowing_backend",
+		usage: "windowing backend to use (glfw or sdl2)",
+		get:   func(c *Config) any { return c.windowingBackend.String() },
+		set: func(c *Config, value string) error {
+			backend, err := ParseWindowingBackend(value)
+			if err != nil {
+				return err
+			}
+			c.windowingBackend = backend
+			return nil
+		},
+	},
+}
+
+// Load populates the Config from multiple sources. Later sources take
+// precedence over earlier ones, in the following order:
+//
+//  1. the values that are already set on the Config
+//  2. the settings file at the specified path (see LoadFile), if any
+//  3. LACKING_* environment variables (see LoadEnv)
+//  4. command-line flags (see RegisterFlags)
+//
+// A missing settings file is not an error, since it usually gets created
+// only once the user changes a setting. Specifying an empty path skips
+// the file. Specifying a nil flag set skips command-line flags.
+func (c *Config) Load(path string, flags *flag.FlagSet, args []string) error {
+	if path != "" {
+		if err := c.LoadFile(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+	}
+	if err := c.LoadEnv(); err != nil {
+		return err
+	}
+	if flags != nil {
+		c.RegisterFlags(flags)
+		if err := flags.Parse(args); err != nil {
+			return fmt.Errorf("failed to parse flags: %w", err)
+		}
+	}
+	return nil
+}
+
+// LoadFile populates the Config from a settings file at the specified path
+// on the local filesystem, which is where SaveFile writes it. The format is determined by the file extension
+// and can be either JSON (".json") or TOML (".toml"). Settings that are
+// not present in the file are left unchanged.
+//
+// Only top-level keys are supported for TOML files, which is sufficient
+// for all settings. Keys match the names of the command-line flags, with
+// dashes replaced by underscores (e.g. "scale_to_monitor").
+func (c *Config) LoadFile(path string) error {
+	in, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open config file %q: %w", path, err)
+	}
+	defer in.Close()
+
+	var values map[string]string
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".json":
+		values, err = decodeConfigJSON(in)
+	case ".toml":
+		values, err = decodeConfigTOML(in)
+	default:
+		return fmt.Errorf("unsupported config file format %q", ext)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to decode config file %q: %w", path, err)
+	}
+
+	for _, option := range configOptions {
+		if value, ok := values[option.name]; ok {
+			if err := option.set(c, value); err != nil {
+				return fmt.Errorf("invalid value for %q in config file %q: %w", option.name, path, err)
+			}
+		}
+	}
+	return nil
+}
+
+// LoadEnv populates the Config from environment variables. Each setting
+// has a corresponding variable that is named after the setting in upper
+// case and prefixed with LACKING_ (e.g. LACKING_FULLSCREEN=true).
+// Settings whose variables are not set are left unchanged.
+func (c *Config) LoadEnv() error {
+	for _, option := range configOptions {
+		name := option.envName()
+		value, ok := os.LookupEnv(name)
+		if !ok {
+			continue
+		}
+		if err := option.set(c, value); err != nil {
+			return fmt.Errorf("invalid value for environment variable %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// RegisterFlags registers command-line flags for all settings on the
+// specified flag set (e.g. -fullscreen, -width=1920, -vsync=false).
+// The Config is updated during parsing, only for flags that are actually
+// specified, so parsing should happen after other sources are loaded.
+//
+// Applications can register their own flags on the same flag set.
+func (c *Config) RegisterFlags(flags *flag.FlagSet) {
+	for _, option := range configOptions {
+		usage := fmt.Sprintf("%s (default %v)", option.usage, option.get(c))
+		set := func(value string) error {
+			return option.set(c, value)
+		}
+		if option.isBool(c) {
+			flags.BoolFunc(option.flagName(), usage, set)
+		} else {
+			flags.Func(option.flagName(), usage, set)
+		}
+	}
+}
+
+// SaveFile writes the effective settings of the Config to the specified
+// path on the local filesystem, in a format that can be read by LoadFile.
+// This allows settings menus to persist the changes made by the user.
+func (c *Config) SaveFile(path string) error {
+	var data []byte
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".json":
+		data = c.encodeJSON()
+	case ".toml":
+		data = c.encodeTOML()
+	default:
+		return fmt.Errorf("unsupported config file format %q", ext)
+	}
+	if dir := filepath.Dir(path); dir != "." {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return fmt.Errorf("failed to create config directory: %w", err)
+		}
+	}
+	// Writing to a temporary file first ensures that an interrupted write
+	// does not corrupt existing settings.
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write config file: %w", err)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to replace config file: %w", err)
+	}
+	return nil
+}
+
+func (c *Config) encodeJSON() []byte {
+	var builder strings.Builder
+	builder.WriteString("{\n")
+	for i, option := range configOptions {
+		value, _ := json.Marshal(option.get(c))
+		fmt.Fprintf(&builder, "  %q: %s", option.name, value)
+		if i < len(configOptions)-1 {
+			builder.WriteString(",")
+		}
+		builder.WriteString("\n")
+	}
+	builder.WriteString("}\n")
+	return []byte(builder.String())
+}
+
+func (c *Config) encodeTOML() []byte {
+	var builder strings.Builder
+	for _, option := range configOptions {
+		switch value := option.get(c).(type) {
+		case string:
+			fmt.Fprintf(&builder, "%s = %s\n", option.name, encodeTOMLString(value))
+		default:
+			fmt.Fprintf(&builder, "%s = %v\n", option.name, value)
+		}
+	}
+	return []byte(builder.String())
+}
+
+func decodeConfigJSON(in io.Reader) (map[string]string, error) {
+	var raw map[string]any
+	if err := json.NewDecoder(in).Decode(&raw); err != nil {
+		return nil, err
+	}
+	result := make(map[string]string, len(raw))
+	for key, value := range raw {
+		switch value := value.(type) {
+		case string:
+			result[key] = value
+		case float64:
+			result[key] = strconv.FormatFloat(value, 'f', -1, 64)
+		case bool:
+			result[key] = strconv.FormatBool(value)
+		default:
+			return nil, fmt.Errorf("unsupported value type for %q", key)
+		}
+	}
+	return result, nil
+}
+
+func decodeConfigTOML(in io.Reader) (map[string]string, error) {
+	result := make(map[string]string)
+	scanner := bufio.NewScanner(in)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if strings.HasPrefix(line, "[") {
+			return nil, fmt.Errorf("line %d: tables are not supported", lineNumber)
+		}
+		key, rawValue, ok := strings.Cut(line, "=")
+		if !ok {
+			return nil, fmt.Errorf("line %d: expected key = value", lineNumber)
+		}
+		key = strings.Trim(strings.TrimSpace(key), `"`)
+		value, err := decodeTOMLValue(strings.TrimSpace(rawValue))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNumber, err)
+		}
+		result[key] = value
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func decodeTOMLValue(raw string) (string, error) {
+	switch {
+	case strings.HasPrefix(raw, `"`):
+		// Basic strings use the same escape sequences as Go, except for
+		// the \U form, which is rarely used in settings.
+		end := 1
+		for end < len(raw) && raw[end] != '"' {
+			if raw[end] == '\\' {
+				end++
+			}
+			end++
+		}
+		if end >= len(raw) || !isTOMLComment(raw[end+1:]) {
+			return "", fmt.Errorf("malformed string %s", raw)
+		}
+		return strconv.Unquote(raw[:end+1])
+	case strings.HasPrefix(raw, "'"):
+		end := strings.Index(raw[1:], "'") + 1
+		if end == 0 || !isTOMLComment(raw[end+1:]) {
+			return "", fmt.Errorf("malformed string %s", raw)
+		}
+		return raw[1:end], nil
+	default:
+		value, _, _ := strings.Cut(raw, "#")
+		value = strings.ReplaceAll(strings.TrimSpace(value), "_", "")
+		if value == "" {
+			return "", fmt.Errorf("missing value")
+		}
+		return value, nil
+	}
+}
+
+func isTOMLComment(rest string) bool {
+	rest = strings.TrimSpace(rest)
+	return rest == "" || strings.HasPrefix(rest, "#")
+}
+
+func encodeTOMLString(value string) string {
+	if !strings.ContainsFunc(value, func(r rune) bool {
+		return r < 0x20 || r == 0x7F || r == '"' || r == '\\'
+	}) {
+		return `"` + value + `"`
+	}
+	var builder strings.Builder
+	builder.WriteByte('"')
+	for _, r := range value {
+		switch {
+		case r == '"' || r == '\\':
+			builder.WriteByte('\\')
+			builder.WriteRune(r)
+		case r == '\n':
+			builder.WriteString(`\n`)
+		case r == '\t':
+			builder.WriteString(`\t`)
+		case r < 0x20 || r == 0x7F:
+			fmt.Fprintf(&builder, `\u%04X`, r)
+		default:
+			builder.WriteRune(r)
+		}
+	}
+	builder.WriteByte('"')
+	return builder.String()
+}
+
+func parseConfigInt(value string, target *int) error {
+	result, err := strconv.Atoi(strings.TrimSpace(value))
+	if err != nil {
+		return fmt.Errorf("expected an integer: %w", err)
+	}
+	*target = result
+	return nil
+}
+
+func parseConfigBool(value string, target *bool) error {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "1", "t", "true", "yes", "on":
+		*target = true
+	case "0", "f", "false", "no", "off":
+		*target = false
+	default:
+		return fmt.Errorf("expected a boolean, got %q", value)
+	}
+	return nil
+}
+
+// ParseWindowingBackend returns the WindowingBackend with the specified
+// name, as returned by WindowingBackend.String.
+func ParseWindowingBackend(name string) (WindowingBackend, error) {
+	backends := []WindowingBackend{WindowingBackendGLFW, WindowingBackendSDL2}
+	index := slices.IndexFunc(backends, func(backend WindowingBackend) bool {
+		return strings.EqualFold(backend.String(), name)
+	})
+	if index < 0 {
+		return 0, fmt.Errorf("unknown windowing backend %q", name)
+	}
+	return backends[index], nil
+}
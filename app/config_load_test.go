@@ -0,0 +1,38 @@
+package app
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestConfigSaveFileRoundTrip(t *testing.T) {
+	for _, name := range []string{"settings.json", "settings.toml"} {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "config", name)
+
+			saved := NewConfig("Saved", 1920, 1080)
+			saved.SetFullscreen(true)
+			saved.SetVSync(false)
+			if err := saved.SaveFile(path); err != nil {
+				t.Fatalf("failed to save config: %v", err)
+			}
+
+			loaded := NewConfig("Default", 800, 600)
+			if err := loaded.LoadFile(path); err != nil {
+				t.Fatalf("failed to load config: %v", err)
+			}
+			if loaded.Title() != "Saved" {
+				t.Fatalf("expected title %q, got %q", "Saved", loaded.Title())
+			}
+			if width, height := loaded.Size(); width != 1920 || height != 1080 {
+				t.Fatalf("expected size 1920x1080, got %dx%d", width, height)
+			}
+			if !loaded.Fullscreen() {
+				t.Fatalf("expected fullscreen")
+			}
+			if loaded.VSync() {
+				t.Fatalf("expected vsync to be disabled")
+			}
+		})
+	}
+}
@@ -1,9 +1,9 @@
 package app
 
-import "github.com/go-gl/glfw/v3.3/glfw"
+import "github.com/mokiat/lacking-native/app/internal"
 
 type customCursor struct {
-	cursor *glfw.Cursor
+	cursor internal.Cursor
 }
 
 func (c *customCursor) Destroy() {
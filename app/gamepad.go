@@ -4,12 +4,12 @@ import (
 	"math"
 	"time"
 
-	"github.com/go-gl/glfw/v3.3/glfw"
 	"github.com/mokiat/gomath/dprec"
+	"github.com/mokiat/lacking-native/app/internal"
 	"github.com/mokiat/lacking/app"
 )
 
-func newGamepad(joystick glfw.Joystick) *Gamepad {
+func newGamepad(joystick internal.Joystick) *Gamepad {
 	return &Gamepad{
 		joystick: joystick,
 
@@ -23,7 +23,7 @@ func newGamepad(joystick glfw.Joystick) *Gamepad {
 }
 
 type Gamepad struct {
-	joystick glfw.Joystick
+	joystick internal.Joystick
 
 	isDirty     bool
 	isConnected bool
@@ -32,26 +32,7 @@ type Gamepad struct {
 	deadzoneStick   float64
 	deadzoneTrigger float64
 
-	leftStickX        float64
-	leftStickY        float64
-	leftStickButton   bool
-	rightStickX       float64
-	rightStickY       float64
-	rightStickButton  bool
-	leftBumperButton  bool
-	leftTrigger       float64
-	rightBumperButton bool
-	rightTrigger      float64
-	dpadLeftButton    bool
-	dpadRightButton   bool
-	dpadUpButton      bool
-	dpadDownButton    bool
-	actionLeftButton  bool
-	actionRightButton bool
-	actionUpButton    bool
-	actionDownButton  bool
-	forwardButton     bool
-	backButton        bool
+	state internal.GamepadState
 }
 
 var _ app.Gamepad = (*Gamepad)(nil)
@@ -84,106 +65,106 @@ func (g *Gamepad) SetTriggerDeadzone(deadzone float64) {
 
 func (g *Gamepad) LeftStickX() float64 {
 	g.refresh()
-	return deadzoneValue(g.leftStickX, g.deadzoneStick)
+	return deadzoneValue(g.state.LeftStickX, g.deadzoneStick)
 }
 
 func (g *Gamepad) LeftStickY() float64 {
 	g.refresh()
-	return deadzoneValue(g.leftStickY, g.deadzoneStick)
+	return deadzoneValue(g.state.LeftStickY, g.deadzoneStick)
 }
 
 func (g *Gamepad) LeftStickButton() bool {
 	g.refresh()
-	return g.leftStickButton
+	return g.state.LeftStickButton
 }
 
 func (g *Gamepad) RightStickX() float64 {
 	g.refresh()
-	return deadzoneValue(g.rightStickX, g.deadzoneStick)
+	return deadzoneValue(g.state.RightStickX, g.deadzoneStick)
 }
 
 func (g *Gamepad) RightStickY() float64 {
 	g.refresh()
-	return deadzoneValue(g.rightStickY, g.deadzoneStick)
+	return deadzoneValue(g.state.RightStickY, g.deadzoneStick)
 }
 
 func (g *Gamepad) RightStickButton() bool {
 	g.refresh()
-	return g.rightStickButton
+	return g.state.RightStickButton
 }
 
 func (g *Gamepad) LeftTrigger() float64 {
 	g.refresh()
-	return deadzoneValue(g.leftTrigger, g.deadzoneTrigger)
+	return deadzoneValue(g.state.LeftTrigger, g.deadzoneTrigger)
 }
 
 func (g *Gamepad) RightTrigger() float64 {
 	g.refresh()
-	return deadzoneValue(g.rightTrigger, g.deadzoneTrigger)
+	return deadzoneValue(g.state.RightTrigger, g.deadzoneTrigger)
 }
 
 func (g *Gamepad) LeftBumper() bool {
 	g.refresh()
-	return g.leftBumperButton
+	return g.state.LeftBumperButton
 }
 
 func (g *Gamepad) RightBumper() bool {
 	g.refresh()
-	return g.rightBumperButton
+	return g.state.RightBumperButton
 }
 
 func (g *Gamepad) DpadUpButton() bool {
 	g.refresh()
-	return g.dpadUpButton
+	return g.state.DpadUpButton
 }
 
 func (g *Gamepad) DpadDownButton() bool {
 	g.refresh()
-	return g.dpadDownButton
+	return g.state.DpadDownButton
 }
 
 func (g *Gamepad) DpadLeftButton() bool {
 	g.refresh()
-	return g.dpadLeftButton
+	return g.state.DpadLeftButton
 }
 
 func (g *Gamepad) DpadRightButton() bool {
 	g.refresh()
-	return g.dpadRightButton
+	return g.state.DpadRightButton
 }
 
 func (g *Gamepad) ActionUpButton() bool {
 	g.refresh()
-	return g.actionUpButton
+	return g.state.ActionUpButton
 }
 
 func (g *Gamepad) ActionDownButton() bool {
 	g.refresh()
-	return g.actionDownButton
+	return g.state.ActionDownButton
 }
 
 func (g *Gamepad) ActionLeftButton() bool {
 	g.refresh()
-	return g.actionLeftButton
+	return g.state.ActionLeftButton
 }
 
 func (g *Gamepad) ActionRightButton() bool {
 	g.refresh()
-	return g.actionRightButton
+	return g.state.ActionRightButton
 }
 
 func (g *Gamepad) ForwardButton() bool {
 	g.refresh()
-	return g.forwardButton
+	return g.state.ForwardButton
 }
 
 func (g *Gamepad) BackButton() bool {
 	g.refresh()
-	return g.backButton
+	return g.state.BackButton
 }
 
 func (g *Gamepad) Pulse(intensity float64, duration time.Duration) {
-	// Haptic feedback is still not supported by glfw.
+	g.joystick.Rumble(intensity, duration)
 }
 
 func (g *Gamepad) markDirty() {
@@ -202,48 +183,9 @@ func (g *Gamepad) refresh() {
 		g.isSupported = false
 	}
 	if g.isSupported {
-		state := g.joystick.GetGamepadState()
-		g.leftStickX = float64(state.Axes[glfw.AxisLeftX])
-		g.leftStickY = float64(state.Axes[glfw.AxisLeftY])
-		g.leftStickButton = state.Buttons[glfw.ButtonLeftThumb] == glfw.Press
-		g.rightStickX = float64(state.Axes[glfw.AxisRightX])
-		g.rightStickY = float64(state.Axes[glfw.AxisRightY])
-		g.rightStickButton = state.Buttons[glfw.ButtonRightThumb] == glfw.Press
-		g.leftBumperButton = state.Buttons[glfw.ButtonLeftBumper] == glfw.Press
-		g.leftTrigger = float64(state.Axes[glfw.AxisLeftTrigger]+1.0) / 2.0
-		g.rightBumperButton = state.Buttons[glfw.ButtonRightBumper] == glfw.Press
-		g.rightTrigger = float64(state.Axes[glfw.AxisRightTrigger]+1.0) / 2.0
-		g.dpadLeftButton = state.Buttons[glfw.ButtonDpadLeft] == glfw.Press
-		g.dpadRightButton = state.Buttons[glfw.ButtonDpadRight] == glfw.Press
-		g.dpadUpButton = state.Buttons[glfw.ButtonDpadUp] == glfw.Press
-		g.dpadDownButton = state.Buttons[glfw.ButtonDpadDown] == glfw.Press
-		g.actionLeftButton = state.Buttons[glfw.ButtonSquare] == glfw.Press
-		g.actionRightButton = state.Buttons[glfw.ButtonCircle] == glfw.Press
-		g.actionUpButton = state.Buttons[glfw.ButtonTriangle] == glfw.Press
-		g.actionDownButton = state.Buttons[glfw.ButtonCross] == glfw.Press
-		g.forwardButton = state.Buttons[glfw.ButtonStart] == glfw.Press
-		g.backButton = state.Buttons[glfw.ButtonBack] == glfw.Press
+		g.state = g.joystick.GamepadState()
 	} else {
-		g.leftStickX = 0.0
-		g.leftStickY = 0.0
-		g.leftStickButton = false
-		g.rightStickX = 0.0
-		g.rightStickY = 0.0
-		g.rightStickButton = false
-		g.leftBumperButton = false
-		g.leftTrigger = 0.0
-		g.rightBumperButton = false
-		g.rightTrigger = 0.0
-		g.dpadLeftButton = false
-		g.dpadRightButton = false
-		g.dpadUpButton = false
-		g.dpadDownButton = false
-		g.actionLeftButton = false
-		g.actionRightButton = false
-		g.actionUpButton = false
-		g.actionDownButton = false
-		g.forwardButton = false
-		g.backButton = false
+		g.state = internal.GamepadState{}
 	}
 }
 
@@ -0,0 +1,298 @@
+package app
+
+import (
+	"fmt"
+	"runtime/debug"
+	"slices"
+	"strings"
+	"unsafe"
+
+	"github.com/go-gl/gl/v4.1-core/gl"
+)
+
+// GLDebugSource identifies the origin of an OpenGL debug message.
+type GLDebugSource uint32
+
+const (
+	GLDebugSourceAPI            GLDebugSource = gl.DEBUG_SOURCE_API
+	GLDebugSourceWindowSystem   GLDebugSource = gl.DEBUG_SOURCE_WINDOW_SYSTEM
+	GLDebugSourceShaderCompiler GLDebugSource = gl.DEBUG_SOURCE_SHADER_COMPILER
+	GLDebugSourceThirdParty     GLDebugSource = gl.DEBUG_SOURCE_THIRD_PARTY
+	GLDebugSourceApplication    GLDebugSource = gl.DEBUG_SOURCE_APPLICATION
+	GLDebugSourceOther          GLDebugSource = gl.DEBUG_SOURCE_OTHER
+)
+
+// String returns a human-readable representation of the source.
+func (s GLDebugSource) String() string {
+	switch s {
+	case GLDebugSourceAPI:
+		return "api"
+	case GLDebugSourceWindowSystem:
+		return "window-system"
+	case GLDebugSourceShaderCompiler:
+		return "shader-compiler"
+	case GLDebugSourceThirdParty:
+		return "third-party"
+	case GLDebugSourceApplication:
+		return "application"
+	default:
+		return "other"
+	}
+}
+
+// GLDebugType identifies the kind of an OpenGL debug message.
+type GLDebugType uint32
+
+const (
+	GLDebugTypeError              GLDebugType = gl.DEBUG_TYPE_ERROR
+	GLDebugTypeDeprecatedBehavior GLDebugType = gl.DEBUG_TYPE_DEPRECATED_BEHAVIOR
+	GLDebugTypeUndefinedBehavior  GLDebugType = gl.DEBUG_TYPE_UNDEFINED_BEHAVIOR
+	GLDebugTypePortability        GLDebugType = gl.DEBUG_TYPE_PORTABILITY
+	GLDebugTypePerformance        GLDebugType = gl.DEBUG_TYPE_PERFORMANCE
+	GLDebugTypeMarker             GLDebugType = gl.DEBUG_TYPE_MARKER
+	GLDebugTypePushGroup          GLDebugType = gl.DEBUG_TYPE_PUSH_GROUP
+	GLDebugTypePopGroup           GLDebugType = gl.DEBUG_TYPE_POP_GROUP
+	GLDebugTypeOther              GLDebugType = gl.DEBUG_TYPE_OTHER
+)
+
+// String returns a human-readable representation of the type.
+func (t GLDebugType) String() string {
+	switch t {
+	case GLDebugTypeError:
+		return "error"
+	case GLDebugTypeDeprecatedBehavior:
+		return "deprecated"
+	case GLDebugTypeUndefinedBehavior:
+		return "undefined"
+	case GLDebugTypePortability:
+		return "portability"
+	case GLDebugTypePerformance:
+		return "performance"
+	case GLDebugTypeMarker:
+		return "marker"
+	case GLDebugTypePushGroup:
+		return "push-group"
+	case GLDebugTypePopGroup:
+		return "pop-group"
+	default:
+		return "other"
+	}
+}
+
+// GLDebugSeverity indicates the importance of an OpenGL debug message.
+type GLDebugSeverity int
+
+const (
+	GLDebugSeverityNotification GLDebugSeverity = iota
+	GLDebugSeverityLow
+	GLDebugSeverityMedium
+	GLDebugSeverityHigh
+)
+
+// GLDebugSettings controls how OpenGL debug messages are handled. Debug
+// output is only enabled when the "opengl" log namespace has debug level
+// logging enabled.
+type GLDebugSettings struct {
+
+	// MinSeverity specifies the lowest severity that is reported.
+	MinSeverity GLDebugSeverity
+
+	// Sources, when not empty, restricts reporting to messages from the
+	// listed sources.
+	Sources []GLDebugSource
+
+	// Types, when not empty, restricts reporting to messages of the
+	// listed types.
+	Types []GLDebugType
+
+	// IgnoredIDs lists message IDs that are never reported.
+	IgnoredIDs []uint32
+
+	// Deduplicate specifies whether repeated messages should be counted
+	// instead of logged each time. Repetitions are reported with a counter
+	// at exponentially growing intervals.
+	Deduplicate bool
+
+	// PanicOnHighSeverity specifies whether high severity messages should
+	// cause a panic. Since debug output is synchronous, the logged Go stack
+	// trace points at the offending call. The panic itself is raised by the
+	// loop once the current iteration completes, since it is not safe to
+	// unwind through the OpenGL driver.
+	PanicOnHighSeverity bool
+}
+
+// DefaultGLDebugSettings returns the GLDebugSettings that are used when
+// none are configured.
+func DefaultGLDebugSettings() GLDebugSettings {
+	return GLDebugSettings{
+		MinSeverity: GLDebugSeverityNotification,
+		Deduplicate: true,
+	}
+}
+
+func newGLDebugHandler(settings GLDebugSettings) *glDebugHandler {
+	return &glDebugHandler{
+		settings: settings,
+		counts:   make(map[glDebugMessageKey]glDebugMessageCount),
+	}
+}
+
+type glDebugMessageKey struct {
+	source  GLDebugSource
+	msgType GLDebugType
+	id      uint32
+	message string
+}
+
+type glDebugMessageCount struct {
+	severity GLDebugSeverity
+	count    int
+}
+
+// glDebugHandler filters, deduplicates and logs OpenGL debug messages.
+type glDebugHandler struct {
+	settings GLDebugSettings
+	groups   []string
+	counts   map[glDebugMessageKey]glDebugMessageCount
+
+	// failure holds the first high severity message that has not been
+	// reported by TakeFailure yet.
+	failure error
+}
+
+// Install enables debug output on the current context and registers the
+// handler as the debug message callback.
+//
+// Debug output is made synchronous, which means that the callback is
+// invoked on the loop thread. This keeps the state of the handler free of
+// data races and ensures that messages are attributed to the right group.
+func (h *glDebugHandler) Install() {
+	gl.Enable(gl.DEBUG_OUTPUT)
+	gl.Enable(gl.DEBUG_OUTPUT_SYNCHRONOUS)
+	gl.DebugMessageCallback(h.onMessage, gl.PtrOffset(0))
+}
+
+// Uninstall disables debug output on the current context and reports
+// the final counts of repeated messages.
+//
+// The callback stays registered, since clearing it would still go through
+// the go-gl trampoline, but it is no longer invoked once debug output is
+// disabled.
+func (h *glDebugHandler) Uninstall() {
+	gl.Disable(gl.DEBUG_OUTPUT_SYNCHRONOUS)
+	gl.Disable(gl.DEBUG_OUTPUT)
+	for key, entry := range h.counts {
+		if entry.count > 1 {
+			logGLDebugMessage(entry.severity, fmt.Sprintf("[%s/%s #%d] %s (repeated %d times in total)", key.source, key.msgType, key.id, key.message, entry.count))
+		}
+	}
+}
+
+func (h *glDebugHandler) onMessage(source uint32, gltype uint32, id uint32, severity uint32, length int32, message string, userParam unsafe.Pointer) {
+	msgSource := GLDebugSource(source)
+	msgType := GLDebugType(gltype)
+
+	// Groups are tracked regardless of filtering, so that messages can be
+	// attributed to the group that produced them.
+	switch msgType {
+	case GLDebugTypePushGroup:
+		h.groups = append(h.groups, message)
+		return
+	case GLDebugTypePopGroup:
+		if len(h.groups) > 0 {
+			h.groups = h.groups[:len(h.groups)-1]
+		}
+		return
+	}
+
+	msgSeverity := glDebugSeverity(severity)
+	if !h.accepts(msgSource, msgType, id, msgSeverity) {
+		return
+	}
+
+	if h.settings.Deduplicate {
+		key := glDebugMessageKey{
+			source:  msgSource,
+			msgType: msgType,
+			id:      id,
+			message: message,
+		}
+		count := h.counts[key].count + 1
+		h.counts[key] = glDebugMessageCount{
+			severity: msgSeverity,
+			count:    count,
+		}
+		if count > 1 {
+			if count&(count-1) != 0 {
+				// Only powers of two are reported.
+				return
+			}
+			message = fmt.Sprintf("%s (repeated %d times)", message, count)
+		}
+	}
+
+	text := fmt.Sprintf("[%s/%s #%d] %s", msgSource, msgType, id, message)
+	if len(h.groups) > 0 {
+		text = fmt.Sprintf("[%s] %s", strings.Join(h.groups, " > "), text)
+	}
+
+	if msgSeverity == GLDebugSeverityHigh && h.settings.PanicOnHighSeverity {
+		// The callback is invoked from C, so the panic is deferred until
+		// control is back in Go.
+		glLogger.Error("%s\n%s", text, debug.Stack())
+		if h.failure == nil {
+			h.failure = fmt.Errorf("opengl error: %s", text)
+		}
+		return
+	}
+	logGLDebugMessage(msgSeverity, text)
+}
+
+func logGLDebugMessage(severity GLDebugSeverity, text string) {
+	switch severity {
+	case GLDebugSeverityMedium:
+		glLogger.Warn("%s", text)
+	case GLDebugSeverityHigh:
+		glLogger.Error("%s", text)
+	default:
+		glLogger.Debug("%s", text)
+	}
+}
+
+// TakeFailure returns the first high severity message that has been
+// received since the last call, when PanicOnHighSeverity is enabled. It
+// is safe to call on a nil handler.
+func (h *glDebugHandler) TakeFailure() error {
+	if h == nil {
+		return nil
+	}
+	failure := h.failure
+	h.failure = nil
+	return failure
+}
+
+func (h *glDebugHandler) accepts(source GLDebugSource, msgType GLDebugType, id uint32, severity GLDebugSeverity) bool {
+	if severity < h.settings.MinSeverity {
+		return false
+	}
+	if len(h.settings.Sources) > 0 && !slices.Contains(h.settings.Sources, source) {
+		return false
+	}
+	if len(h.settings.Types) > 0 && !slices.Contains(h.settings.Types, msgType) {
+		return false
+	}
+	return !slices.Contains(h.settings.IgnoredIDs, id)
+}
+
+func glDebugSeverity(severity uint32) GLDebugSeverity {
+	switch severity {
+	case gl.DEBUG_SEVERITY_LOW:
+		return GLDebugSeverityLow
+	case gl.DEBUG_SEVERITY_MEDIUM:
+		return GLDebugSeverityMedium
+	case gl.DEBUG_SEVERITY_HIGH:
+		return GLDebugSeverityHigh
+	default:
+		return GLDebugSeverityNotification
+	}
+}
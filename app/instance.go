@@ -0,0 +1,216 @@
+package app
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/mokiat/lacking/app"
+	"github.com/mokiat/lacking/debug/log"
+)
+
+// ErrAlreadyRunning is returned by Run when single-instance enforcement
+// is enabled and another instance is already running. In such cases the
+// command-line arguments of the current process are forwarded to the
+// running instance.
+var ErrAlreadyRunning = errors.New("application instance already running")
+
+// InstanceEvent represents the command-line arguments of a secondary
+// launch of the application that were forwarded to the running instance.
+type InstanceEvent struct {
+
+	// Args holds the command-line arguments (excluding the program name)
+	// of the secondary launch.
+	Args []string
+
+	// Paths holds the absolute paths of all arguments that refer to
+	// existing files or directories.
+	Paths []string
+}
+
+// InstanceHandler can optionally be implemented by an app.Controller
+// in order to receive InstanceEvent notifications.
+type InstanceHandler interface {
+
+	// OnInstanceEvent is called on the loop thread when a secondary launch
+	// of the application has forwarded its arguments. The window is
+	// focused regardless of the returned value, which only indicates
+	// whether the event was handled, as with the event methods of
+	// app.Controller.
+	OnInstanceEvent(window app.Window, event InstanceEvent) bool
+}
+
+const (
+	// instanceConnTimeout limits the time that a forwarded event can
+	// take to be sent or received.
+	instanceConnTimeout = 5 * time.Second
+
+	// instanceDialAttempts specifies how many times a secondary launch
+	// tries to reach the primary instance, which might still be setting
+	// up its socket.
+	instanceDialAttempts = 20
+	instanceDialInterval = 50 * time.Millisecond
+)
+
+// errInstanceLocked indicates that the instance lock is held by another
+// process.
+var errInstanceLocked = errors.New("instance lock is held")
+
+func newInstanceEvent(args []string) InstanceEvent {
+	event := InstanceEvent{
+		Args:  args,
+		Paths: make([]string, 0, len(args)),
+	}
+	for _, arg := range args {
+		if _, err := os.Stat(arg); err != nil {
+			continue
+		}
+		if path, err := filepath.Abs(arg); err == nil {
+			event.Paths = append(event.Paths, path)
+		}
+	}
+	return event
+}
+
+// acquireInstance attempts to become the primary instance for the
+// specified id. If another instance is already running, the specified
+// event is forwarded to it and ErrAlreadyRunning is returned.
+//
+// Where supported, the primary instance holds a lock on a file next to
+// the socket for as long as it is running. This serializes concurrent
+// launches and, since the lock is released when the process exits, it
+// allows a stale socket to be replaced safely.
+func acquireInstance(id string, event InstanceEvent) (*instanceServer, error) {
+	path := instanceSocketPath(id)
+	lock, err := lockInstance(path)
+	if errors.Is(err, errInstanceLocked) {
+		if err := forwardInstanceEvent(path, event); err != nil {
+			return nil, err
+		}
+		return nil, ErrAlreadyRunning
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock instance: %w", err)
+	}
+
+	// The socket, if any, is a leftover from an instance that did not
+	// shut down cleanly.
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		lock.Close()
+		return nil, fmt.Errorf("failed to remove stale instance socket: %w", err)
+	}
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		lock.Close()
+		return nil, fmt.Errorf("failed to create instance socket: %w", err)
+	}
+	return newInstanceServer(listener, lock), nil
+}
+
+func forwardInstanceEvent(path string, event InstanceEvent) error {
+	var (
+		conn net.Conn
+		err  error
+	)
+	for range instanceDialAttempts {
+		conn, err = net.DialTimeout("unix", path, instanceConnTimeout)
+		if err == nil {
+			break
+		}
+		time.Sleep(instanceDialInterval)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to connect to instance: %w", err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(instanceConnTimeout))
+	if err := json.NewEncoder(conn).Encode(event); err != nil {
+		return fmt.Errorf("failed to send instance event: %w", err)
+	}
+	return nil
+}
+
+func instanceSocketPath(id string) string {
+	dir := os.Getenv("XDG_RUNTIME_DIR")
+	if dir == "" {
+		dir = os.TempDir()
+	}
+	hash := sha256.Sum256([]byte(id))
+	return filepath.Join(dir, fmt.Sprintf("lacking-%s.sock", hex.EncodeToString(hash[:8])))
+}
+
+func newInstanceServer(listener net.Listener, lock io.Closer) *instanceServer {
+	return &instanceServer{
+		listener: listener,
+		lock:     lock,
+	}
+}
+
+type instanceServer struct {
+	listener  net.Listener
+	lock      io.Closer
+	wg        sync.WaitGroup
+	closeOnce sync.Once
+}
+
+// Serve starts accepting forwarded events in the background until the
+// server is closed. Each received event is passed to the specified
+// callback from a background goroutine.
+func (s *instanceServer) Serve(callback func(event InstanceEvent)) {
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		for {
+			conn, err := s.listener.Accept()
+			if err != nil {
+				if !errors.Is(err, net.ErrClosed) {
+					log.Error("Failed to accept instance connection: %v", err)
+				}
+				return
+			}
+			s.wg.Add(1)
+			go func() {
+				defer s.wg.Done()
+				s.handle(conn, callback)
+			}()
+		}
+	}()
+}
+
+// Close stops accepting events and waits for the ones that are being
+// received. The callback passed to Serve is not called afterwards.
+func (s *instanceServer) Close() {
+	s.closeOnce.Do(func() {
+		if err := s.listener.Close(); err != nil {
+			log.Error("Failed to close instance socket: %v", err)
+		}
+		s.wg.Wait()
+		if err := s.lock.Close(); err != nil {
+			log.Error("Failed to release instance lock: %v", err)
+		}
+	})
+}
+
+func (s *instanceServer) handle(conn net.Conn, callback func(event InstanceEvent)) {
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(instanceConnTimeout))
+	var event InstanceEvent
+	err := json.NewDecoder(conn).Decode(&event)
+	if errors.Is(err, io.EOF) {
+		// The connection was only used to probe for a running instance.
+		return
+	}
+	if err != nil {
+		log.Error("Failed to decode instance event: %v", err)
+		return
+	}
+	callback(event)
+}
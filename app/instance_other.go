@@ -0,0 +1,26 @@
+//go:build !unix
+
+package app
+
+import (
+	"io"
+	"net"
+)
+
+// lockInstance returns errInstanceLocked if another instance accepts
+// connections on the specified socket. File locks are not used on this
+// platform, which means that concurrent launches are not serialized.
+func lockInstance(socketPath string) (io.Closer, error) {
+	conn, err := net.Dial("unix", socketPath)
+	if err != nil {
+		return nopCloser{}, nil
+	}
+	conn.Close()
+	return nil, errInstanceLocked
+}
+
+type nopCloser struct{}
+
+func (nopCloser) Close() error {
+	return nil
+}
@@ -0,0 +1,57 @@
+//go:build unix
+
+package app
+
+import (
+	"errors"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestAcquireInstanceForwardsToPrimary(t *testing.T) {
+	t.Setenv("XDG_RUNTIME_DIR", t.TempDir())
+
+	primary, err := acquireInstance("test", InstanceEvent{})
+	if err != nil {
+		t.Fatalf("failed to acquire primary instance: %v", err)
+	}
+	defer primary.Close()
+
+	events := make(chan InstanceEvent, 1)
+	primary.Serve(func(event InstanceEvent) {
+		events <- event
+	})
+
+	_, err = acquireInstance("test", InstanceEvent{Args: []string{"scene.dat"}})
+	if !errors.Is(err, ErrAlreadyRunning) {
+		t.Fatalf("expected ErrAlreadyRunning, got %v", err)
+	}
+
+	select {
+	case event := <-events:
+		if !slices.Equal(event.Args, []string{"scene.dat"}) {
+			t.Fatalf("unexpected forwarded args %v", event.Args)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("event was not forwarded")
+	}
+}
+
+func TestAcquireInstanceAfterClose(t *testing.T) {
+	t.Setenv("XDG_RUNTIME_DIR", t.TempDir())
+
+	primary, err := acquireInstance("test", InstanceEvent{})
+	if err != nil {
+		t.Fatalf("failed to acquire primary instance: %v", err)
+	}
+	primary.Serve(func(event InstanceEvent) {})
+	primary.Close()
+	primary.Close()
+
+	next, err := acquireInstance("test", InstanceEvent{})
+	if err != nil {
+		t.Fatalf("failed to acquire instance after close: %v", err)
+	}
+	next.Close()
+}
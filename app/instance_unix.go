@@ -0,0 +1,29 @@
+//go:build unix
+
+package app
+
+import (
+	"errors"
+	"io"
+	"os"
+	"syscall"
+)
+
+// lockInstance acquires an exclusive lock on a file next to the specified
+// socket. It returns errInstanceLocked if another process holds the lock.
+// The lock is released when the returned io.Closer is closed or when the
+// process exits.
+func lockInstance(socketPath string) (io.Closer, error) {
+	file, err := os.OpenFile(socketPath+".lock", os.O_RDWR|os.O_CREATE, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	if err := syscall.Flock(int(file.Fd()), syscall.LOCK_EX|syscall.LOCK_NB); err != nil {
+		file.Close()
+		if errors.Is(err, syscall.EWOULDBLOCK) {
+			return nil, errInstanceLocked
+		}
+		return nil, err
+	}
+	return file, nil
+}
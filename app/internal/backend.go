@@ -0,0 +1,231 @@
+package internal
+
+import (
+	"image"
+	"time"
+
+	"github.com/mokiat/lacking/app"
+)
+
+// Backend represents a windowing library that is used to create windows
+// and OpenGL contexts and to receive input events.
+//
+// All methods, except PostEmptyEvent, need to be called from the thread
+// that created the Backend.
+type Backend interface {
+
+	// CreateWindow creates a new window that has an OpenGL context.
+	CreateWindow(info WindowInfo) (Window, error)
+
+	// DetachCurrentContext releases the OpenGL context that is current
+	// on the calling thread.
+	DetachCurrentContext()
+
+	// SetSwapInterval configures the number of screen updates to wait for
+	// before swapping buffers of the current context.
+	SetSwapInterval(interval int)
+
+	// PollEvents processes all pending events without blocking.
+	PollEvents()
+
+	// WaitEvents blocks until at least one event is available and then
+	// processes all pending events.
+	WaitEvents()
+
+	// PostEmptyEvent wakes up a blocked WaitEvents call. This method can be
+	// called from any goroutine.
+	PostEmptyEvent()
+
+	// ClipboardText returns the text contents of the system clipboard.
+	ClipboardText() string
+
+	// SetClipboardText changes the text contents of the system clipboard.
+	SetClipboardText(text string)
+
+	// CreateCursor creates a new custom cursor from the specified image.
+	CreateCursor(img image.Image, hotspotX, hotspotY int) Cursor
+
+	// Joystick returns the joystick with the specified index.
+	Joystick(index int) Joystick
+
+	// Terminate releases all resources held by the Backend.
+	Terminate()
+}
+
+// WindowInfo describes how a Window should be created.
+type WindowInfo struct {
+	Title  string
+	Width  int
+	Height int
+
+	// MinWidth, MinHeight, MaxWidth and MaxHeight limit the size of the
+	// Window. Each dimension is limited independently and a zero value
+	// leaves it unlimited.
+	MinWidth  int
+	MinHeight int
+	MaxWidth  int
+	MaxHeight int
+
+	Maximized  bool
+	Fullscreen bool
+
+	// ScaleToMonitor specifies whether the size of the Window should be
+	// scaled by the content scale of the monitor it is placed on.
+	ScaleToMonitor bool
+
+	// Share, if specified, is a Window whose OpenGL context should share
+	// objects with the context of the new Window.
+	Share Window
+}
+
+// Window represents a native window.
+type Window interface {
+
+	// SetEventHandler configures the EventHandler that will receive
+	// events for this Window.
+	SetEventHandler(handler EventHandler)
+
+	// SetTitle changes the title of the Window.
+	SetTitle(title string)
+
+	// SetIcon changes the icon of the Window.
+	SetIcon(img image.Image)
+
+	// Size returns the size of the Window in screen coordinates.
+	Size() (int, int)
+
+	// SetSize changes the size of the Window in screen coordinates.
+	SetSize(width, height int)
+
+	// FramebufferSize returns the size of the Window's framebuffer
+	// in pixels.
+	FramebufferSize() (int, int)
+
+	// ContentScale returns the ratio between the current DPI of the
+	// Window and the platform's default DPI.
+	ContentScale() (float32, float32)
+
+	// SetCursor changes the cursor that is displayed when hovering the
+	// Window. Specifying nil restores the default cursor.
+	SetCursor(cursor Cursor)
+
+	// SetCursorMode changes how the cursor behaves over the Window.
+	SetCursorMode(mode CursorMode)
+
+	// SetTextInputEnabled specifies whether the Window should receive text
+	// input, including input method composition. It returns false if the
+	// Window does not support controlling text input.
+	SetTextInputEnabled(enabled bool) bool
+
+	// SetTextInputRect specifies the area, in screen coordinates relative
+	// to the Window, where text is being entered. Input methods use this
+	// to position their candidate window. It returns false if the Window
+	// does not support input methods.
+	SetTextInputRect(x, y, width, height int) bool
+
+	// Focus brings the Window to the front, restoring it if it has been
+	// minimized.
+	Focus()
+
+	// MakeContextCurrent makes the OpenGL context of the Window current
+	// on the calling thread.
+	MakeContextCurrent()
+
+	// SwapBuffers swaps the front and back buffers of the Window.
+	SwapBuffers()
+
+	// Destroy releases the Window and its OpenGL context.
+	Destroy()
+}
+
+// EventHandler receives the events of a Window.
+type EventHandler interface {
+	OnRefresh()
+	OnResize(width, height int)
+	OnFramebufferResize(width, height int)
+	OnContentScaleChange(scaleX, scaleY float32)
+	OnCloseRequested()
+	OnKeyboardEvent(event app.KeyboardEvent)
+	OnCompositionEvent(action CompositionAction, text string, cursor, selection int)
+	OnMouseEvent(event app.MouseEvent)
+}
+
+// CompositionAction specifies the stage of an input method composition.
+type CompositionAction int
+
+const (
+	// CompositionActionStart indicates that a composition has started.
+	CompositionActionStart CompositionAction = iota
+
+	// CompositionActionUpdate indicates that the preedit text or the
+	// cursor within it has changed.
+	CompositionActionUpdate
+
+	// CompositionActionCommit indicates that the composition has ended.
+	// The text is empty if the composition was cancelled.
+	CompositionActionCommit
+)
+
+// CursorMode specifies how the cursor behaves over a Window.
+type CursorMode int
+
+const (
+	// CursorModeNormal shows the cursor and lets it move freely.
+	CursorModeNormal CursorMode = iota
+
+	// CursorModeHidden hides the cursor while it is over the Window.
+	CursorModeHidden
+
+	// CursorModeDisabled hides and locks the cursor to the Window.
+	CursorModeDisabled
+)
+
+// Cursor represents a custom cursor image.
+type Cursor interface {
+	Destroy()
+}
+
+// Joystick represents an input device slot.
+type Joystick interface {
+
+	// Present returns whether a device is connected to this slot.
+	Present() bool
+
+	// IsGamepad returns whether the connected device has a known
+	// gamepad mapping.
+	IsGamepad() bool
+
+	// GamepadState returns the current state of the gamepad.
+	GamepadState() GamepadState
+
+	// Rumble starts a haptic effect with the specified intensity and
+	// duration. It returns false if haptics are not supported.
+	Rumble(intensity float64, duration time.Duration) bool
+}
+
+// GamepadState holds the state of the axes and buttons of a gamepad.
+//
+// Stick axes are in the range [-1.0, 1.0] and trigger axes are in the
+// range [0.0, 1.0].
+type GamepadState struct {
+	LeftStickX        float64
+	LeftStickY        float64
+	LeftStickButton   bool
+	RightStickX       float64
+	RightStickY       float64
+	RightStickButton  bool
+	LeftBumperButton  bool
+	LeftTrigger       float64
+	RightBumperButton bool
+	RightTrigger      float64
+	DpadLeftButton    bool
+	DpadRightButton   bool
+	DpadUpButton      bool
+	DpadDownButton    bool
+	ActionLeftButton  bool
+	ActionRightButton bool
+	ActionUpButton    bool
+	ActionDownButton  bool
+	ForwardButton     bool
+	BackButton        bool
+}
@@ -0,0 +1,300 @@
+package internal
+
+import (
+	"image"
+	"sync"
+	"time"
+)
+
+// NewFakeBackend returns a Backend that does not open any native windows.
+// It is meant to be used for testing the application loop.
+func NewFakeBackend() *FakeBackend {
+	return &FakeBackend{
+		joysticks: [4]*FakeJoystick{{}, {}, {}, {}},
+	}
+}
+
+var _ Backend = (*FakeBackend)(nil)
+
+// FakeBackend is an in-memory implementation of Backend.
+//
+// Native events are simulated by queuing functions through Inject. These
+// are executed during the next PollEvents or WaitEvents call.
+type FakeBackend struct {
+	eventsMU sync.Mutex
+	events   []func()
+	wakeups  int
+
+	windows      []*FakeWindow
+	swapInterval int
+	clipboard    string
+	joysticks    [4]*FakeJoystick
+}
+
+// Inject queues a function that simulates a native event.
+func (b *FakeBackend) Inject(fn func()) {
+	b.eventsMU.Lock()
+	defer b.eventsMU.Unlock()
+	b.events = append(b.events, fn)
+}
+
+// Wakeups returns the number of PostEmptyEvent calls so far.
+func (b *FakeBackend) Wakeups() int {
+	b.eventsMU.Lock()
+	defer b.eventsMU.Unlock()
+	return b.wakeups
+}
+
+// Windows returns all windows that have been created and not destroyed.
+func (b *FakeBackend) Windows() []*FakeWindow {
+	return b.windows
+}
+
+// SwapInterval returns the last configured swap interval.
+func (b *FakeBackend) SwapInterval() int {
+	return b.swapInterval
+}
+
+// FakeJoystick returns the joystick with the specified index, allowing
+// its state to be modified.
+func (b *FakeBackend) FakeJoystick(index int) *FakeJoystick {
+	return b.joysticks[index]
+}
+
+func (b *FakeBackend) CreateWindow(info WindowInfo) (Window, error) {
+	window := &FakeWindow{
+		backend:           b,
+		info:              info,
+		width:             info.Width,
+		height:            info.Height,
+		framebufferWidth:  info.Width,
+		framebufferHeight: info.Height,
+		contentScaleX:     1.0,
+		contentScaleY:     1.0,
+	}
+	b.windows = append(b.windows, window)
+	return window, nil
+}
+
+func (b *FakeBackend) DetachCurrentContext() {}
+
+func (b *FakeBackend) SetSwapInterval(interval int) {
+	b.swapInterval = interval
+}
+
+func (b *FakeBackend) PollEvents() {
+	b.eventsMU.Lock()
+	events := b.events
+	b.events = nil
+	b.eventsMU.Unlock()
+
+	for _, event := range events {
+		event()
+	}
+}
+
+func (b *FakeBackend) WaitEvents() {
+	// A fake backend has no event source to block on.
+	b.PollEvents()
+}
+
+func (b *FakeBackend) PostEmptyEvent() {
+	b.eventsMU.Lock()
+	defer b.eventsMU.Unlock()
+	b.wakeups++
+}
+
+func (b *FakeBackend) ClipboardText() string {
+	return b.clipboard
+}
+
+func (b *FakeBackend) SetClipboardText(text string) {
+	b.clipboard = text
+}
+
+func (b *FakeBackend) CreateCursor(img image.Image, hotspotX, hotspotY int) Cursor {
+	return &FakeCursor{}
+}
+
+func (b *FakeBackend) Joystick(index int) Joystick {
+	return b.joysticks[index]
+}
+
+func (b *FakeBackend) Terminate() {}
+
+var _ Window = (*FakeWindow)(nil)
+
+// FakeWindow is an in-memory implementation of Window.
+type FakeWindow struct {
+	backend *FakeBackend
+	info    WindowInfo
+	handler EventHandler
+
+	title             string
+	width             int
+	height            int
+	framebufferWidth  int
+	framebufferHeight int
+	contentScaleX     float32
+	contentScaleY     float32
+	cursor            Cursor
+	cursorMode        CursorMode
+	focused           bool
+	textInputDisabled bool
+	textInputRect     [4]int
+	swaps             int
+}
+
+// Info returns the WindowInfo that was used to create this window.
+func (w *FakeWindow) Info() WindowInfo {
+	return w.info
+}
+
+// EventHandler returns the configured EventHandler, which can be used
+// to simulate events.
+func (w *FakeWindow) EventHandler() EventHandler {
+	return w.handler
+}
+
+// Title returns the last configured title.
+func (w *FakeWindow) Title() string {
+	return w.title
+}
+
+// CursorMode returns the last configured cursor mode.
+func (w *FakeWindow) CursorMode() CursorMode {
+	return w.cursorMode
+}
+
+// Focused returns whether Focus has been called.
+func (w *FakeWindow) Focused() bool {
+	return w.focused
+}
+
+// TextInputEnabled returns whether text input is enabled.
+func (w *FakeWindow) TextInputEnabled() bool {
+	return !w.textInputDisabled
+}
+
+// TextInputRect returns the last configured text input area.
+func (w *FakeWindow) TextInputRect() (int, int, int, int) {
+	return w.textInputRect[0], w.textInputRect[1], w.textInputRect[2], w.textInputRect[3]
+}
+
+// Swaps returns the number of SwapBuffers calls so far.
+func (w *FakeWindow) Swaps() int {
+	return w.swaps
+}
+
+// SetFramebufferSize changes the simulated framebuffer size.
+func (w *FakeWindow) SetFramebufferSize(width, height int) {
+	w.framebufferWidth = width
+	w.framebufferHeight = height
+}
+
+// SetContentScale changes the simulated content scale.
+func (w *FakeWindow) SetContentScale(scaleX, scaleY float32) {
+	w.contentScaleX = scaleX
+	w.contentScaleY = scaleY
+}
+
+func (w *FakeWindow) SetEventHandler(handler EventHandler) {
+	w.handler = handler
+}
+
+func (w *FakeWindow) SetTitle(title string) {
+	w.title = title
+}
+
+func (w *FakeWindow) SetIcon(img image.Image) {}
+
+func (w *FakeWindow) Size() (int, int) {
+	return w.width, w.height
+}
+
+func (w *FakeWindow) SetSize(width, height int) {
+	w.width = width
+	w.height = height
+}
+
+func (w *FakeWindow) FramebufferSize() (int, int) {
+	return w.framebufferWidth, w.framebufferHeight
+}
+
+func (w *FakeWindow) ContentScale() (float32, float32) {
+	return w.contentScaleX, w.contentScaleY
+}
+
+func (w *FakeWindow) SetCursor(cursor Cursor) {
+	w.cursor = cursor
+}
+
+func (w *FakeWindow) SetCursorMode(mode CursorMode) {
+	w.cursorMode = mode
+}
+
+func (w *FakeWindow) SetTextInputEnabled(enabled bool) bool {
+	w.textInputDisabled = !enabled
+	return true
+}
+
+func (w *FakeWindow) SetTextInputRect(x, y, width, height int) bool {
+	w.textInputRect = [4]int{x, y, width, height}
+	return true
+}
+
+func (w *FakeWindow) Focus() {
+	w.focused = true
+}
+
+func (w *FakeWindow) MakeContextCurrent() {}
+
+func (w *FakeWindow) SwapBuffers() {
+	w.swaps++
+}
+
+func (w *FakeWindow) Destroy() {
+	windows := w.backend.windows[:0]
+	for _, window := range w.backend.windows {
+		if window != w {
+			windows = append(windows, window)
+		}
+	}
+	w.backend.windows = windows
+}
+
+// FakeCursor is an in-memory implementation of Cursor.
+type FakeCursor struct {
+	Destroyed bool
+}
+
+func (c *FakeCursor) Destroy() {
+	c.Destroyed = true
+}
+
+var _ Joystick = (*FakeJoystick)(nil)
+
+// FakeJoystick is an in-memory implementation of Joystick.
+type FakeJoystick struct {
+	Connected bool
+	Gamepad   bool
+	State     GamepadState
+	Rumbles   []time.Duration
+}
+
+func (j *FakeJoystick) Present() bool {
+	return j.Connected
+}
+
+func (j *FakeJoystick) IsGamepad() bool {
+	return j.Connected && j.Gamepad
+}
+
+func (j *FakeJoystick) GamepadState() GamepadState {
+	return j.State
+}
+
+func (j *FakeJoystick) Rumble(intensity float64, duration time.Duration) bool {
+	j.Rumbles = append(j.Rumbles, duration)
+	return true
+}
@@ -0,0 +1,428 @@
+package internal
+
+import (
+	"fmt"
+	"image"
+	"time"
+
+	"github.com/go-gl/glfw/v3.3/glfw"
+	"github.com/mokiat/lacking/app"
+)
+
+// NewGLFWBackend initializes GLFW and returns a Backend that uses it.
+func NewGLFWBackend() (Backend, error) {
+	if err := glfw.Init(); err != nil {
+		return nil, fmt.Errorf("failed to initialize glfw: %w", err)
+	}
+	return &glfwBackend{
+		joysticks: [4]*glfwJoystick{
+			{joystick: glfw.Joystick1},
+			{joystick: glfw.Joystick2},
+			{joystick: glfw.Joystick3},
+			{joystick: glfw.Joystick4},
+		},
+	}, nil
+}
+
+type glfwBackend struct {
+	joysticks [4]*glfwJoystick
+}
+
+func (b *glfwBackend) CreateWindow(info WindowInfo) (Window, error) {
+	var (
+		windowWidth  = info.Width
+		windowHeight = info.Height
+		monitor      *glfw.Monitor
+	)
+	if info.Fullscreen {
+		monitor = glfw.GetPrimaryMonitor()
+		videoMode := monitor.GetVideoMode()
+		windowWidth = videoMode.Width
+		windowHeight = videoMode.Height
+	}
+	glfw.DefaultWindowHints()
+	glfw.WindowHint(glfw.ContextVersionMajor, 4)
+	glfw.WindowHint(glfw.ContextVersionMinor, 1)
+	glfw.WindowHint(glfw.OpenGLProfile, glfw.OpenGLCoreProfile)
+	glfw.WindowHint(glfw.OpenGLForwardCompatible, glfw.True)
+	glfw.WindowHint(glfw.SRGBCapable, glfw.True)
+	if info.Maximized {
+		glfw.WindowHint(glfw.Maximized, glfw.True)
+	}
+	if info.ScaleToMonitor {
+		glfw.WindowHint(glfw.ScaleToMonitor, glfw.True)
+	}
+
+	var share *glfw.Window
+	if shareWindow, ok := info.Share.(*glfwWindow); ok {
+		share = shareWindow.window
+	}
+
+	window, err := glfw.CreateWindow(windowWidth, windowHeight, info.Title, monitor, share)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create glfw window: %w", err)
+	}
+
+	if info.MinWidth > 0 || info.MaxWidth > 0 || info.MinHeight > 0 || info.MaxHeight > 0 {
+		window.SetSizeLimits(
+			glfwSizeLimit(info.MinWidth),
+			glfwSizeLimit(info.MinHeight),
+			glfwSizeLimit(info.MaxWidth),
+			glfwSizeLimit(info.MaxHeight),
+		)
+	}
+
+	result := &glfwWindow{
+		window: window,
+	}
+	window.SetRefreshCallback(result.onGLFWRefresh)
+	window.SetSizeCallback(result.onGLFWSize)
+	window.SetFramebufferSizeCallback(result.onGLFWFramebufferSize)
+	window.SetContentScaleCallback(result.onGLFWContentScale)
+	window.SetCloseCallback(result.onGLFWClose)
+	window.SetKeyCallback(result.onGLFWKey)
+	window.SetCharCallback(result.onGLFWChar)
+	window.SetCursorPosCallback(result.onGLFWCursorPos)
+	window.SetCursorEnterCallback(result.onGLFWCursorEnter)
+	window.SetMouseButtonCallback(result.onGLFWMouseButton)
+	window.SetScrollCallback(result.onGLFWScroll)
+	window.SetDropCallback(result.onGLFWMouseDrop)
+	return result, nil
+}
+
+func (b *glfwBackend) DetachCurrentContext() {
+	glfw.DetachCurrentContext()
+}
+
+func (b *glfwBackend) SetSwapInterval(interval int) {
+	glfw.SwapInterval(interval)
+}
+
+func (b *glfwBackend) PollEvents() {
+	glfw.PollEvents()
+}
+
+func (b *glfwBackend) WaitEvents() {
+	glfw.WaitEvents()
+}
+
+func (b *glfwBackend) PostEmptyEvent() {
+	glfw.PostEmptyEvent()
+}
+
+func (b *glfwBackend) ClipboardText() string {
+	return glfw.GetClipboardString()
+}
+
+func (b *glfwBackend) SetClipboardText(text string) {
+	glfw.SetClipboardString(text)
+}
+
+func (b *glfwBackend) CreateCursor(img image.Image, hotspotX, hotspotY int) Cursor {
+	return &glfwCursor{
+		cursor: glfw.CreateCursor(img, hotspotX, hotspotY),
+	}
+}
+
+func (b *glfwBackend) Joystick(index int) Joystick {
+	return b.joysticks[index]
+}
+
+func (b *glfwBackend) Terminate() {
+	glfw.Terminate()
+}
+
+type glfwWindow struct {
+	window  *glfw.Window
+	handler EventHandler
+}
+
+func (w *glfwWindow) SetEventHandler(handler EventHandler) {
+	w.handler = handler
+}
+
+func (w *glfwWindow) SetTitle(title string) {
+	w.window.SetTitle(title)
+}
+
+func (w *glfwWindow) SetIcon(img image.Image) {
+	w.window.SetIcon([]image.Image{img})
+}
+
+func (w *glfwWindow) Size() (int, int) {
+	return w.window.GetSize()
+}
+
+func (w *glfwWindow) SetSize(width, height int) {
+	w.window.SetSize(width, height)
+}
+
+func (w *glfwWindow) FramebufferSize() (int, int) {
+	return w.window.GetFramebufferSize()
+}
+
+func (w *glfwWindow) ContentScale() (float32, float32) {
+	return w.window.GetContentScale()
+}
+
+func (w *glfwWindow) SetCursor(cursor Cursor) {
+	if cursor, ok := cursor.(*glfwCursor); ok {
+		w.window.SetCursor(cursor.cursor)
+	} else {
+		w.window.SetCursor(nil)
+	}
+}
+
+func (w *glfwWindow) SetCursorMode(mode CursorMode) {
+	switch mode {
+	case CursorModeDisabled:
+		w.window.SetInputMode(glfw.CursorMode, glfw.CursorDisabled)
+	case CursorModeHidden:
+		w.window.SetInputMode(glfw.CursorMode, glfw.CursorHidden)
+	default:
+		w.window.SetInputMode(glfw.CursorMode, glfw.CursorNormal)
+	}
+}
+
+func (w *glfwWindow) SetTextInputEnabled(enabled bool) bool {
+	// GLFW 3.3 has no input method support; characters are always
+	// reported once committed.
+	return false
+}
+
+func (w *glfwWindow) SetTextInputRect(x, y, width, height int) bool {
+	// GLFW 3.3 has no input method support.
+	return false
+}
+
+func (w *glfwWindow) Focus() {
+	if w.window.GetAttrib(glfw.Iconified) == glfw.True {
+		w.window.Restore()
+	}
+	w.window.Focus()
+}
+
+func (w *glfwWindow) MakeContextCurrent() {
+	w.window.MakeContextCurrent()
+}
+
+func (w *glfwWindow) SwapBuffers() {
+	w.window.SwapBuffers()
+}
+
+func (w *glfwWindow) Destroy() {
+	w.window.Destroy()
+}
+
+func (w *glfwWindow) onGLFWRefresh(_ *glfw.Window) {
+	if w.handler != nil {
+		w.handler.OnRefresh()
+	}
+}
+
+func (w *glfwWindow) onGLFWSize(_ *glfw.Window, width int, height int) {
+	if w.handler != nil {
+		w.handler.OnResize(width, height)
+	}
+}
+
+func (w *glfwWindow) onGLFWFramebufferSize(_ *glfw.Window, width int, height int) {
+	if w.handler != nil {
+		w.handler.OnFramebufferResize(width, height)
+	}
+}
+
+func (w *glfwWindow) onGLFWContentScale(_ *glfw.Window, scaleX float32, scaleY float32) {
+	if w.handler != nil {
+		w.handler.OnContentScaleChange(scaleX, scaleY)
+	}
+}
+
+func (w *glfwWindow) onGLFWClose(_ *glfw.Window) {
+	w.window.SetShouldClose(false)
+	if w.handler != nil {
+		w.handler.OnCloseRequested()
+	}
+}
+
+func (w *glfwWindow) onGLFWKey(_ *glfw.Window, key glfw.Key, scancode int, action glfw.Action, mods glfw.ModifierKey) {
+	if w.handler == nil {
+		return
+	}
+	eventType, ok := glfwKeyboardActionMapping[action]
+	if !ok {
+		return
+	}
+	keyCode, ok := glfwKeyboardKeyMapping[key]
+	if !ok {
+		return
+	}
+	w.handler.OnKeyboardEvent(app.KeyboardEvent{
+		Action: eventType,
+		Code:   keyCode,
+	})
+}
+
+func (w *glfwWindow) onGLFWChar(_ *glfw.Window, char rune) {
+	if w.handler == nil {
+		return
+	}
+	w.handler.OnKeyboardEvent(app.KeyboardEvent{
+		Action:    app.KeyboardActionType,
+		Character: char,
+	})
+}
+
+func (w *glfwWindow) onGLFWCursorPos(_ *glfw.Window, xpos float64, ypos float64) {
+	if w.handler == nil {
+		return
+	}
+	w.handler.OnMouseEvent(app.MouseEvent{
+		Index:  0,
+		X:      int(xpos),
+		Y:      int(ypos),
+		Action: app.MouseActionMove,
+	})
+}
+
+func (w *glfwWindow) onGLFWCursorEnter(_ *glfw.Window, entered bool) {
+	if w.handler == nil {
+		return
+	}
+	var eventType app.MouseAction
+	if entered {
+		eventType = app.MouseActionEnter
+	} else {
+		eventType = app.MouseActionLeave
+	}
+	xpos, ypos := w.window.GetCursorPos()
+	w.handler.OnMouseEvent(app.MouseEvent{
+		Index:  0,
+		X:      int(xpos),
+		Y:      int(ypos),
+		Action: eventType,
+	})
+}
+
+func (w *glfwWindow) onGLFWMouseButton(_ *glfw.Window, button glfw.MouseButton, action glfw.Action, mods glfw.ModifierKey) {
+	if w.handler == nil {
+		return
+	}
+	xpos, ypos := w.window.GetCursorPos()
+	var eventType app.MouseAction
+	switch action {
+	case glfw.Press:
+		eventType = app.MouseActionDown
+	case glfw.Release:
+		eventType = app.MouseActionUp
+	}
+	var eventButton app.MouseButton
+	switch button {
+	case glfw.MouseButton1:
+		eventButton = app.MouseButtonLeft
+	case glfw.MouseButton2:
+		eventButton = app.MouseButtonRight
+	case glfw.MouseButton3:
+		eventButton = app.MouseButtonMiddle
+	}
+	w.handler.OnMouseEvent(app.MouseEvent{
+		Index:  0,
+		X:      int(xpos),
+		Y:      int(ypos),
+		Action: eventType,
+		Button: eventButton,
+	})
+}
+
+func (w *glfwWindow) onGLFWScroll(_ *glfw.Window, xoff float64, yoff float64) {
+	if w.handler == nil {
+		return
+	}
+	xpos, ypos := w.window.GetCursorPos()
+	w.handler.OnMouseEvent(app.MouseEvent{
+		Index:   0,
+		X:       int(xpos),
+		Y:       int(ypos),
+		Action:  app.MouseActionScroll,
+		ScrollX: xoff * 20.0,
+		ScrollY: yoff * 20.0,
+	})
+}
+
+func (w *glfwWindow) onGLFWMouseDrop(_ *glfw.Window, names []string) {
+	if w.handler == nil {
+		return
+	}
+	xpos, ypos := w.window.GetCursorPos()
+	w.handler.OnMouseEvent(app.MouseEvent{
+		Index:  0,
+		X:      int(xpos),
+		Y:      int(ypos),
+		Action: app.MouseActionDrop,
+		Payload: app.FilepathPayload{
+			Paths: names,
+		},
+	})
+}
+
+type glfwCursor struct {
+	cursor *glfw.Cursor
+}
+
+func (c *glfwCursor) Destroy() {
+	c.cursor.Destroy()
+	c.cursor = nil
+}
+
+type glfwJoystick struct {
+	joystick glfw.Joystick
+}
+
+func (j *glfwJoystick) Present() bool {
+	return j.joystick.Present()
+}
+
+func (j *glfwJoystick) IsGamepad() bool {
+	return j.joystick.IsGamepad()
+}
+
+func (j *glfwJoystick) GamepadState() GamepadState {
+	state := j.joystick.GetGamepadState()
+	if state == nil {
+		return GamepadState{}
+	}
+	return GamepadState{
+		LeftStickX:        float64(state.Axes[glfw.AxisLeftX]),
+		LeftStickY:        float64(state.Axes[glfw.AxisLeftY]),
+		LeftStickButton:   state.Buttons[glfw.ButtonLeftThumb] == glfw.Press,
+		RightStickX:       float64(state.Axes[glfw.AxisRightX]),
+		RightStickY:       float64(state.Axes[glfw.AxisRightY]),
+		RightStickButton:  state.Buttons[glfw.ButtonRightThumb] == glfw.Press,
+		LeftBumperButton:  state.Buttons[glfw.ButtonLeftBumper] == glfw.Press,
+		LeftTrigger:       float64(state.Axes[glfw.AxisLeftTrigger]+1.0) / 2.0,
+		RightBumperButton: state.Buttons[glfw.ButtonRightBumper] == glfw.Press,
+		RightTrigger:      float64(state.Axes[glfw.AxisRightTrigger]+1.0) / 2.0,
+		DpadLeftButton:    state.Buttons[glfw.ButtonDpadLeft] == glfw.Press,
+		DpadRightButton:   state.Buttons[glfw.ButtonDpadRight] == glfw.Press,
+		DpadUpButton:      state.Buttons[glfw.ButtonDpadUp] == glfw.Press,
+		DpadDownButton:    state.Buttons[glfw.ButtonDpadDown] == glfw.Press,
+		ActionLeftButton:  state.Buttons[glfw.ButtonSquare] == glfw.Press,
+		ActionRightButton: state.Buttons[glfw.ButtonCircle] == glfw.Press,
+		ActionUpButton:    state.Buttons[glfw.ButtonTriangle] == glfw.Press,
+		ActionDownButton:  state.Buttons[glfw.ButtonCross] == glfw.Press,
+		ForwardButton:     state.Buttons[glfw.ButtonStart] == glfw.Press,
+		BackButton:        state.Buttons[glfw.ButtonBack] == glfw.Press,
+	}
+}
+
+func (j *glfwJoystick) Rumble(intensity float64, duration time.Duration) bool {
+	// Haptic feedback is still not supported by glfw.
+	return false
+}
+
+func glfwSizeLimit(value int) int {
+	if value <= 0 {
+		return glfw.DontCare
+	}
+	return value
+}
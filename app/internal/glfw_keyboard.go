@@ -0,0 +1,104 @@
+package internal
+
+import (
+	"github.com/go-gl/glfw/v3.3/glfw"
+
+	"github.com/mokiat/lacking/app"
+)
+
+var (
+	glfwKeyboardActionMapping map[glfw.Action]app.KeyboardAction
+	glfwKeyboardKeyMapping    map[glfw.Key]app.KeyCode
+)
+
+func init() {
+	glfwKeyboardActionMapping = make(map[glfw.Action]app.KeyboardAction)
+	glfwKeyboardActionMapping[glfw.Press] = app.KeyboardActionDown
+	glfwKeyboardActionMapping[glfw.Release] = app.KeyboardActionUp
+	glfwKeyboardActionMapping[glfw.Repeat] = app.KeyboardActionRepeat
+
+	glfwKeyboardKeyMapping = make(map[glfw.Key]app.KeyCode)
+	glfwKeyboardKeyMapping[glfw.KeyEscape] = app.KeyCodeEscape
+	glfwKeyboardKeyMapping[glfw.KeyEnter] = app.KeyCodeEnter
+	glfwKeyboardKeyMapping[glfw.KeySpace] = app.KeyCodeSpace
+	glfwKeyboardKeyMapping[glfw.KeyTab] = app.KeyCodeTab
+	glfwKeyboardKeyMapping[glfw.KeyCapsLock] = app.KeyCodeCaps
+	glfwKeyboardKeyMapping[glfw.KeyLeftShift] = app.KeyCodeLeftShift
+	glfwKeyboardKeyMapping[glfw.KeyRightShift] = app.KeyCodeRightShift
+	glfwKeyboardKeyMapping[glfw.KeyLeftControl] = app.KeyCodeLeftControl
+	glfwKeyboardKeyMapping[glfw.KeyRightControl] = app.KeyCodeRightControl
+	glfwKeyboardKeyMapping[glfw.KeyLeftAlt] = app.KeyCodeLeftAlt
+	glfwKeyboardKeyMapping[glfw.KeyRightAlt] = app.KeyCodeRightAlt
+	glfwKeyboardKeyMapping[glfw.KeyLeftSuper] = app.KeyCodeLeftSuper
+	glfwKeyboardKeyMapping[glfw.KeyRightSuper] = app.KeyCodeRightSuper
+	glfwKeyboardKeyMapping[glfw.KeyBackspace] = app.KeyCodeBackspace
+	glfwKeyboardKeyMapping[glfw.KeyInsert] = app.KeyCodeInsert
+	glfwKeyboardKeyMapping[glfw.KeyDelete] = app.KeyCodeDelete
+	glfwKeyboardKeyMapping[glfw.KeyHome] = app.KeyCodeHome
+	glfwKeyboardKeyMapping[glfw.KeyEnd] = app.KeyCodeEnd
+	glfwKeyboardKeyMapping[glfw.KeyPageUp] = app.KeyCodePageUp
+	glfwKeyboardKeyMapping[glfw.KeyPageDown] = app.KeyCodePageDown
+	glfwKeyboardKeyMapping[glfw.KeyLeft] = app.KeyCodeArrowLeft
+	glfwKeyboardKeyMapping[glfw.KeyRight] = app.KeyCodeArrowRight
+	glfwKeyboardKeyMapping[glfw.KeyUp] = app.KeyCodeArrowUp
+	glfwKeyboardKeyMapping[glfw.KeyDown] = app.KeyCodeArrowDown
+	glfwKeyboardKeyMapping[glfw.KeyMinus] = app.KeyCodeMinus
+	glfwKeyboardKeyMapping[glfw.KeyEqual] = app.KeyCodeEqual
+	glfwKeyboardKeyMapping[glfw.KeyLeftBracket] = app.KeyCodeLeftBracket
+	glfwKeyboardKeyMapping[glfw.KeyRightBracket] = app.KeyCodeRightBracket
+	glfwKeyboardKeyMapping[glfw.KeySemicolon] = app.KeyCodeSemicolon
+	glfwKeyboardKeyMapping[glfw.KeyComma] = app.KeyCodeComma
+	glfwKeyboardKeyMapping[glfw.KeyPeriod] = app.KeyCodePeriod
+	glfwKeyboardKeyMapping[glfw.KeySlash] = app.KeyCodeSlash
+	glfwKeyboardKeyMapping[glfw.KeyBackslash] = app.KeyCodeBackslash
+	glfwKeyboardKeyMapping[glfw.KeyApostrophe] = app.KeyCodeApostrophe
+	glfwKeyboardKeyMapping[glfw.KeyGraveAccent] = app.KeyCodeGraveAccent
+	glfwKeyboardKeyMapping[glfw.KeyA] = app.KeyCodeA
+	glfwKeyboardKeyMapping[glfw.KeyB] = app.KeyCodeB
+	glfwKeyboardKeyMapping[glfw.KeyC] = app.KeyCodeC
+	glfwKeyboardKeyMapping[glfw.KeyD] = app.KeyCodeD
+	glfwKeyboardKeyMapping[glfw.KeyE] = app.KeyCodeE
+	glfwKeyboardKeyMapping[glfw.KeyF] = app.KeyCodeF
+	glfwKeyboardKeyMapping[glfw.KeyG] = app.KeyCodeG
+	glfwKeyboardKeyMapping[glfw.KeyH] = app.KeyCodeH
+	glfwKeyboardKeyMapping[glfw.KeyI] = app.KeyCodeI
+	glfwKeyboardKeyMapping[glfw.KeyJ] = app.KeyCodeJ
+	glfwKeyboardKeyMapping[glfw.KeyK] = app.KeyCodeK
+	glfwKeyboardKeyMapping[glfw.KeyL] = app.KeyCodeL
+	glfwKeyboardKeyMapping[glfw.KeyM] = app.KeyCodeM
+	glfwKeyboardKeyMapping[glfw.KeyN] = app.KeyCodeN
+	glfwKeyboardKeyMapping[glfw.KeyO] = app.KeyCodeO
+	glfwKeyboardKeyMapping[glfw.KeyP] = app.KeyCodeP
+	glfwKeyboardKeyMapping[glfw.KeyQ] = app.KeyCodeQ
+	glfwKeyboardKeyMapping[glfw.KeyR] = app.KeyCodeR
+	glfwKeyboardKeyMapping[glfw.KeyS] = app.KeyCodeS
+	glfwKeyboardKeyMapping[glfw.KeyT] = app.KeyCodeT
+	glfwKeyboardKeyMapping[glfw.KeyU] = app.KeyCodeU
+	glfwKeyboardKeyMapping[glfw.KeyV] = app.KeyCodeV
+	glfwKeyboardKeyMapping[glfw.KeyW] = app.KeyCodeW
+	glfwKeyboardKeyMapping[glfw.KeyX] = app.KeyCodeX
+	glfwKeyboardKeyMapping[glfw.KeyY] = app.KeyCodeY
+	glfwKeyboardKeyMapping[glfw.KeyZ] = app.KeyCodeZ
+	glfwKeyboardKeyMapping[glfw.Key0] = app.KeyCode0
+	glfwKeyboardKeyMapping[glfw.Key1] = app.KeyCode1
+	glfwKeyboardKeyMapping[glfw.Key2] = app.KeyCode2
+	glfwKeyboardKeyMapping[glfw.Key3] = app.KeyCode3
+	glfwKeyboardKeyMapping[glfw.Key4] = app.KeyCode4
+	glfwKeyboardKeyMapping[glfw.Key5] = app.KeyCode5
+	glfwKeyboardKeyMapping[glfw.Key6] = app.KeyCode6
+	glfwKeyboardKeyMapping[glfw.Key7] = app.KeyCode7
+	glfwKeyboardKeyMapping[glfw.Key8] = app.KeyCode8
+	glfwKeyboardKeyMapping[glfw.Key9] = app.KeyCode9
+	glfwKeyboardKeyMapping[glfw.KeyF1] = app.KeyCodeF1
+	glfwKeyboardKeyMapping[glfw.KeyF2] = app.KeyCodeF2
+	glfwKeyboardKeyMapping[glfw.KeyF3] = app.KeyCodeF3
+	glfwKeyboardKeyMapping[glfw.KeyF4] = app.KeyCodeF4
+	glfwKeyboardKeyMapping[glfw.KeyF5] = app.KeyCodeF5
+	glfwKeyboardKeyMapping[glfw.KeyF6] = app.KeyCodeF6
+	glfwKeyboardKeyMapping[glfw.KeyF7] = app.KeyCodeF7
+	glfwKeyboardKeyMapping[glfw.KeyF8] = app.KeyCodeF8
+	glfwKeyboardKeyMapping[glfw.KeyF9] = app.KeyCodeF9
+	glfwKeyboardKeyMapping[glfw.KeyF10] = app.KeyCodeF10
+	glfwKeyboardKeyMapping[glfw.KeyF11] = app.KeyCodeF11
+	glfwKeyboardKeyMapping[glfw.KeyF12] = app.KeyCodeF12
+}
@@ -0,0 +1,676 @@
+//go:build sdl2
+
+package internal
+
+import (
+	"fmt"
+	"image"
+	"image/draw"
+	"math"
+	"runtime"
+	"time"
+	"unicode/utf8"
+
+	"github.com/mokiat/lacking/app"
+	"github.com/veandco/go-sdl2/sdl"
+)
+
+// sdlDefaultDPI is the DPI that corresponds to a content scale of one.
+const sdlDefaultDPI = 96.0
+
+// NewSDLBackend initializes SDL2 and returns a Backend that uses it.
+func NewSDLBackend() (Backend, error) {
+	if err := sdl.Init(sdl.INIT_VIDEO | sdl.INIT_EVENTS | sdl.INIT_GAMECONTROLLER | sdl.INIT_HAPTIC); err != nil {
+		return nil, fmt.Errorf("failed to initialize sdl: %w", err)
+	}
+	return &sdlBackend{
+		windows: make(map[uint32]*sdlWindow),
+		joysticks: [4]*sdlJoystick{
+			{index: 0},
+			{index: 1},
+			{index: 2},
+			{index: 3},
+		},
+	}, nil
+}
+
+type sdlBackend struct {
+	windows   map[uint32]*sdlWindow
+	joysticks [4]*sdlJoystick
+}
+
+func (b *sdlBackend) CreateWindow(info WindowInfo) (Window, error) {
+	sdl.GLSetAttribute(sdl.GL_CONTEXT_MAJOR_VERSION, 4)
+	sdl.GLSetAttribute(sdl.GL_CONTEXT_MINOR_VERSION, 1)
+	sdl.GLSetAttribute(sdl.GL_CONTEXT_PROFILE_MASK, sdl.GL_CONTEXT_PROFILE_CORE)
+	sdl.GLSetAttribute(sdl.GL_CONTEXT_FLAGS, sdl.GL_CONTEXT_FORWARD_COMPATIBLE_FLAG)
+	sdl.GLSetAttribute(sdl.GL_FRAMEBUFFER_SRGB_CAPABLE, 1)
+
+	var (
+		windowWidth  = int32(info.Width)
+		windowHeight = int32(info.Height)
+		flags        = uint32(sdl.WINDOW_OPENGL | sdl.WINDOW_RESIZABLE | sdl.WINDOW_ALLOW_HIGHDPI)
+	)
+	if info.Fullscreen {
+		if mode, err := sdl.GetDesktopDisplayMode(0); err == nil {
+			windowWidth = mode.W
+			windowHeight = mode.H
+		}
+		flags |= sdl.WINDOW_FULLSCREEN
+	}
+	if info.Maximized {
+		flags |= sdl.WINDOW_MAXIMIZED
+	}
+	if info.ScaleToMonitor && !info.Fullscreen && runtime.GOOS != "darwin" {
+		// On macOS the window size is already expressed in points, which
+		// the system scales by itself.
+		if _, hdpi, vdpi, err := sdl.GetDisplayDPI(0); err == nil {
+			windowWidth = int32(float32(windowWidth) * hdpi / sdlDefaultDPI)
+			windowHeight = int32(float32(windowHeight) * vdpi / sdlDefaultDPI)
+		}
+	}
+
+	// SDL shares objects with the context that is current at the time
+	// the new context is created.
+	shareWindow, sharing := info.Share.(*sdlWindow)
+	if sharing {
+		shareWindow.MakeContextCurrent()
+		sdl.GLSetAttribute(sdl.GL_SHARE_WITH_CURRENT_CONTEXT, 1)
+	} else {
+		sdl.GLSetAttribute(sdl.GL_SHARE_WITH_CURRENT_CONTEXT, 0)
+	}
+
+	window, err := sdl.CreateWindow(info.Title, sdl.WINDOWPOS_UNDEFINED, sdl.WINDOWPOS_UNDEFINED, windowWidth, windowHeight, flags)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create sdl window: %w", err)
+	}
+	context, err := window.GLCreateContext()
+	if err != nil {
+		window.Destroy()
+		return nil, fmt.Errorf("failed to create sdl opengl context: %w", err)
+	}
+	if sharing {
+		// Creating a context makes it current, which differs from GLFW.
+		shareWindow.MakeContextCurrent()
+	}
+	id, err := window.GetID()
+	if err != nil {
+		sdl.GLDeleteContext(context)
+		window.Destroy()
+		return nil, fmt.Errorf("failed to get sdl window id: %w", err)
+	}
+
+	// SDL always limits both dimensions, so an unlimited dimension is
+	// given the widest possible range instead.
+	if info.MinWidth > 0 || info.MinHeight > 0 {
+		window.SetMinimumSize(sdlSizeLimit(info.MinWidth, 1), sdlSizeLimit(info.MinHeight, 1))
+	}
+	if info.MaxWidth > 0 || info.MaxHeight > 0 {
+		window.SetMaximumSize(sdlSizeLimit(info.MaxWidth, math.MaxInt32), sdlSizeLimit(info.MaxHeight, math.MaxInt32))
+	}
+
+	result := &sdlWindow{
+		backend: b,
+		id:      id,
+		window:  window,
+		context: context,
+	}
+	result.scaleX, result.scaleY = result.ContentScale()
+	b.windows[id] = result
+	return result, nil
+}
+
+func (b *sdlBackend) DetachCurrentContext() {
+	var (
+		noWindow  *sdl.Window
+		noContext sdl.GLContext
+	)
+	noWindow.GLMakeCurrent(noContext)
+}
+
+func (b *sdlBackend) SetSwapInterval(interval int) {
+	sdl.GLSetSwapInterval(interval)
+}
+
+func (b *sdlBackend) PollEvents() {
+	for event := sdl.PollEvent(); event != nil; event = sdl.PollEvent() {
+		b.dispatch(event)
+	}
+}
+
+func (b *sdlBackend) WaitEvents() {
+	if event := sdl.WaitEvent(); event != nil {
+		b.dispatch(event)
+	}
+	b.PollEvents()
+}
+
+func (b *sdlBackend) PostEmptyEvent() {
+	sdl.PushEvent(&sdl.UserEvent{
+		Type: sdl.USEREVENT,
+	})
+}
+
+func (b *sdlBackend) ClipboardText() string {
+	text, err := sdl.GetClipboardText()
+	if err != nil {
+		return ""
+	}
+	return text
+}
+
+func (b *sdlBackend) SetClipboardText(text string) {
+	sdl.SetClipboardText(text)
+}
+
+func (b *sdlBackend) CreateCursor(img image.Image, hotspotX, hotspotY int) Cursor {
+	surface := sdlSurface(img)
+	if surface == nil {
+		return &sdlCursor{}
+	}
+	defer surface.Free()
+	return &sdlCursor{
+		cursor: sdl.CreateColorCursor(surface, int32(hotspotX), int32(hotspotY)),
+	}
+}
+
+func (b *sdlBackend) Joystick(index int) Joystick {
+	return b.joysticks[index]
+}
+
+func (b *sdlBackend) Terminate() {
+	for _, joystick := range b.joysticks {
+		joystick.close()
+	}
+	sdl.Quit()
+}
+
+func (b *sdlBackend) dispatch(event sdl.Event) {
+	switch event := event.(type) {
+	case *sdl.WindowEvent:
+		if window, ok := b.windows[event.WindowID]; ok {
+			window.onWindowEvent(event)
+		}
+	case *sdl.KeyboardEvent:
+		if window, ok := b.windows[event.WindowID]; ok {
+			window.onKeyboardEvent(event)
+		}
+	case *sdl.TextInputEvent:
+		if window, ok := b.windows[event.WindowID]; ok {
+			window.onTextInputEvent(event)
+		}
+	case *sdl.TextEditingEvent:
+		if window, ok := b.windows[event.WindowID]; ok {
+			window.onTextEditingEvent(event)
+		}
+	case *sdl.MouseMotionEvent:
+		if window, ok := b.windows[event.WindowID]; ok {
+			window.onMouseMotionEvent(event)
+		}
+	case *sdl.MouseButtonEvent:
+		if window, ok := b.windows[event.WindowID]; ok {
+			window.onMouseButtonEvent(event)
+		}
+	case *sdl.MouseWheelEvent:
+		if window, ok := b.windows[event.WindowID]; ok {
+			window.onMouseWheelEvent(event)
+		}
+	case *sdl.DropEvent:
+		if window, ok := b.windows[event.WindowID]; ok {
+			window.onDropEvent(event)
+		}
+	}
+}
+
+type sdlWindow struct {
+	backend *sdlBackend
+	id      uint32
+	window  *sdl.Window
+	context sdl.GLContext
+	handler EventHandler
+
+	scaleX     float32
+	scaleY     float32
+	composing  bool
+	cursorMode CursorMode
+	cursorX    int
+	cursorY    int
+	dropPaths  []string
+}
+
+func (w *sdlWindow) SetEventHandler(handler EventHandler) {
+	w.handler = handler
+}
+
+func (w *sdlWindow) SetTitle(title string) {
+	w.window.SetTitle(title)
+}
+
+func (w *sdlWindow) SetIcon(img image.Image) {
+	surface := sdlSurface(img)
+	if surface == nil {
+		return
+	}
+	defer surface.Free()
+	w.window.SetIcon(surface)
+}
+
+func (w *sdlWindow) Size() (int, int) {
+	width, height := w.window.GetSize()
+	return int(width), int(height)
+}
+
+func (w *sdlWindow) SetSize(width, height int) {
+	w.window.SetSize(int32(width), int32(height))
+}
+
+func (w *sdlWindow) FramebufferSize() (int, int) {
+	width, height := w.window.GLGetDrawableSize()
+	return int(width), int(height)
+}
+
+func (w *sdlWindow) ContentScale() (float32, float32) {
+	if runtime.GOOS == "darwin" {
+		width, _ := w.Size()
+		framebufferWidth, _ := w.FramebufferSize()
+		if width == 0 {
+			return 1.0, 1.0
+		}
+		scale := float32(framebufferWidth) / float32(width)
+		return scale, scale
+	}
+	index, err := w.window.GetDisplayIndex()
+	if err != nil {
+		return 1.0, 1.0
+	}
+	_, hdpi, vdpi, err := sdl.GetDisplayDPI(index)
+	if err != nil {
+		return 1.0, 1.0
+	}
+	return hdpi / sdlDefaultDPI, vdpi / sdlDefaultDPI
+}
+
+func (w *sdlWindow) SetCursor(cursor Cursor) {
+	if cursor, ok := cursor.(*sdlCursor); ok && cursor.cursor != nil {
+		sdl.SetCursor(cursor.cursor)
+	} else {
+		sdl.SetCursor(sdl.GetDefaultCursor())
+	}
+}
+
+func (w *sdlWindow) SetCursorMode(mode CursorMode) {
+	w.cursorMode = mode
+	switch mode {
+	case CursorModeDisabled:
+		sdl.SetRelativeMouseMode(true)
+	case CursorModeHidden:
+		sdl.SetRelativeMouseMode(false)
+		sdl.ShowCursor(sdl.DISABLE)
+	default:
+		sdl.SetRelativeMouseMode(false)
+		sdl.ShowCursor(sdl.ENABLE)
+	}
+}
+
+func (w *sdlWindow) SetTextInputEnabled(enabled bool) bool {
+	// SDL has a single text input state, which applies to the window
+	// that has keyboard focus.
+	if enabled {
+		sdl.StartTextInput()
+	} else {
+		sdl.StopTextInput()
+		w.endComposition("")
+	}
+	return true
+}
+
+func (w *sdlWindow) SetTextInputRect(x, y, width, height int) bool {
+	sdl.SetTextInputRect(&sdl.Rect{
+		X: int32(x),
+		Y: int32(y),
+		W: int32(width),
+		H: int32(height),
+	})
+	return true
+}
+
+func (w *sdlWindow) Focus() {
+	if w.window.GetFlags()&sdl.WINDOW_MINIMIZED != 0 {
+		w.window.Restore()
+	}
+	w.window.Raise()
+}
+
+func (w *sdlWindow) MakeContextCurrent() {
+	w.window.GLMakeCurrent(w.context)
+}
+
+func (w *sdlWindow) SwapBuffers() {
+	w.window.GLSwap()
+}
+
+func (w *sdlWindow) Destroy() {
+	delete(w.backend.windows, w.id)
+	sdl.GLDeleteContext(w.context)
+	w.window.Destroy()
+}
+
+func (w *sdlWindow) onWindowEvent(event *sdl.WindowEvent) {
+	if w.handler == nil {
+		return
+	}
+	switch event.Event {
+	case sdl.WINDOWEVENT_EXPOSED:
+		w.handler.OnRefresh()
+	case sdl.WINDOWEVENT_SIZE_CHANGED:
+		w.handler.OnResize(int(event.Data1), int(event.Data2))
+		w.handler.OnFramebufferResize(w.FramebufferSize())
+		w.checkContentScale()
+	case sdl.WINDOWEVENT_MOVED:
+		w.checkContentScale()
+	case sdl.WINDOWEVENT_CLOSE:
+		w.handler.OnCloseRequested()
+	case sdl.WINDOWEVENT_FOCUS_LOST:
+		w.endComposition("")
+	case sdl.WINDOWEVENT_ENTER:
+		w.handler.OnMouseEvent(app.MouseEvent{
+			Index:  0,
+			X:      w.cursorX,
+			Y:      w.cursorY,
+			Action: app.MouseActionEnter,
+		})
+	case sdl.WINDOWEVENT_LEAVE:
+		w.handler.OnMouseEvent(app.MouseEvent{
+			Index:  0,
+			X:      w.cursorX,
+			Y:      w.cursorY,
+			Action: app.MouseActionLeave,
+		})
+	}
+}
+
+// checkContentScale notifies the handler if the window has been moved
+// to a display with a different DPI, since SDL has no dedicated event.
+func (w *sdlWindow) checkContentScale() {
+	scaleX, scaleY := w.ContentScale()
+	if scaleX != w.scaleX || scaleY != w.scaleY {
+		w.scaleX, w.scaleY = scaleX, scaleY
+		w.handler.OnContentScaleChange(scaleX, scaleY)
+	}
+}
+
+func (w *sdlWindow) onKeyboardEvent(event *sdl.KeyboardEvent) {
+	if w.handler == nil {
+		return
+	}
+	keyCode, ok := sdlKeyboardKeyMapping[event.Keysym.Scancode]
+	if !ok {
+		return
+	}
+	var eventType app.KeyboardAction
+	switch {
+	case event.Type == sdl.KEYUP:
+		eventType = app.KeyboardActionUp
+	case event.Repeat != 0:
+		eventType = app.KeyboardActionRepeat
+	default:
+		eventType = app.KeyboardActionDown
+	}
+	w.handler.OnKeyboardEvent(app.KeyboardEvent{
+		Action: eventType,
+		Code:   keyCode,
+	})
+}
+
+func (w *sdlWindow) onTextInputEvent(event *sdl.TextInputEvent) {
+	if w.handler == nil {
+		return
+	}
+	text := event.GetText()
+	w.endComposition(text)
+	for len(text) > 0 {
+		char, size := utf8.DecodeRuneInString(text)
+		text = text[size:]
+		w.handler.OnKeyboardEvent(app.KeyboardEvent{
+			Action:    app.KeyboardActionType,
+			Character: char,
+		})
+	}
+}
+
+func (w *sdlWindow) onTextEditingEvent(event *sdl.TextEditingEvent) {
+	if w.handler == nil {
+		return
+	}
+	text := event.GetText()
+	if text == "" {
+		// An empty preedit text is reported when the composition is
+		// cancelled and by some input methods right before the text is
+		// committed, in which case the commit follows as text input.
+		if w.composing {
+			w.handler.OnCompositionEvent(CompositionActionUpdate, "", 0, 0)
+		}
+		return
+	}
+	if !w.composing {
+		w.composing = true
+		w.handler.OnCompositionEvent(CompositionActionStart, "", 0, 0)
+	}
+	w.handler.OnCompositionEvent(CompositionActionUpdate, text, int(event.Start), int(event.Length))
+}
+
+// endComposition reports the end of an active composition, if any.
+func (w *sdlWindow) endComposition(text string) {
+	if !w.composing {
+		return
+	}
+	w.composing = false
+	if w.handler != nil {
+		w.handler.OnCompositionEvent(CompositionActionCommit, text, 0, 0)
+	}
+}
+
+func (w *sdlWindow) onMouseMotionEvent(event *sdl.MouseMotionEvent) {
+	if w.cursorMode == CursorModeDisabled {
+		// Relative mode keeps the cursor in place, so a virtual
+		// position is accumulated instead.
+		w.cursorX += int(event.XRel)
+		w.cursorY += int(event.YRel)
+	} else {
+		w.cursorX = int(event.X)
+		w.cursorY = int(event.Y)
+	}
+	if w.handler == nil {
+		return
+	}
+	w.handler.OnMouseEvent(app.MouseEvent{
+		Index:  0,
+		X:      w.cursorX,
+		Y:      w.cursorY,
+		Action: app.MouseActionMove,
+	})
+}
+
+func (w *sdlWindow) onMouseButtonEvent(event *sdl.MouseButtonEvent) {
+	if w.handler == nil {
+		return
+	}
+	var eventType app.MouseAction
+	switch event.Type {
+	case sdl.MOUSEBUTTONDOWN:
+		eventType = app.MouseActionDown
+	case sdl.MOUSEBUTTONUP:
+		eventType = app.MouseActionUp
+	}
+	var eventButton app.MouseButton
+	switch event.Button {
+	case sdl.BUTTON_LEFT:
+		eventButton = app.MouseButtonLeft
+	case sdl.BUTTON_RIGHT:
+		eventButton = app.MouseButtonRight
+	case sdl.BUTTON_MIDDLE:
+		eventButton = app.MouseButtonMiddle
+	}
+	w.handler.OnMouseEvent(app.MouseEvent{
+		Index:  0,
+		X:      w.cursorX,
+		Y:      w.cursorY,
+		Action: eventType,
+		Button: eventButton,
+	})
+}
+
+func (w *sdlWindow) onMouseWheelEvent(event *sdl.MouseWheelEvent) {
+	if w.handler == nil {
+		return
+	}
+	w.handler.OnMouseEvent(app.MouseEvent{
+		Index:   0,
+		X:       w.cursorX,
+		Y:       w.cursorY,
+		Action:  app.MouseActionScroll,
+		ScrollX: float64(event.X) * 20.0,
+		ScrollY: float64(event.Y) * 20.0,
+	})
+}
+
+func (w *sdlWindow) onDropEvent(event *sdl.DropEvent) {
+	switch event.Type {
+	case sdl.DROPBEGIN:
+		w.dropPaths = nil
+	case sdl.DROPFILE:
+		w.dropPaths = append(w.dropPaths, event.File)
+	case sdl.DROPCOMPLETE:
+		paths := w.dropPaths
+		w.dropPaths = nil
+		if w.handler == nil || len(paths) == 0 {
+			return
+		}
+		w.handler.OnMouseEvent(app.MouseEvent{
+			Index:  0,
+			X:      w.cursorX,
+			Y:      w.cursorY,
+			Action: app.MouseActionDrop,
+			Payload: app.FilepathPayload{
+				Paths: paths,
+			},
+		})
+	}
+}
+
+type sdlCursor struct {
+	cursor *sdl.Cursor
+}
+
+func (c *sdlCursor) Destroy() {
+	if c.cursor != nil {
+		sdl.FreeCursor(c.cursor)
+		c.cursor = nil
+	}
+}
+
+type sdlJoystick struct {
+	index      int
+	controller *sdl.GameController
+}
+
+func (j *sdlJoystick) Present() bool {
+	return j.index < sdl.NumJoysticks()
+}
+
+func (j *sdlJoystick) IsGamepad() bool {
+	return j.Present() && sdl.IsGameController(j.index)
+}
+
+func (j *sdlJoystick) GamepadState() GamepadState {
+	controller := j.open()
+	if controller == nil {
+		return GamepadState{}
+	}
+	pressed := func(button sdl.GameControllerButton) bool {
+		return controller.Button(button) != 0
+	}
+	return GamepadState{
+		LeftStickX:        sdlStickValue(controller.Axis(sdl.CONTROLLER_AXIS_LEFTX)),
+		LeftStickY:        sdlStickValue(controller.Axis(sdl.CONTROLLER_AXIS_LEFTY)),
+		LeftStickButton:   pressed(sdl.CONTROLLER_BUTTON_LEFTSTICK),
+		RightStickX:       sdlStickValue(controller.Axis(sdl.CONTROLLER_AXIS_RIGHTX)),
+		RightStickY:       sdlStickValue(controller.Axis(sdl.CONTROLLER_AXIS_RIGHTY)),
+		RightStickButton:  pressed(sdl.CONTROLLER_BUTTON_RIGHTSTICK),
+		LeftBumperButton:  pressed(sdl.CONTROLLER_BUTTON_LEFTSHOULDER),
+		LeftTrigger:       sdlTriggerValue(controller.Axis(sdl.CONTROLLER_AXIS_TRIGGERLEFT)),
+		RightBumperButton: pressed(sdl.CONTROLLER_BUTTON_RIGHTSHOULDER),
+		RightTrigger:      sdlTriggerValue(controller.Axis(sdl.CONTROLLER_AXIS_TRIGGERRIGHT)),
+		DpadLeftButton:    pressed(sdl.CONTROLLER_BUTTON_DPAD_LEFT),
+		DpadRightButton:   pressed(sdl.CONTROLLER_BUTTON_DPAD_RIGHT),
+		DpadUpButton:      pressed(sdl.CONTROLLER_BUTTON_DPAD_UP),
+		DpadDownButton:    pressed(sdl.CONTROLLER_BUTTON_DPAD_DOWN),
+		ActionLeftButton:  pressed(sdl.CONTROLLER_BUTTON_X),
+		ActionRightButton: pressed(sdl.CONTROLLER_BUTTON_B),
+		ActionUpButton:    pressed(sdl.CONTROLLER_BUTTON_Y),
+		ActionDownButton:  pressed(sdl.CONTROLLER_BUTTON_A),
+		ForwardButton:     pressed(sdl.CONTROLLER_BUTTON_START),
+		BackButton:        pressed(sdl.CONTROLLER_BUTTON_BACK),
+	}
+}
+
+func (j *sdlJoystick) Rumble(intensity float64, duration time.Duration) bool {
+	controller := j.open()
+	if controller == nil {
+		return false
+	}
+	strength := uint16(max(min(intensity, 1.0), 0.0) * 0xFFFF)
+	return controller.Rumble(strength, strength, uint32(duration.Milliseconds())) == nil
+}
+
+func (j *sdlJoystick) open() *sdl.GameController {
+	if j.controller != nil && j.controller.Attached() {
+		return j.controller
+	}
+	j.close()
+	if !j.IsGamepad() {
+		return nil
+	}
+	j.controller = sdl.GameControllerOpen(j.index)
+	return j.controller
+}
+
+func (j *sdlJoystick) close() {
+	if j.controller != nil {
+		j.controller.Close()
+		j.controller = nil
+	}
+}
+
+func sdlStickValue(value int16) float64 {
+	return max(float64(value)/32767.0, -1.0)
+}
+
+func sdlTriggerValue(value int16) float64 {
+	return max(float64(value)/32767.0, 0.0)
+}
+
+func sdlSurface(img image.Image) *sdl.Surface {
+	bounds := img.Bounds()
+	rgba := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(rgba, rgba.Bounds(), img, bounds.Min, draw.Src)
+
+	surface, err := sdl.CreateRGBSurfaceWithFormat(0, int32(bounds.Dx()), int32(bounds.Dy()), 32, uint32(sdl.PIXELFORMAT_RGBA32))
+	if err != nil {
+		return nil
+	}
+	pixels := surface.Pixels()
+	for y := 0; y < bounds.Dy(); y++ {
+		copy(pixels[y*int(surface.Pitch):], rgba.Pix[y*rgba.Stride:(y+1)*rgba.Stride])
+	}
+	return surface
+}
+
+// sdlSizeLimit returns the specified size limit or the fallback if the
+// dimension is not limited.
+func sdlSizeLimit(value int, fallback int32) int32 {
+	if value <= 0 {
+		return fallback
+	}
+	return int32(value)
+}
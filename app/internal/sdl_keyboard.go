@@ -0,0 +1,99 @@
+//go:build sdl2
+
+package internal
+
+import (
+	"github.com/mokiat/lacking/app"
+	"github.com/veandco/go-sdl2/sdl"
+)
+
+var (
+	sdlKeyboardKeyMapping map[sdl.Scancode]app.KeyCode
+)
+
+func init() {
+	sdlKeyboardKeyMapping = make(map[sdl.Scancode]app.KeyCode)
+	sdlKeyboardKeyMapping[sdl.SCANCODE_ESCAPE] = app.KeyCodeEscape
+	sdlKeyboardKeyMapping[sdl.SCANCODE_RETURN] = app.KeyCodeEnter
+	sdlKeyboardKeyMapping[sdl.SCANCODE_SPACE] = app.KeyCodeSpace
+	sdlKeyboardKeyMapping[sdl.SCANCODE_TAB] = app.KeyCodeTab
+	sdlKeyboardKeyMapping[sdl.SCANCODE_CAPSLOCK] = app.KeyCodeCaps
+	sdlKeyboardKeyMapping[sdl.SCANCODE_LSHIFT] = app.KeyCodeLeftShift
+	sdlKeyboardKeyMapping[sdl.SCANCODE_RSHIFT] = app.KeyCodeRightShift
+	sdlKeyboardKeyMapping[sdl.SCANCODE_LCTRL] = app.KeyCodeLeftControl
+	sdlKeyboardKeyMapping[sdl.SCANCODE_RCTRL] = app.KeyCodeRightControl
+	sdlKeyboardKeyMapping[sdl.SCANCODE_LALT] = app.KeyCodeLeftAlt
+	sdlKeyboardKeyMapping[sdl.SCANCODE_RALT] = app.KeyCodeRightAlt
+	sdlKeyboardKeyMapping[sdl.SCANCODE_LGUI] = app.KeyCodeLeftSuper
+	sdlKeyboardKeyMapping[sdl.SCANCODE_RGUI] = app.KeyCodeRightSuper
+	sdlKeyboardKeyMapping[sdl.SCANCODE_BACKSPACE] = app.KeyCodeBackspace
+	sdlKeyboardKeyMapping[sdl.SCANCODE_INSERT] = app.KeyCodeInsert
+	sdlKeyboardKeyMapping[sdl.SCANCODE_DELETE] = app.KeyCodeDelete
+	sdlKeyboardKeyMapping[sdl.SCANCODE_HOME] = app.KeyCodeHome
+	sdlKeyboardKeyMapping[sdl.SCANCODE_END] = app.KeyCodeEnd
+	sdlKeyboardKeyMapping[sdl.SCANCODE_PAGEUP] = app.KeyCodePageUp
+	sdlKeyboardKeyMapping[sdl.SCANCODE_PAGEDOWN] = app.KeyCodePageDown
+	sdlKeyboardKeyMapping[sdl.SCANCODE_LEFT] = app.KeyCodeArrowLeft
+	sdlKeyboardKeyMapping[sdl.SCANCODE_RIGHT] = app.KeyCodeArrowRight
+	sdlKeyboardKeyMapping[sdl.SCANCODE_UP] = app.KeyCodeArrowUp
+	sdlKeyboardKeyMapping[sdl.SCANCODE_DOWN] = app.KeyCodeArrowDown
+	sdlKeyboardKeyMapping[sdl.SCANCODE_MINUS] = app.KeyCodeMinus
+	sdlKeyboardKeyMapping[sdl.SCANCODE_EQUALS] = app.KeyCodeEqual
+	sdlKeyboardKeyMapping[sdl.SCANCODE_LEFTBRACKET] = app.KeyCodeLeftBracket
+	sdlKeyboardKeyMapping[sdl.SCANCODE_RIGHTBRACKET] = app.KeyCodeRightBracket
+	sdlKeyboardKeyMapping[sdl.SCANCODE_SEMICOLON] = app.KeyCodeSemicolon
+	sdlKeyboardKeyMapping[sdl.SCANCODE_COMMA] = app.KeyCodeComma
+	sdlKeyboardKeyMapping[sdl.SCANCODE_PERIOD] = app.KeyCodePeriod
+	sdlKeyboardKeyMapping[sdl.SCANCODE_SLASH] = app.KeyCodeSlash
+	sdlKeyboardKeyMapping[sdl.SCANCODE_BACKSLASH] = app.KeyCodeBackslash
+	sdlKeyboardKeyMapping[sdl.SCANCODE_APOSTROPHE] = app.KeyCodeApostrophe
+	sdlKeyboardKeyMapping[sdl.SCANCODE_GRAVE] = app.KeyCodeGraveAccent
+	sdlKeyboardKeyMapping[sdl.SCANCODE_A] = app.KeyCodeA
+	sdlKeyboardKeyMapping[sdl.SCANCODE_B] = app.KeyCodeB
+	sdlKeyboardKeyMapping[sdl.SCANCODE_C] = app.KeyCodeC
+	sdlKeyboardKeyMapping[sdl.SCANCODE_D] = app.KeyCodeD
+	sdlKeyboardKeyMapping[sdl.SCANCODE_E] = app.KeyCodeE
+	sdlKeyboardKeyMapping[sdl.SCANCODE_F] = app.KeyCodeF
+	sdlKeyboardKeyMapping[sdl.SCANCODE_G] = app.KeyCodeG
+	sdlKeyboardKeyMapping[sdl.SCANCODE_H] = app.KeyCodeH
+	sdlKeyboardKeyMapping[sdl.SCANCODE_I] = app.KeyCodeI
+	sdlKeyboardKeyMapping[sdl.SCANCODE_J] = app.KeyCodeJ
+	sdlKeyboardKeyMapping[sdl.SCANCODE_K] = app.KeyCodeK
+	sdlKeyboardKeyMapping[sdl.SCANCODE_L] = app.KeyCodeL
+	sdlKeyboardKeyMapping[sdl.SCANCODE_M] = app.KeyCodeM
+	sdlKeyboardKeyMapping[sdl.SCANCODE_N] = app.KeyCodeN
+	sdlKeyboardKeyMapping[sdl.SCANCODE_O] = app.KeyCodeO
+	sdlKeyboardKeyMapping[sdl.SCANCODE_P] = app.KeyCodeP
+	sdlKeyboardKeyMapping[sdl.SCANCODE_Q] = app.KeyCodeQ
+	sdlKeyboardKeyMapping[sdl.SCANCODE_R] = app.KeyCodeR
+	sdlKeyboardKeyMapping[sdl.SCANCODE_S] = app.KeyCodeS
+	sdlKeyboardKeyMapping[sdl.SCANCODE_T] = app.KeyCodeT
+	sdlKeyboardKeyMapping[sdl.SCANCODE_U] = app.KeyCodeU
+	sdlKeyboardKeyMapping[sdl.SCANCODE_V] = app.KeyCodeV
+	sdlKeyboardKeyMapping[sdl.SCANCODE_W] = app.KeyCodeW
+	sdlKeyboardKeyMapping[sdl.SCANCODE_X] = app.KeyCodeX
+	sdlKeyboardKeyMapping[sdl.SCANCODE_Y] = app.KeyCodeY
+	sdlKeyboardKeyMapping[sdl.SCANCODE_Z] = app.KeyCodeZ
+	sdlKeyboardKeyMapping[sdl.SCANCODE_0] = app.KeyCode0
+	sdlKeyboardKeyMapping[sdl.SCANCODE_1] = app.KeyCode1
+	sdlKeyboardKeyMapping[sdl.SCANCODE_2] = app.KeyCode2
+	sdlKeyboardKeyMapping[sdl.SCANCODE_3] = app.KeyCode3
+	sdlKeyboardKeyMapping[sdl.SCANCODE_4] = app.KeyCode4
+	sdlKeyboardKeyMapping[sdl.SCANCODE_5] = app.KeyCode5
+	sdlKeyboardKeyMapping[sdl.SCANCODE_6] = app.KeyCode6
+	sdlKeyboardKeyMapping[sdl.SCANCODE_7] = app.KeyCode7
+	sdlKeyboardKeyMapping[sdl.SCANCODE_8] = app.KeyCode8
+	sdlKeyboardKeyMapping[sdl.SCANCODE_9] = app.KeyCode9
+	sdlKeyboardKeyMapping[sdl.SCANCODE_F1] = app.KeyCodeF1
+	sdlKeyboardKeyMapping[sdl.SCANCODE_F2] = app.KeyCodeF2
+	sdlKeyboardKeyMapping[sdl.SCANCODE_F3] = app.KeyCodeF3
+	sdlKeyboardKeyMapping[sdl.SCANCODE_F4] = app.KeyCodeF4
+	sdlKeyboardKeyMapping[sdl.SCANCODE_F5] = app.KeyCodeF5
+	sdlKeyboardKeyMapping[sdl.SCANCODE_F6] = app.KeyCodeF6
+	sdlKeyboardKeyMapping[sdl.SCANCODE_F7] = app.KeyCodeF7
+	sdlKeyboardKeyMapping[sdl.SCANCODE_F8] = app.KeyCodeF8
+	sdlKeyboardKeyMapping[sdl.SCANCODE_F9] = app.KeyCodeF9
+	sdlKeyboardKeyMapping[sdl.SCANCODE_F10] = app.KeyCodeF10
+	sdlKeyboardKeyMapping[sdl.SCANCODE_F11] = app.KeyCodeF11
+	sdlKeyboardKeyMapping[sdl.SCANCODE_F12] = app.KeyCodeF12
+}
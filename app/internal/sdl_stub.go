@@ -0,0 +1,11 @@
+//go:build !sdl2
+
+package internal
+
+import "errors"
+
+// NewSDLBackend returns an error, since the application has not been
+// built with the sdl2 build tag.
+func NewSDLBackend() (Backend, error) {
+	return nil, errors.New("sdl2 backend not available; build with the sdl2 tag")
+}
@@ -4,12 +4,10 @@ import (
 	"fmt"
 	"time"
 
-	"github.com/go-gl/glfw/v3.3/glfw"
+	"github.com/mokiat/lacking-native/app/internal"
 	nativeaudio "github.com/mokiat/lacking-native/audio"
-	glrender "github.com/mokiat/lacking-native/render"
 	"github.com/mokiat/lacking/app"
 	"github.com/mokiat/lacking/audio"
-	"github.com/mokiat/lacking/debug/log"
 	"github.com/mokiat/lacking/debug/metric"
 	"github.com/mokiat/lacking/render"
 	"github.com/mokiat/lacking/util/resource"
@@ -18,51 +16,54 @@ import (
 const (
 	taskQueueSize         = 1024
 	taskProcessingTimeout = 30 * time.Millisecond
+	clipboardQueueSize    = 16
 )
 
-func newLoop(locator resource.ReadLocator, title string, window *glfw.Window, controller app.Controller, audioEnabled bool) *loop {
-	var audioAPI *nativeaudio.API
-	if audioEnabled {
-		var err error
-		audioAPI, err = nativeaudio.NewAPI()
-		if err != nil {
-			log.Error("Failed to initialize audio: %v", err)
-			audioAPI = nil
-		}
-	}
-
+func newLoop(backend internal.Backend, window internal.Window, locator resource.ReadLocator, title string, controller app.Controller, renderAPI render.API, audioAPI *nativeaudio.API, tracer *traceRecorder) *loop {
 	return &loop{
 		platform:      newPlatform(),
+		backend:       backend,
 		locator:       locator,
 		title:         title,
 		window:        window,
 		controller:    controller,
-		renderAPI:     glrender.NewAPI(),
+		renderAPI:     renderAPI,
 		audioAPI:      audioAPI,
+		tracer:        tracer,
+		clipboard:     newClipboardProvider(),
 		tasks:         make(chan func(), taskQueueSize),
 		shouldStop:    false,
 		shouldDraw:    true,
 		cursorVisible: true,
 		cursorLocked:  false,
 		gamepads: [4]*Gamepad{
-			newGamepad(glfw.Joystick1),
-			newGamepad(glfw.Joystick2),
-			newGamepad(glfw.Joystick3),
-			newGamepad(glfw.Joystick4),
+			newGamepad(backend.Joystick(0)),
+			newGamepad(backend.Joystick(1)),
+			newGamepad(backend.Joystick(2)),
+			newGamepad(backend.Joystick(3)),
 		},
 	}
 }
 
-var _ app.Window = (*loop)(nil)
+var (
+	_ app.Window            = (*loop)(nil)
+	_ internal.EventHandler = (*loop)(nil)
+)
 
 type loop struct {
 	platform      *platform
+	backend       internal.Backend
 	locator       resource.ReadLocator
 	title         string
-	window        *glfw.Window
+	window        internal.Window
 	controller    app.Controller
 	renderAPI     render.API
 	audioAPI      *nativeaudio.API
+	tracer        *traceRecorder
+	glDebug       *glDebugHandler
+	clipboard     clipboardProvider
+	clipboardJobs chan func()
+	clipboardDone chan struct{}
 	tasks         chan func()
 	shouldStop    bool
 	shouldDraw    bool
@@ -70,6 +71,8 @@ type loop struct {
 	cursorVisible bool
 	cursorLocked  bool
 	gamepads      [4]*Gamepad
+	children      []*childWindow
+	lastContext   int
 }
 
 func (l *loop) Run() error {
@@ -79,74 +82,80 @@ func (l *loop) Run() error {
 
 	l.controller.OnCreate(l)
 
-	l.window.SetRefreshCallback(l.onGLFWRefresh)
-
-	l.window.SetSizeCallback(l.onGLFWSize)
-	width, height := l.window.GetSize()
-	l.onGLFWSize(l.window, width, height)
-
-	l.window.SetFramebufferSizeCallback(l.onGLFWFramebufferSize)
-	width, height = l.window.GetFramebufferSize()
-	l.onGLFWFramebufferSize(l.window, width, height)
-
-	l.window.SetKeyCallback(l.onGLFWKey)
-	l.window.SetCharCallback(l.onGLFWChar)
-
-	l.window.SetCursorPosCallback(l.onGLFWCursorPos)
-	l.window.SetCursorEnterCallback(l.onGLFWCursorEnter)
-	l.window.SetMouseButtonCallback(l.onGLFWMouseButton)
-	l.window.SetScrollCallback(l.onGLFWScroll)
-	l.window.SetDropCallback(l.onGLFWMouseDrop)
+	l.window.SetEventHandler(l)
+	defer l.window.SetEventHandler(nil)
+	l.OnResize(l.window.Size())
+	l.OnFramebufferResize(l.window.FramebufferSize())
 
 	for !l.shouldStop {
-		if l.shouldWake {
-			l.shouldWake = false
-			glfw.PollEvents()
-		} else {
-			glfw.WaitEvents()
-		}
-
-		if l.window.ShouldClose() {
-			l.shouldStop = l.controller.OnCloseRequested(l)
-			l.window.SetShouldClose(false)
-		}
-
-		for _, gamepad := range l.gamepads {
-			gamepad.markDirty()
-		}
-
-		if !l.processTasks(taskProcessingTimeout) {
-			// Not all events were processed, loop should not
-			// block on next iteration.
-			l.shouldWake = true
-		}
-
-		if l.shouldDraw {
-			l.shouldDraw = false
-			metric.BeginFrame()
-
-			ctrlRegion := metric.BeginRegion("controller")
-			l.controller.OnRender(l)
-			ctrlRegion.End()
-
-			swapRegion := metric.BeginRegion("swap")
-			l.window.SwapBuffers()
-			swapRegion.End()
-
-			metric.EndFrame()
+		l.runIteration()
+		if err := l.glDebug.TakeFailure(); err != nil {
+			panic(err)
 		}
 	}
 
+	for _, child := range l.children {
+		child.destroy()
+	}
+	l.children = nil
 	l.controller.OnDestroy(l)
 
 	// Give any async tasks a chance to complete.
-	if !l.processTasks(5 * time.Second) {
+	tasksDone := l.processTasks(5 * time.Second)
+	l.stopClipboardWorker()
+	if !tasksDone {
 		return fmt.Errorf("failed to cleanup within timeout")
 	}
 
 	return nil
 }
 
+func (l *loop) runIteration() {
+	eventsRegion := l.tracer.BeginRegion("events")
+	if l.shouldWake {
+		l.shouldWake = false
+		l.backend.PollEvents()
+	} else {
+		l.backend.WaitEvents()
+	}
+	eventsRegion.End()
+
+	for _, gamepad := range l.gamepads {
+		gamepad.markDirty()
+	}
+
+	tasksRegion := l.tracer.BeginRegion("tasks")
+	if !l.processTasks(taskProcessingTimeout) {
+		// Not all events were processed, loop should not
+		// block on next iteration.
+		l.shouldWake = true
+	}
+	tasksRegion.End()
+
+	if l.shouldDraw {
+		l.shouldDraw = false
+		metric.BeginFrame()
+		l.tracer.BeginFrame()
+
+		ctrlRegion := metric.BeginRegion("controller")
+		ctrlTraceRegion := l.tracer.BeginRegion("controller")
+		l.controller.OnRender(l)
+		ctrlTraceRegion.End()
+		ctrlRegion.End()
+
+		swapRegion := metric.BeginRegion("swap")
+		swapTraceRegion := l.tracer.BeginRegion("swap")
+		l.window.SwapBuffers()
+		swapTraceRegion.End()
+		swapRegion.End()
+
+		l.tracer.EndFrame()
+		metric.EndFrame()
+	}
+
+	l.updateChildren()
+}
+
 func (l *loop) Platform() app.Platform {
 	return l.platform
 }
@@ -161,7 +170,7 @@ func (l *loop) SetTitle(title string) {
 }
 
 func (l *loop) Size() (int, int) {
-	return l.window.GetSize()
+	return l.window.Size()
 }
 
 func (l *loop) SetSize(width, height int) {
@@ -169,7 +178,12 @@ func (l *loop) SetSize(width, height int) {
 }
 
 func (l *loop) FramebufferSize() (int, int) {
-	return l.window.GetFramebufferSize()
+	return l.window.FramebufferSize()
+}
+
+// ContentScale returns the content scale of the window.
+func (l *loop) ContentScale() (float32, float32) {
+	return l.window.ContentScale()
 }
 
 func (l *loop) Gamepads() [4]app.Gamepad {
@@ -183,19 +197,29 @@ func (l *loop) Gamepads() [4]app.Gamepad {
 func (l *loop) Schedule(fn func()) {
 	select {
 	case l.tasks <- fn:
-		glfw.PostEmptyEvent()
+		l.backend.PostEmptyEvent()
 	default:
 		panic(fmt.Errorf("failed to queue task; queue is full"))
 	}
 }
 
+// trySchedule is like Schedule but returns false instead of panicking
+// when the queue is full. It is used for events that originate from
+// background goroutines, where a panic would bring down the process.
+func (l *loop) trySchedule(fn func()) bool {
+	select {
+	case l.tasks <- fn:
+		l.backend.PostEmptyEvent()
+		return true
+	default:
+		return false
+	}
+}
+
 func (l *loop) Invalidate() {
 	if !l.shouldDraw {
 		l.shouldDraw = true
-		if !l.shouldWake {
-			l.shouldWake = true
-			glfw.PostEmptyEvent()
-		}
+		l.wake()
 	}
 }
 
@@ -205,15 +229,14 @@ func (l *loop) CreateCursor(definition app.CursorDefinition) app.Cursor {
 		panic(fmt.Errorf("failed to open cursor %q: %w", definition.Path, err))
 	}
 	return &customCursor{
-		cursor: glfw.CreateCursor(img, definition.HotspotX, definition.HotspotY),
+		cursor: l.backend.CreateCursor(img, definition.HotspotX, definition.HotspotY),
 	}
 }
 
 func (l *loop) UseCursor(cursor app.Cursor) {
-	switch cursor := cursor.(type) {
-	case *customCursor:
+	if cursor, ok := cursor.(*customCursor); ok {
 		l.window.SetCursor(cursor.cursor)
-	default:
+	} else {
 		l.window.SetCursor(nil)
 	}
 }
@@ -233,11 +256,11 @@ func (l *loop) SetCursorLocked(locked bool) {
 }
 
 func (l *loop) RequestCopy(text string) {
-	glfw.SetClipboardString(text)
+	l.backend.SetClipboardText(text)
 }
 
 func (l *loop) RequestPaste() {
-	text := glfw.GetClipboardString()
+	text := l.backend.ClipboardText()
 	l.Schedule(func() {
 		l.controller.OnClipboardEvent(l, app.ClipboardEvent{
 			Text: text,
@@ -245,6 +268,24 @@ func (l *loop) RequestPaste() {
 	})
 }
 
+func (l *loop) requestCopyData(item ClipboardItem) {
+	l.copyClipboardData(item)
+}
+
+func (l *loop) requestPasteData(mimeTypes []string) {
+	l.pasteClipboardData(mimeTypes, func(item ClipboardItem) {
+		deliverClipboardData(l, l.controller, item)
+	})
+}
+
+func (l *loop) setTextInputEnabled(enabled bool) bool {
+	return l.window.SetTextInputEnabled(enabled)
+}
+
+func (l *loop) setTextInputRect(x, y, width, height int) bool {
+	return l.window.SetTextInputRect(x, y, width, height)
+}
+
 func (l *loop) RenderAPI() render.API {
 	return l.renderAPI
 }
@@ -259,18 +300,89 @@ func (l *loop) AudioAPI() audio.API {
 func (l *loop) Close() {
 	if !l.shouldStop {
 		l.shouldStop = true
-		glfw.PostEmptyEvent()
+		l.backend.PostEmptyEvent()
 	}
 }
 
+func (l *loop) wake() {
+	if !l.shouldWake {
+		l.shouldWake = true
+		l.backend.PostEmptyEvent()
+	}
+}
+
+func (l *loop) makeCurrent() {
+	l.window.MakeContextCurrent()
+	if switcher, ok := l.renderAPI.(renderContextSwitcher); ok {
+		switcher.SetCurrentContext(0)
+	}
+}
+
+func (l *loop) openChild(cfg *Config, controller app.Controller) (*childWindow, error) {
+	window, err := createWindow(l.backend, cfg, l.window)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create window: %w", err)
+	}
+
+	// Child windows should not wait for vertical sync, since the loop
+	// already does so when swapping the main window.
+	window.MakeContextCurrent()
+	l.backend.SetSwapInterval(0)
+	l.window.MakeContextCurrent()
+
+	l.lastContext++
+	child := &childWindow{
+		loop:          l,
+		window:        window,
+		controller:    controller,
+		contextIndex:  l.lastContext,
+		title:         cfg.title,
+		shouldDraw:    true,
+		cursorVisible: true,
+	}
+	l.children = append(l.children, child)
+
+	controller.OnCreate(child)
+	window.SetEventHandler(child)
+	child.OnResize(window.Size())
+	child.OnFramebufferResize(window.FramebufferSize())
+
+	if cfg.cursor != nil {
+		child.cursor = child.CreateCursor(*cfg.cursor)
+		child.UseCursor(child.cursor)
+	}
+	if !cfg.cursorVisible {
+		child.SetCursorVisible(false)
+	}
+	l.wake()
+	return child, nil
+}
+
+func (l *loop) updateChildren() {
+	children := l.children[:0]
+	for _, child := range l.children {
+		if child.shouldClose {
+			child.destroy()
+			continue
+		}
+		if child.shouldDraw {
+			child.shouldDraw = false
+			child.render()
+		}
+		children = append(children, child)
+	}
+	clear(l.children[len(children):])
+	l.children = children
+}
+
 func (l *loop) updateCursorMode() {
 	switch {
 	case l.cursorLocked:
-		l.window.SetInputMode(glfw.CursorMode, glfw.CursorDisabled)
+		l.window.SetCursorMode(internal.CursorModeDisabled)
 	case l.cursorVisible:
-		l.window.SetInputMode(glfw.CursorMode, glfw.CursorNormal)
+		l.window.SetCursorMode(internal.CursorModeNormal)
 	default:
-		l.window.SetInputMode(glfw.CursorMode, glfw.CursorHidden)
+		l.window.SetCursorMode(internal.CursorModeHidden)
 	}
 }
 
@@ -291,114 +403,55 @@ func (l *loop) processTasks(limit time.Duration) bool {
 	return false
 }
 
-func (l *loop) onGLFWRefresh(w *glfw.Window) {
+func (l *loop) onInstanceEvent(event InstanceEvent) {
+	l.window.Focus()
+	if handler, ok := l.controller.(InstanceHandler); ok {
+		handler.OnInstanceEvent(l, event)
+	}
+}
+
+func (l *loop) onResourceEvent(event ResourceEvent) {
+	if handler, ok := l.controller.(ResourceHandler); ok {
+		handler.OnResourcesChanged(l, event)
+	}
+}
+
+func (l *loop) OnRefresh() {
 	l.controller.OnRender(l)
 	l.window.SwapBuffers()
 }
 
-func (l *loop) onGLFWSize(w *glfw.Window, width int, height int) {
+func (l *loop) OnResize(width, height int) {
 	l.controller.OnResize(l, width, height)
 }
 
-func (l *loop) onGLFWFramebufferSize(w *glfw.Window, width int, height int) {
+func (l *loop) OnFramebufferResize(width, height int) {
 	l.controller.OnFramebufferResize(l, width, height)
 }
 
-func (l *loop) onGLFWKey(w *glfw.Window, key glfw.Key, scancode int, action glfw.Action, mods glfw.ModifierKey) {
-	eventType, ok := keyboardActionMapping[action]
-	if !ok {
-		return
-	}
-	keyCode, ok := keyboardKeyMapping[key]
-	if !ok {
-		return
+func (l *loop) OnContentScaleChange(scaleX, scaleY float32) {
+	if handler, ok := l.controller.(ContentScaleHandler); ok {
+		handler.OnContentScaleChanged(l, ContentScaleEvent{
+			ScaleX: scaleX,
+			ScaleY: scaleY,
+		})
 	}
-	l.controller.OnKeyboardEvent(l, app.KeyboardEvent{
-		Action: eventType,
-		Code:   keyCode,
-	})
-}
-
-func (l *loop) onGLFWChar(w *glfw.Window, char rune) {
-	l.controller.OnKeyboardEvent(l, app.KeyboardEvent{
-		Action:    app.KeyboardActionType,
-		Character: char,
-	})
 }
 
-func (l *loop) onGLFWCursorPos(w *glfw.Window, xpos float64, ypos float64) {
-	l.controller.OnMouseEvent(l, app.MouseEvent{
-		Index:  0,
-		X:      int(xpos),
-		Y:      int(ypos),
-		Action: app.MouseActionMove,
-	})
+func (l *loop) OnCloseRequested() {
+	l.shouldStop = l.controller.OnCloseRequested(l)
 }
 
-func (l *loop) onGLFWCursorEnter(w *glfw.Window, entered bool) {
-	var eventType app.MouseAction
-	if entered {
-		eventType = app.MouseActionEnter
-	} else {
-		eventType = app.MouseActionLeave
-	}
-	xpos, ypos := l.window.GetCursorPos()
-	l.controller.OnMouseEvent(l, app.MouseEvent{
-		Index:  0,
-		X:      int(xpos),
-		Y:      int(ypos),
-		Action: eventType,
-	})
+func (l *loop) OnKeyboardEvent(event app.KeyboardEvent) {
+	l.controller.OnKeyboardEvent(l, event)
 }
 
-func (l *loop) onGLFWMouseButton(w *glfw.Window, button glfw.MouseButton, action glfw.Action, mods glfw.ModifierKey) {
-	xpos, ypos := l.window.GetCursorPos()
-	var eventType app.MouseAction
-	switch action {
-	case glfw.Press:
-		eventType = app.MouseActionDown
-	case glfw.Release:
-		eventType = app.MouseActionUp
-	}
-	var eventButton app.MouseButton
-	switch button {
-	case glfw.MouseButton1:
-		eventButton = app.MouseButtonLeft
-	case glfw.MouseButton2:
-		eventButton = app.MouseButtonRight
-	case glfw.MouseButton3:
-		eventButton = app.MouseButtonMiddle
+func (l *loop) OnCompositionEvent(action internal.CompositionAction, text string, cursor, selection int) {
+	if handler, ok := l.controller.(CompositionHandler); ok {
+		handler.OnCompositionEvent(l, newCompositionEvent(action, text, cursor, selection))
 	}
-	l.controller.OnMouseEvent(l, app.MouseEvent{
-		Index:  0,
-		X:      int(xpos),
-		Y:      int(ypos),
-		Action: eventType,
-		Button: eventButton,
-	})
-}
-
-func (l *loop) onGLFWScroll(w *glfw.Window, xoff float64, yoff float64) {
-	xpos, ypos := l.window.GetCursorPos()
-	l.controller.OnMouseEvent(l, app.MouseEvent{
-		Index:   0,
-		X:       int(xpos),
-		Y:       int(ypos),
-		Action:  app.MouseActionScroll,
-		ScrollX: xoff * 20.0,
-		ScrollY: yoff * 20.0,
-	})
 }
 
-func (l *loop) onGLFWMouseDrop(w *glfw.Window, names []string) {
-	xpos, ypos := l.window.GetCursorPos()
-	l.controller.OnMouseEvent(l, app.MouseEvent{
-		Index:  0,
-		X:      int(xpos),
-		Y:      int(ypos),
-		Action: app.MouseActionDrop,
-		Payload: app.FilepathPayload{
-			Paths: names,
-		},
-	})
+func (l *loop) OnMouseEvent(event app.MouseEvent) {
+	l.controller.OnMouseEvent(l, event)
 }
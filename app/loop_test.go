@@ -0,0 +1,185 @@
+package app
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/mokiat/lacking-native/app/internal"
+	"github.com/mokiat/lacking/app"
+)
+
+func TestLoopRunIterationRendersWhenInvalidated(t *testing.T) {
+	controller := &recordingController{}
+	l, backend, window := newTestLoop(t, controller)
+
+	l.runIteration()
+	if controller.renders != 1 || window.Swaps() != 1 {
+		t.Fatalf("expected initial frame, got %d renders and %d swaps", controller.renders, window.Swaps())
+	}
+
+	l.runIteration()
+	if controller.renders != 1 {
+		t.Fatalf("expected no frame without invalidation, got %d renders", controller.renders)
+	}
+
+	wakeups := backend.Wakeups()
+	l.Invalidate()
+	if backend.Wakeups() != wakeups+1 {
+		t.Fatalf("expected invalidation to wake the backend")
+	}
+	l.runIteration()
+	if controller.renders != 2 || window.Swaps() != 2 {
+		t.Fatalf("expected second frame, got %d renders and %d swaps", controller.renders, window.Swaps())
+	}
+}
+
+func TestLoopRunIterationDispatchesEvents(t *testing.T) {
+	controller := &recordingController{}
+	l, backend, window := newTestLoop(t, controller)
+
+	keyEvent := app.KeyboardEvent{
+		Action: app.KeyboardActionDown,
+		Code:   app.KeyCodeA,
+	}
+	mouseEvent := app.MouseEvent{
+		X:      10,
+		Y:      20,
+		Action: app.MouseActionMove,
+	}
+	backend.Inject(func() {
+		window.EventHandler().OnKeyboardEvent(keyEvent)
+		window.EventHandler().OnMouseEvent(mouseEvent)
+	})
+	if len(controller.keyboardEvents) != 0 {
+		t.Fatalf("expected events to be delayed until the next iteration")
+	}
+
+	l.runIteration()
+	if len(controller.keyboardEvents) != 1 || controller.keyboardEvents[0].Code != keyEvent.Code {
+		t.Fatalf("unexpected keyboard events: %v", controller.keyboardEvents)
+	}
+	if len(controller.mouseEvents) != 1 || controller.mouseEvents[0].X != mouseEvent.X || controller.mouseEvents[0].Y != mouseEvent.Y {
+		t.Fatalf("unexpected mouse events: %v", controller.mouseEvents)
+	}
+}
+
+func TestLoopRunIterationHandlesResize(t *testing.T) {
+	controller := &recordingController{}
+	l, backend, window := newTestLoop(t, controller)
+
+	backend.Inject(func() {
+		window.SetSize(1024, 768)
+		window.SetFramebufferSize(2048, 1536)
+		window.EventHandler().OnResize(window.Size())
+		window.EventHandler().OnFramebufferResize(window.FramebufferSize())
+	})
+	l.runIteration()
+
+	if want := [][2]int{{1024, 768}}; !slices.Equal(controller.resizes, want) {
+		t.Fatalf("expected resizes %v, got %v", want, controller.resizes)
+	}
+	if want := [][2]int{{2048, 1536}}; !slices.Equal(controller.framebufferResizes, want) {
+		t.Fatalf("expected framebuffer resizes %v, got %v", want, controller.framebufferResizes)
+	}
+	if width, height := l.FramebufferSize(); width != 2048 || height != 1536 {
+		t.Fatalf("expected framebuffer size 2048x1536, got %dx%d", width, height)
+	}
+}
+
+func TestLoopRunIterationHandlesCloseRequest(t *testing.T) {
+	controller := &recordingController{}
+	l, backend, window := newTestLoop(t, controller)
+
+	backend.Inject(func() {
+		window.EventHandler().OnCloseRequested()
+	})
+	l.runIteration()
+	if controller.closeRequests != 1 {
+		t.Fatalf("expected one close request, got %d", controller.closeRequests)
+	}
+	if l.shouldStop {
+		t.Fatalf("expected loop to keep running when the controller rejects closing")
+	}
+
+	controller.allowClose = true
+	backend.Inject(func() {
+		window.EventHandler().OnCloseRequested()
+	})
+	l.runIteration()
+	if !l.shouldStop {
+		t.Fatalf("expected loop to stop when the controller accepts closing")
+	}
+}
+
+func TestLoopRunIterationProcessesTasks(t *testing.T) {
+	controller := &recordingController{}
+	l, backend, _ := newTestLoop(t, controller)
+
+	executed := false
+	l.Schedule(func() {
+		executed = true
+	})
+	if backend.Wakeups() != 1 {
+		t.Fatalf("expected scheduling to wake the backend, got %d wakeups", backend.Wakeups())
+	}
+	l.runIteration()
+	if !executed {
+		t.Fatalf("expected scheduled task to run")
+	}
+}
+
+func newTestLoop(t *testing.T, controller app.Controller) (*loop, *internal.FakeBackend, *internal.FakeWindow) {
+	t.Helper()
+	backend := internal.NewFakeBackend()
+	window, err := backend.CreateWindow(internal.WindowInfo{
+		Title:  "test",
+		Width:  800,
+		Height: 600,
+	})
+	if err != nil {
+		t.Fatalf("failed to create window: %v", err)
+	}
+	l := newLoop(backend, window, nil, "test", controller, nil, nil, nil)
+	window.SetEventHandler(l)
+	return l, backend, window.(*internal.FakeWindow)
+}
+
+type recordingController struct {
+	app.NopController
+
+	allowClose bool
+
+	renders            int
+	closeRequests      int
+	resizes            [][2]int
+	framebufferResizes [][2]int
+	keyboardEvents     []app.KeyboardEvent
+	mouseEvents        []app.MouseEvent
+}
+
+func (c *recordingController) OnResize(window app.Window, width, height int) {
+	c.resizes = append(c.resizes, [2]int{width, height})
+}
+
+func (c *recordingController) OnFramebufferResize(window app.Window, width, height int) {
+	c.framebufferResizes = append(c.framebufferResizes, [2]int{width, height})
+}
+
+func (c *recordingController) OnKeyboardEvent(window app.Window, event app.KeyboardEvent) bool {
+	c.keyboardEvents = append(c.keyboardEvents, event)
+	return true
+}
+
+func (c *recordingController) OnMouseEvent(window app.Window, event app.MouseEvent) bool {
+	c.mouseEvents = append(c.mouseEvents, event)
+	return true
+}
+
+func (c *recordingController) OnRender(window app.Window) {
+	c.renders++
+}
+
+func (c *recordingController) OnCloseRequested(window app.Window) bool {
+	c.closeRequests++
+	return c.allowClose
+}
@@ -0,0 +1,38 @@
+package app
+
+import "github.com/mokiat/lacking/app"
+
+// ContentScaleEvent represents a notification that the content scale of
+// a window has changed. This usually happens when the window is moved to
+// a monitor with a different DPI or when the system scaling is changed.
+type ContentScaleEvent struct {
+
+	// ScaleX holds the new horizontal content scale.
+	ScaleX float32
+
+	// ScaleY holds the new vertical content scale.
+	ScaleY float32
+}
+
+// ContentScaleHandler can optionally be implemented by an app.Controller
+// in order to receive ContentScaleEvent notifications.
+type ContentScaleHandler interface {
+
+	// OnContentScaleChanged is called on the loop thread when the content
+	// scale of the window changes.
+	OnContentScaleChanged(window app.Window, event ContentScaleEvent) bool
+}
+
+// ContentScale returns the ratio between the current DPI of the specified
+// window and the platform's default DPI. User interfaces can multiply
+// their sizes by this value in order to have the same apparent size on
+// high-DPI monitors.
+//
+// A scale of one is returned for windows that were not created by
+// this package.
+func ContentScale(window app.Window) (float32, float32) {
+	if window, ok := window.(interface{ ContentScale() (float32, float32) }); ok {
+		return window.ContentScale()
+	}
+	return 1.0, 1.0
+}
@@ -0,0 +1,270 @@
+package app
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/go-gl/gl/v4.1-core/gl"
+)
+
+const (
+	traceProcessID = 1
+	traceCPUThread = 1
+	traceGPUThread = 2
+
+	// traceGPUQueryCount is the number of frames that the GPU is allowed
+	// to lag behind before timer queries start to be dropped.
+	traceGPUQueryCount = 8
+)
+
+// traceEvent represents a single entry in the Chrome Trace Event format.
+//
+// See https://docs.google.com/document/d/1CvAClvFfyA5R-PhYUmn5OOQtYMH4h6I0nSsKchNAySU
+type traceEvent struct {
+	Name      string         `json:"name"`
+	Category  string         `json:"cat,omitempty"`
+	Phase     string         `json:"ph"`
+	Timestamp int64          `json:"ts"`
+	Duration  int64          `json:"dur,omitempty"`
+	ProcessID int            `json:"pid"`
+	ThreadID  int            `json:"tid"`
+	Args      map[string]any `json:"args,omitempty"`
+}
+
+// newTraceRecorder creates a recorder that writes frame timings to the
+// specified file. The GPU lane requires a current OpenGL context.
+func newTraceRecorder(path string, gpuEnabled bool) (*traceRecorder, error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create trace file: %w", err)
+	}
+	recorder := &traceRecorder{
+		file:      file,
+		out:       bufio.NewWriter(file),
+		startTime: time.Now(),
+	}
+	if _, err := recorder.out.WriteString("[\n"); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to write trace header: %w", err)
+	}
+	recorder.writeThreadName(traceCPUThread, "CPU")
+	if gpuEnabled {
+		recorder.gpu = newTraceGPUTimer(recorder)
+		recorder.writeThreadName(traceGPUThread, "GPU")
+	}
+	return recorder, nil
+}
+
+// traceRecorder writes frame, region and GPU timings in the Chrome Trace
+// Event JSON format, which can be opened with Perfetto or chrome://tracing.
+//
+// All methods are safe to call on a nil recorder, in which case they do
+// nothing.
+type traceRecorder struct {
+	file      *os.File
+	out       *bufio.Writer
+	startTime time.Time
+	written   int
+	err       error
+
+	gpu        *traceGPUTimer
+	frameIndex int
+	frameStart time.Duration
+}
+
+// BeginFrame marks the start of a rendered frame.
+func (r *traceRecorder) BeginFrame() {
+	if r == nil {
+		return
+	}
+	r.frameStart = time.Since(r.startTime)
+	if r.gpu != nil {
+		r.gpu.Begin(r.frameIndex)
+	}
+}
+
+// EndFrame marks the end of a rendered frame.
+func (r *traceRecorder) EndFrame() {
+	if r == nil {
+		return
+	}
+	if r.gpu != nil {
+		r.gpu.End()
+		r.gpu.Collect()
+	}
+	r.writeComplete("frame", "frame", traceCPUThread, r.frameStart, time.Since(r.startTime), map[string]any{
+		"index": r.frameIndex,
+	})
+	r.frameIndex++
+}
+
+// BeginRegion starts measuring the named region. The region is recorded
+// once End is called on the returned value.
+func (r *traceRecorder) BeginRegion(name string) traceRegion {
+	if r == nil {
+		return traceRegion{}
+	}
+	return traceRegion{
+		recorder: r,
+		name:     name,
+		start:    time.Since(r.startTime),
+	}
+}
+
+// Close flushes all pending events and closes the trace file.
+func (r *traceRecorder) Close() error {
+	if r == nil {
+		return nil
+	}
+	if r.gpu != nil {
+		r.gpu.Release()
+	}
+	if r.err == nil {
+		_, r.err = r.out.WriteString("\n]\n")
+	}
+	if r.err == nil {
+		r.err = r.out.Flush()
+	}
+	if err := r.file.Close(); err != nil && r.err == nil {
+		r.err = err
+	}
+	if r.err != nil {
+		return fmt.Errorf("failed to write trace file: %w", r.err)
+	}
+	return nil
+}
+
+func (r *traceRecorder) writeThreadName(thread int, name string) {
+	r.write(traceEvent{
+		Name:      "thread_name",
+		Phase:     "M",
+		ProcessID: traceProcessID,
+		ThreadID:  thread,
+		Args: map[string]any{
+			"name": name,
+		},
+	})
+}
+
+func (r *traceRecorder) writeComplete(name, category string, thread int, start, end time.Duration, args map[string]any) {
+	r.write(traceEvent{
+		Name:      name,
+		Category:  category,
+		Phase:     "X",
+		Timestamp: start.Microseconds(),
+		Duration:  max((end - start).Microseconds(), 1),
+		ProcessID: traceProcessID,
+		ThreadID:  thread,
+		Args:      args,
+	})
+}
+
+func (r *traceRecorder) write(event traceEvent) {
+	if r.err != nil {
+		return
+	}
+	data, err := json.Marshal(event)
+	if err != nil {
+		r.err = err
+		return
+	}
+	if r.written > 0 {
+		if _, r.err = r.out.WriteString(",\n"); r.err != nil {
+			return
+		}
+	}
+	_, r.err = r.out.Write(data)
+	r.written++
+}
+
+// traceRegion represents an in-progress measurement of a named region.
+type traceRegion struct {
+	recorder *traceRecorder
+	name     string
+	start    time.Duration
+}
+
+// End records the region.
+func (r traceRegion) End() {
+	if r.recorder == nil {
+		return
+	}
+	r.recorder.writeComplete(r.name, "region", traceCPUThread, r.start, time.Since(r.recorder.startTime), nil)
+}
+
+func newTraceGPUTimer(recorder *traceRecorder) *traceGPUTimer {
+	timer := &traceGPUTimer{
+		recorder: recorder,
+	}
+	gl.GenQueries(int32(len(timer.queries)), &timer.queries[0])
+
+	// The GPU clock has an arbitrary origin, so it is aligned to the
+	// trace clock once.
+	var gpuTime int64
+	gl.GetInteger64v(gl.TIMESTAMP, &gpuTime)
+	timer.offset = time.Since(recorder.startTime) - time.Duration(gpuTime)
+	return timer
+}
+
+// traceGPUTimer measures GPU frame durations through timestamp queries.
+// Results are collected a few frames later, so that the CPU does not have
+// to wait on the GPU.
+type traceGPUTimer struct {
+	recorder *traceRecorder
+	queries  [2 * traceGPUQueryCount]uint32
+	frames   [traceGPUQueryCount]int
+	pending  [traceGPUQueryCount]bool
+	head     int
+	tail     int
+	offset   time.Duration
+}
+
+// Begin issues the start timestamp query of a frame.
+func (t *traceGPUTimer) Begin(frameIndex int) {
+	if t.pending[t.head] {
+		// The GPU is too far behind; drop the oldest measurement.
+		t.pending[t.head] = false
+		t.tail = (t.tail + 1) % traceGPUQueryCount
+	}
+	t.frames[t.head] = frameIndex
+	gl.QueryCounter(t.queries[2*t.head], gl.TIMESTAMP)
+}
+
+// End issues the end timestamp query of a frame.
+func (t *traceGPUTimer) End() {
+	gl.QueryCounter(t.queries[2*t.head+1], gl.TIMESTAMP)
+	t.pending[t.head] = true
+	t.head = (t.head + 1) % traceGPUQueryCount
+}
+
+// Collect writes the measurements of all frames whose queries have
+// completed.
+func (t *traceGPUTimer) Collect() {
+	for t.pending[t.tail] {
+		var available int32
+		gl.GetQueryObjectiv(t.queries[2*t.tail+1], gl.QUERY_RESULT_AVAILABLE, &available)
+		if available == 0 {
+			return
+		}
+		var startTime, endTime uint64
+		gl.GetQueryObjectui64v(t.queries[2*t.tail], gl.QUERY_RESULT, &startTime)
+		gl.GetQueryObjectui64v(t.queries[2*t.tail+1], gl.QUERY_RESULT, &endTime)
+		t.recorder.writeComplete("gpu frame", "gpu", traceGPUThread,
+			time.Duration(startTime)+t.offset,
+			time.Duration(endTime)+t.offset,
+			map[string]any{
+				"index": t.frames[t.tail],
+			},
+		)
+		t.pending[t.tail] = false
+		t.tail = (t.tail + 1) % traceGPUQueryCount
+	}
+}
+
+// Release deletes all timestamp queries.
+func (t *traceGPUTimer) Release() {
+	gl.DeleteQueries(int32(len(t.queries)), &t.queries[0])
+}
@@ -0,0 +1,131 @@
+package app
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/mokiat/lacking/app"
+)
+
+// resourceWatchDelay is the amount of time during which no further changes
+// should occur before a batch of changes is reported. Editors often write
+// files in multiple steps, which would otherwise produce multiple events.
+const resourceWatchDelay = 150 * time.Millisecond
+
+// ResourceEvent represents a notification that resources have changed on
+// disk while the application is running.
+type ResourceEvent struct {
+
+	// Paths holds the slash-separated paths of the changed resources,
+	// relative to the watched root directory. These are suitable for use
+	// with a resource.ReadLocator that uses the same root.
+	Paths []string
+
+	// Rescan indicates that some changes could not be tracked, for
+	// example because the operating system dropped notifications. In such
+	// cases Paths is incomplete and all resources should be considered
+	// changed.
+	Rescan bool
+}
+
+// ResourceHandler can optionally be implemented by an app.Controller
+// in order to receive ResourceEvent notifications.
+type ResourceHandler interface {
+
+	// OnResourcesChanged is called on the loop thread with a batch of
+	// resources that have changed.
+	OnResourcesChanged(window app.Window, event ResourceEvent) bool
+}
+
+// fileChange is reported by watchFiles when a file changes.
+type fileChange struct {
+
+	// path holds the absolute path of the changed file.
+	path string
+
+	// overflow indicates that changes were lost, in which case the path
+	// is empty.
+	overflow bool
+}
+
+// newResourceWatcher starts watching the specified root directory,
+// including all of its subdirectories. Batched changes are passed to the
+// specified callback from a background goroutine.
+func newResourceWatcher(root string, callback func(event ResourceEvent)) (*resourceWatcher, error) {
+	absRoot, err := filepath.Abs(root)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve watch root: %w", err)
+	}
+	changes := make(chan fileChange, 64)
+	closer, err := watchFiles(absRoot, changes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to watch %q: %w", absRoot, err)
+	}
+	watcher := &resourceWatcher{
+		root:     absRoot,
+		callback: callback,
+		closer:   closer,
+		changes:  changes,
+		done:     make(chan struct{}),
+	}
+	go watcher.run()
+	return watcher, nil
+}
+
+type resourceWatcher struct {
+	root     string
+	callback func(event ResourceEvent)
+	closer   io.Closer
+	changes  chan fileChange
+	done     chan struct{}
+}
+
+// Close stops watching for changes.
+func (w *resourceWatcher) Close() error {
+	err := w.closer.Close()
+	<-w.done
+	return err
+}
+
+func (w *resourceWatcher) run() {
+	defer close(w.done)
+
+	timer := time.NewTimer(resourceWatchDelay)
+	timer.Stop()
+
+	var (
+		pending []string
+		rescan  bool
+	)
+	for {
+		select {
+		case change, ok := <-w.changes:
+			if !ok {
+				timer.Stop()
+				return
+			}
+			if change.overflow {
+				rescan = true
+			} else if rel, err := filepath.Rel(w.root, change.path); err == nil {
+				rel = filepath.ToSlash(rel)
+				if !slices.Contains(pending, rel) {
+					pending = append(pending, rel)
+				}
+			}
+			timer.Reset(resourceWatchDelay)
+		case <-timer.C:
+			if len(pending) > 0 || rescan {
+				slices.Sort(pending)
+				w.callback(ResourceEvent{
+					Paths:  pending,
+					Rescan: rescan,
+				})
+				pending = nil
+				rescan = false
+			}
+		}
+	}
+}
@@ -0,0 +1,158 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"syscall"
+	"unsafe"
+
+	"github.com/mokiat/lacking/debug/log"
+)
+
+const inotifyMask = syscall.IN_CLOSE_WRITE |
+	syscall.IN_CREATE |
+	syscall.IN_DELETE |
+	syscall.IN_MOVED_FROM |
+	syscall.IN_MOVED_TO |
+	syscall.IN_DELETE_SELF
+
+// inotifyOverflowWd is the watch descriptor of IN_Q_OVERFLOW events, which
+// do not belong to any watch.
+const inotifyOverflowWd = -1
+
+// watchFiles uses inotify to watch the specified root directory
+// recursively. The absolute paths of changed files are sent to the
+// changes channel, which is closed once the returned io.Closer is closed.
+func watchFiles(root string, changes chan<- fileChange) (io.Closer, error) {
+	fd, err := syscall.InotifyInit1(syscall.IN_CLOEXEC | syscall.IN_NONBLOCK)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize inotify: %w", err)
+	}
+	// Wrapping the descriptor in an os.File registers it with the runtime
+	// poller, which allows Close to interrupt a blocked Read.
+	file := os.NewFile(uintptr(fd), "inotify")
+	watcher := &inotifyWatcher{
+		fd:      fd,
+		file:    file,
+		root:    root,
+		dirs:    make(map[int32]string),
+		changes: changes,
+	}
+	if err := watcher.addTree(root); err != nil {
+		file.Close()
+		return nil, err
+	}
+	go watcher.run()
+	return file, nil
+}
+
+type inotifyWatcher struct {
+	fd      int
+	file    *os.File
+	root    string
+	dirs    map[int32]string
+	changes chan<- fileChange
+}
+
+func (w *inotifyWatcher) addTree(root string) error {
+	return filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !entry.IsDir() {
+			return nil
+		}
+		wd, err := syscall.InotifyAddWatch(w.fd, path, inotifyMask)
+		if err != nil {
+			return fmt.Errorf("failed to watch %q: %w", path, err)
+		}
+		w.dirs[int32(wd)] = path
+		return nil
+	})
+}
+
+func (w *inotifyWatcher) run() {
+	defer close(w.changes)
+
+	var buffer [64 * (syscall.SizeofInotifyEvent + syscall.NAME_MAX + 1)]byte
+	for {
+		count, err := w.file.Read(buffer[:])
+		if err != nil {
+			if !errors.Is(err, os.ErrClosed) {
+				log.Error("Failed to read inotify events: %v", err)
+			}
+			return
+		}
+		for offset := 0; offset+syscall.SizeofInotifyEvent <= count; {
+			event := (*syscall.InotifyEvent)(unsafe.Pointer(&buffer[offset]))
+			nameBytes := buffer[offset+syscall.SizeofInotifyEvent : offset+syscall.SizeofInotifyEvent+int(event.Len)]
+			offset += syscall.SizeofInotifyEvent + int(event.Len)
+			w.handle(event, nameBytes)
+		}
+	}
+}
+
+func (w *inotifyWatcher) handle(event *syscall.InotifyEvent, nameBytes []byte) {
+	if event.Wd == inotifyOverflowWd && event.Mask&syscall.IN_Q_OVERFLOW != 0 {
+		// Directories might have been created while events were being
+		// dropped, so these need to be watched as well.
+		if err := w.addTree(w.root); err != nil {
+			log.Warn("Failed to watch directories after overflow: %v", err)
+		}
+		w.changes <- fileChange{
+			overflow: true,
+		}
+		return
+	}
+	dir, ok := w.dirs[event.Wd]
+	if !ok {
+		return
+	}
+	if event.Mask&(syscall.IN_DELETE_SELF|syscall.IN_IGNORED) != 0 {
+		delete(w.dirs, event.Wd)
+		return
+	}
+	name := string(nameBytes)
+	for i, b := range nameBytes {
+		if b == 0 {
+			name = string(nameBytes[:i])
+			break
+		}
+	}
+	path := filepath.Join(dir, name)
+
+	if event.Mask&syscall.IN_ISDIR != 0 {
+		if event.Mask&(syscall.IN_CREATE|syscall.IN_MOVED_TO) != 0 {
+			// Files might have been added to the new directory before the
+			// watch got established, so these are reported as well.
+			if err := w.addTree(path); err != nil {
+				log.Warn("Failed to watch new directory: %v", err)
+			}
+			w.reportTree(path)
+		}
+		return
+	}
+	if event.Mask&syscall.IN_CREATE != 0 {
+		// A subsequent IN_CLOSE_WRITE event reports the file once its
+		// contents have been written.
+		return
+	}
+	w.changes <- fileChange{
+		path: path,
+	}
+}
+
+func (w *inotifyWatcher) reportTree(root string) {
+	filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+		if err == nil && !entry.IsDir() {
+			w.changes <- fileChange{
+				path: path,
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,12 @@
+//go:build !linux
+
+package app
+
+import (
+	"errors"
+	"io"
+)
+
+func watchFiles(root string, changes chan<- fileChange) (io.Closer, error) {
+	return nil, errors.New("file watching is not supported on this platform")
+}
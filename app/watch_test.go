@@ -0,0 +1,66 @@
+package app
+
+import (
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestResourceWatcherBatchesChanges(t *testing.T) {
+	root := t.TempDir()
+	changes := make(chan fileChange, 8)
+	events := make(chan ResourceEvent, 8)
+	watcher := &resourceWatcher{
+		root: root,
+		callback: func(event ResourceEvent) {
+			events <- event
+		},
+		closer:  channelCloser(changes),
+		changes: changes,
+		done:    make(chan struct{}),
+	}
+	go watcher.run()
+	defer watcher.Close()
+
+	changes <- fileChange{path: filepath.Join(root, "shaders", "sky.glsl")}
+	changes <- fileChange{path: filepath.Join(root, "model.glb")}
+	changes <- fileChange{path: filepath.Join(root, "model.glb")}
+
+	event := awaitResourceEvent(t, events)
+	if !slices.Equal(event.Paths, []string{"model.glb", "shaders/sky.glsl"}) {
+		t.Fatalf("unexpected paths %v", event.Paths)
+	}
+	if event.Rescan {
+		t.Fatalf("expected no rescan")
+	}
+
+	changes <- fileChange{overflow: true}
+
+	event = awaitResourceEvent(t, events)
+	if !event.Rescan {
+		t.Fatalf("expected rescan after overflow")
+	}
+	if len(event.Paths) != 0 {
+		t.Fatalf("unexpected paths %v", event.Paths)
+	}
+}
+
+func awaitResourceEvent(t *testing.T, events <-chan ResourceEvent) ResourceEvent {
+	t.Helper()
+	select {
+	case event := <-events:
+		return event
+	case <-time.After(10 * resourceWatchDelay):
+		t.Fatalf("no resource event was reported")
+		return ResourceEvent{}
+	}
+}
+
+// channelCloser closes the changes channel, as watchFiles would.
+type channelCloser chan fileChange
+
+func (c channelCloser) Close() error {
+	close(c)
+	return nil
+}
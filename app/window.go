@@ -2,14 +2,16 @@ package app
 
 import (
 	"fmt"
-	"image"
+	"os"
 	"runtime"
-	"unsafe"
 
 	"github.com/go-gl/gl/v4.1-core/gl"
-	"github.com/go-gl/glfw/v3.3/glfw"
 
+	"github.com/mokiat/lacking-native/app/internal"
+	nativeaudio "github.com/mokiat/lacking-native/audio"
+	glrender "github.com/mokiat/lacking-native/render"
 	"github.com/mokiat/lacking/app"
+	"github.com/mokiat/lacking/debug/log"
 )
 
 // Run starts a new application and opens a single window.
@@ -23,90 +25,73 @@ func Run(cfg *Config, controller app.Controller) error {
 	runtime.LockOSThread()
 	defer runtime.UnlockOSThread()
 
-	if err := glfw.Init(); err != nil {
-		return fmt.Errorf("failed to initialize glfw: %w", err)
-	}
-	defer glfw.Terminate()
-
-	var (
-		windowWidth  = cfg.width
-		windowHeight = cfg.height
-		monitor      *glfw.Monitor
-	)
-	if cfg.fullscreen {
-		monitor = glfw.GetPrimaryMonitor()
-		videoMode := monitor.GetVideoMode()
-		windowWidth = videoMode.Width
-		windowHeight = videoMode.Height
-	}
-	glfw.WindowHint(glfw.ContextVersionMajor, 4)
-	glfw.WindowHint(glfw.ContextVersionMinor, 1)
-	glfw.WindowHint(glfw.OpenGLProfile, glfw.OpenGLCoreProfile)
-	glfw.WindowHint(glfw.OpenGLForwardCompatible, glfw.True)
-	glfw.WindowHint(glfw.SRGBCapable, glfw.True)
-	if cfg.maximized {
-		glfw.WindowHint(glfw.Maximized, glfw.True)
+	var instance *instanceServer
+	if cfg.instanceID != "" {
+		var err error
+		instance, err = acquireInstance(cfg.instanceID, newInstanceEvent(os.Args[1:]))
+		if err != nil {
+			return err
+		}
+		// Closing the server multiple times is safe, so this is only
+		// relevant to early returns.
+		defer instance.Close()
 	}
 
-	window, err := glfw.CreateWindow(windowWidth, windowHeight, cfg.title, monitor, nil)
+	backend, err := newBackend(cfg.windowingBackend)
 	if err != nil {
-		return fmt.Errorf("failed to create glfw window: %w", err)
+		return err
 	}
-	defer window.Destroy()
+	defer backend.Terminate()
 
-	if cfg.minWidth != nil || cfg.maxWidth != nil || cfg.minHeight != nil || cfg.maxHeight != nil {
-		minWidth := glfw.DontCare
-		if cfg.minWidth != nil {
-			minWidth = *cfg.minWidth
-		}
-		minHeight := glfw.DontCare
-		if cfg.minHeight != nil {
-			minHeight = *cfg.minHeight
-		}
-		maxWidth := glfw.DontCare
-		if cfg.maxWidth != nil {
-			maxWidth = *cfg.maxWidth
-		}
-		maxHeight := glfw.DontCare
-		if cfg.maxHeight != nil {
-			maxHeight = *cfg.maxHeight
-		}
-		window.SetSizeLimits(minWidth, minHeight, maxWidth, maxHeight)
-	}
+	checkCompositionSupport(cfg.windowingBackend, controller)
 
-	if cfg.icon != "" {
-		img, err := openImage(cfg.locator, cfg.icon)
-		if err != nil {
-			return fmt.Errorf("failed to open icon %q: %w", cfg.icon, err)
-		}
-		window.SetIcon([]image.Image{img})
+	window, err := createWindow(backend, cfg, nil)
+	if err != nil {
+		return err
 	}
+	defer window.Destroy()
 
 	window.MakeContextCurrent()
-	defer glfw.DetachCurrentContext()
-	glfw.SwapInterval(cfg.swapInterval)
+	defer backend.DetachCurrentContext()
+	backend.SetSwapInterval(cfg.swapInterval)
 
 	if err := gl.Init(); err != nil {
 		return fmt.Errorf("failed to initialize opengl: %w", err)
 	}
 
+	var debugHandler *glDebugHandler
 	if glLogger.IsDebugEnabled() {
-		gl.Enable(gl.DEBUG_OUTPUT)
-		gl.DebugMessageCallback(func(source uint32, gltype uint32, id uint32, severity uint32, length int32, message string, userParam unsafe.Pointer) {
-			switch severity {
-			case gl.DEBUG_SEVERITY_LOW:
-				glLogger.Debug(message)
-			case gl.DEBUG_SEVERITY_MEDIUM:
-				glLogger.Warn(message)
-			case gl.DEBUG_SEVERITY_HIGH:
-				glLogger.Error(message)
-			default:
-				glLogger.Debug(message)
+		debugHandler = newGLDebugHandler(cfg.glDebugSettings)
+		debugHandler.Install()
+		defer debugHandler.Uninstall()
+	}
+
+	// The tracer is created before the audio API, since the latter is
+	// only closed by the loop.
+	var tracer *traceRecorder
+	if cfg.traceFile != "" {
+		tracer, err = newTraceRecorder(cfg.traceFile, cfg.traceGPU)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			if err := tracer.Close(); err != nil {
+				log.Error("Failed to close trace: %v", err)
 			}
-		}, gl.PtrOffset(0))
+		}()
 	}
 
-	l := newLoop(cfg.locator, cfg.title, window, controller, cfg.audioEnabled)
+	var audioAPI *nativeaudio.API
+	if cfg.audioEnabled {
+		audioAPI, err = nativeaudio.NewAPIWithConfig(cfg.audioDevice)
+		if err != nil {
+			log.Error("Failed to initialize audio: %v", err)
+			audioAPI = nil
+		}
+	}
+
+	l := newLoop(backend, window, cfg.locator, cfg.title, controller, glrender.NewAPI(), audioAPI, tracer)
+	l.glDebug = debugHandler
 
 	if cfg.cursor != nil {
 		cursor := l.CreateCursor(*cfg.cursor)
@@ -119,5 +104,102 @@ func Run(cfg *Config, controller app.Controller) error {
 		l.SetCursorVisible(false)
 	}
 
-	return l.Run()
+	if instance != nil {
+		instance.Serve(func(event InstanceEvent) {
+			if !l.trySchedule(func() {
+				l.onInstanceEvent(event)
+			}) {
+				log.Warn("Dropping instance event; task queue is full")
+			}
+		})
+	}
+
+	if audioAPI != nil {
+		audioAPI.SetDeviceEventCallback(func(event nativeaudio.DeviceEvent) {
+			if !l.trySchedule(func() {
+				l.onAudioDeviceEvent(event)
+			}) {
+				log.Warn("Dropping audio device event; task queue is full")
+			}
+		})
+	}
+
+	if cfg.resourceWatching {
+		if cfg.locatorDir == "" {
+			log.Warn("Resource watching is unavailable: locator is not a file locator")
+		} else {
+			watcher, err := newResourceWatcher(cfg.locatorDir, func(event ResourceEvent) {
+				if !l.trySchedule(func() {
+					l.onResourceEvent(event)
+				}) {
+					log.Warn("Dropping resource event; task queue is full")
+				}
+			})
+			if err != nil {
+				log.Warn("Resource watching is unavailable: %v", err)
+			} else {
+				defer watcher.Close()
+			}
+		}
+	}
+
+	err = l.Run()
+	if instance != nil {
+		// Forwarded events must not be delivered to a loop whose backend
+		// is about to be terminated.
+		instance.Close()
+	}
+	return err
+}
+
+// createWindow creates a native window based on the window-related
+// settings of the specified configuration.
+func createWindow(backend internal.Backend, cfg *Config, share internal.Window) (internal.Window, error) {
+	windowInfo := internal.WindowInfo{
+		Title:      cfg.title,
+		Width:      cfg.width,
+		Height:     cfg.height,
+		Maximized:  cfg.maximized,
+		Fullscreen: cfg.fullscreen,
+		Share:      share,
+
+		ScaleToMonitor: cfg.scaleToMonitor,
+	}
+	if cfg.minWidth != nil {
+		windowInfo.MinWidth = *cfg.minWidth
+	}
+	if cfg.minHeight != nil {
+		windowInfo.MinHeight = *cfg.minHeight
+	}
+	if cfg.maxWidth != nil {
+		windowInfo.MaxWidth = *cfg.maxWidth
+	}
+	if cfg.maxHeight != nil {
+		windowInfo.MaxHeight = *cfg.maxHeight
+	}
+	window, err := backend.CreateWindow(windowInfo)
+	if err != nil {
+		return nil, err
+	}
+
+	if cfg.icon != "" {
+		img, err := openImage(cfg.locator, cfg.icon)
+		if err != nil {
+			window.Destroy()
+			return nil, fmt.Errorf("failed to open icon %q: %w", cfg.icon, err)
+		}
+		window.SetIcon(img)
+	}
+	return window, nil
+}
+
+func newBackend(kind WindowingBackend) (internal.Backend, error) {
+	switch kind {
+	case WindowingBackendGLFW:
+		return internal.NewGLFWBackend()
+	case WindowingBackendSDL2:
+		return internal.NewSDLBackend()
+	default:
+		return nil, fmt.Errorf("unknown windowing backend %d", kind)
+	}
 }
@@ -7,8 +7,24 @@ import (
 	"github.com/mokiat/lacking/audio"
 )
 
+// ResampleQuality controls the trade-off between the accuracy and the CPU
+// cost of converting media to the sample rate of the audio device.
+type ResampleQuality = internal.ResampleQuality
+
+const (
+	ResampleQualityLow    = internal.ResampleQualityLow
+	ResampleQualityMedium = internal.ResampleQualityMedium
+	ResampleQualityHigh   = internal.ResampleQualityHigh
+)
+
 func NewAPI() (*API, error) {
-	player, err := internal.NewPlayer()
+	return NewAPIWithConfig(DefaultDeviceConfig())
+}
+
+// NewAPIWithConfig creates an API that plays audio through the output
+// device that is specified by the config.
+func NewAPIWithConfig(config DeviceConfig) (*API, error) {
+	player, err := internal.NewPlayer(config)
 	if err != nil {
 		return nil, fmt.Errorf("error creating player: %w", err)
 	}
@@ -23,12 +39,30 @@ type API struct {
 	player *internal.Player
 }
 
+// SetResampleQuality specifies the quality with which media whose sample
+// rate differs from the one of the audio device is converted. It applies
+// to media that is created afterwards. The default is
+// ResampleQualityMedium.
+func (a *API) SetResampleQuality(quality ResampleQuality) {
+	a.player.SetResampleQuality(quality)
+}
+
 func (a *API) CreateMedia(info audio.MediaInfo) audio.Media {
 	return a.player.CreateMedia(info)
 }
 
+// CreateStreamingMedia creates a media that is decoded incrementally
+// during playback instead of upfront. This is suitable for long tracks,
+// like music, since playback starts almost immediately and memory usage
+// does not depend on the track length.
+//
+// Every playback of the returned media decodes the data independently.
+func (a *API) CreateStreamingMedia(info audio.MediaInfo) audio.Media {
+	return a.player.CreateStreamingMedia(info)
+}
+
 func (a *API) Play(media audio.Media, info audio.PlayInfo) audio.Playback {
-	return a.player.Play(media.(*internal.Media), info)
+	return a.player.Play(media, info)
 }
 
 func (a *API) Close() {
@@ -0,0 +1,30 @@
+package audio
+
+import (
+	"github.com/mokiat/lacking-native/audio/internal"
+	"github.com/mokiat/lacking/audio"
+)
+
+// Bus groups playbacks and other buses, so that their volume, stereo
+// balance, muting and soloing can be controlled together (e.g. separate
+// music, effects and voice volumes). Every bus routes its output to a
+// parent bus, up to the master bus.
+type Bus = internal.Bus
+
+// MasterBus returns the bus that all other buses route to. Playbacks that
+// are started with Play are routed directly to it.
+func (a *API) MasterBus() *Bus {
+	return a.player.MasterBus()
+}
+
+// CreateBus creates a new bus that routes to the specified parent bus. A
+// nil parent routes to the master bus.
+func (a *API) CreateBus(parent *Bus) *Bus {
+	return a.player.CreateBus(parent)
+}
+
+// PlayOnBus starts a playback of the specified media that routes to the
+// specified bus. A nil bus routes to the master bus.
+func (a *API) PlayOnBus(bus *Bus, media audio.Media, info audio.PlayInfo) audio.Playback {
+	return a.player.PlayOnBus(bus, media, info)
+}
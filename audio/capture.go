@@ -0,0 +1,30 @@
+package audio
+
+import "github.com/mokiat/lacking-native/audio/internal"
+
+// CaptureConfig specifies the input device and the format in which audio
+// is captured.
+type CaptureConfig = internal.CaptureConfig
+
+// Capture records audio from an input device, like a microphone. Captured
+// samples can be received through a callback or read from a buffer, can
+// be metered and can be played back through the mixer.
+type Capture = internal.Capture
+
+// ListCaptureDevices returns the audio input devices of the system. It can
+// be used before an API has been created.
+func ListCaptureDevices() ([]DeviceInfo, error) {
+	return internal.ListCaptureDevices()
+}
+
+// CaptureDevices returns the audio input devices of the system.
+func (a *API) CaptureDevices() ([]DeviceInfo, error) {
+	return a.player.CaptureDevices()
+}
+
+// OpenCapture starts capturing audio from the input device that is
+// specified by the config. The capture needs to be closed once it is no
+// longer needed. Captures are closed together with the API as well.
+func (a *API) OpenCapture(config CaptureConfig) (*Capture, error) {
+	return a.player.OpenCapture(config)
+}
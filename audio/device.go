@@ -0,0 +1,79 @@
+package audio
+
+import "github.com/mokiat/lacking-native/audio/internal"
+
+// SampleFormat specifies the format of the samples that are sent to the
+// output device.
+type SampleFormat = internal.SampleFormat
+
+const (
+	SampleFormatS16 = internal.SampleFormatS16
+	SampleFormatS32 = internal.SampleFormatS32
+	SampleFormatF32 = internal.SampleFormatF32
+)
+
+// ParseSampleFormat returns the SampleFormat with the specified name
+// (s16, s32 or f32).
+func ParseSampleFormat(name string) (SampleFormat, error) {
+	return internal.ParseSampleFormat(name)
+}
+
+// DeviceInfo describes an audio output device.
+type DeviceInfo = internal.DeviceInfo
+
+// DeviceConfig specifies the output device and the format in which audio
+// is sent to it.
+type DeviceConfig = internal.DeviceConfig
+
+// DefaultDeviceConfig returns a DeviceConfig that uses the default device
+// with 16-bit stereo output at 44100 Hz.
+func DefaultDeviceConfig() DeviceConfig {
+	return internal.DefaultDeviceConfig()
+}
+
+// ListDevices returns the audio output devices of the system. It can be
+// used before an API has been created (e.g. to populate a settings file).
+func ListDevices() ([]DeviceInfo, error) {
+	return internal.ListDevices()
+}
+
+// Devices returns the audio output devices of the system.
+func (a *API) Devices() ([]DeviceInfo, error) {
+	return a.player.Devices()
+}
+
+// DeviceConfig returns the configuration of the current output device.
+func (a *API) DeviceConfig() DeviceConfig {
+	return a.player.DeviceConfig()
+}
+
+// SetDevice switches playback to the device with the specified ID, as
+// returned by Devices. An empty ID selects the default device of the
+// system. Active playbacks continue on the new device. The sample rate
+// at which audio is mixed does not change.
+func (a *API) SetDevice(deviceID string) error {
+	return a.player.SetDevice(deviceID)
+}
+
+// DeviceEventType specifies the kind of a DeviceEvent.
+type DeviceEventType = internal.DeviceEventType
+
+const (
+	DeviceEventLost      = internal.DeviceEventLost
+	DeviceEventRecovered = internal.DeviceEventRecovered
+)
+
+// DeviceEvent is a notification about a change in the availability of the
+// output device.
+type DeviceEvent = internal.DeviceEvent
+
+// SetDeviceEventCallback specifies a callback that is notified when the
+// output device stops unexpectedly (e.g. it gets unplugged) and when
+// playback has been recovered. The player reopens the configured device,
+// or the default one if the former is gone, and active playbacks resume
+// from where they were.
+//
+// The callback is invoked from a background goroutine.
+func (a *API) SetDeviceEventCallback(callback func(event DeviceEvent)) {
+	a.player.SetDeviceEventCallback(callback)
+}
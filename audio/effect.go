@@ -0,0 +1,71 @@
+package audio
+
+import (
+	"time"
+
+	"github.com/mokiat/lacking-native/audio/internal"
+)
+
+// MediaFrame is a single stereo frame of audio, as processed by effects.
+type MediaFrame = internal.MediaFrame
+
+// Effect processes the audio of a Playback or of a Bus. Custom effects can
+// be implemented as well as the built-in ones.
+type Effect = internal.Effect
+
+// FilterType specifies the frequency response of a BiquadFilter.
+type FilterType = internal.FilterType
+
+const (
+	FilterTypeLowPass   = internal.FilterTypeLowPass
+	FilterTypeHighPass  = internal.FilterTypeHighPass
+	FilterTypeBandPass  = internal.FilterTypeBandPass
+	FilterTypeLowShelf  = internal.FilterTypeLowShelf
+	FilterTypeHighShelf = internal.FilterTypeHighShelf
+	FilterTypePeaking   = internal.FilterTypePeaking
+)
+
+// BiquadFilter is an Effect that applies a second-order filter.
+type BiquadFilter = internal.BiquadFilter
+
+// NewBiquadFilter creates a second-order filter of the specified type,
+// frequency in Hz and quality factor.
+func NewBiquadFilter(filterType FilterType, frequency, q float32) *BiquadFilter {
+	return internal.NewBiquadFilter(filterType, frequency, q)
+}
+
+// Delay is an Effect that produces echoes.
+type Delay = internal.Delay
+
+// NewDelay creates an echo effect with the specified delay time, feedback
+// and mix level.
+func NewDelay(delay time.Duration, feedback, mix float32) *Delay {
+	return internal.NewDelay(delay, feedback, mix)
+}
+
+// Reverb is an Effect that simulates the reflections of a room.
+type Reverb = internal.Reverb
+
+// NewReverb creates a reverberation effect with the specified room size,
+// damping and wet level.
+func NewReverb(roomSize, damping, wet float32) *Reverb {
+	return internal.NewReverb(roomSize, damping, wet)
+}
+
+// Compressor is an Effect that reduces the level of loud passages.
+type Compressor = internal.Compressor
+
+// NewCompressor creates a compressor with the specified threshold, in
+// decibels, and ratio.
+func NewCompressor(threshold, ratio float32) *Compressor {
+	return internal.NewCompressor(threshold, ratio)
+}
+
+// Limiter is the look-ahead peak limiter of the master output.
+type Limiter = internal.Limiter
+
+// Limiter returns the limiter of the master output, which keeps the output
+// below a ceiling without clipping.
+func (a *API) Limiter() *Limiter {
+	return a.player.Limiter()
+}
@@ -0,0 +1,319 @@
+package internal
+
+import (
+	"math"
+	"slices"
+	"sync/atomic"
+	"time"
+)
+
+func newBus(player *Player, parent *Bus) *Bus {
+	bus := &Bus{
+		player:    player,
+		parent:    parent,
+		mixParent: parent,
+		fader:     fader{level: 1.0},
+	}
+	bus.gain.Store(1.0)
+	bus.level.Store(1.0)
+	return bus
+}
+
+// Bus groups playbacks and other buses, so that they can be controlled
+// together. Every bus routes its output to a parent bus, up to the master
+// bus, which is sent to the device.
+//
+// The setters of a Bus can be called from any goroutine.
+type Bus struct {
+	player *Player
+
+	// parent and deleted are guarded by the control mutex of the player.
+	parent  *Bus
+	deleted bool
+
+	gain    atomicFloat32
+	pan     atomicFloat32
+	muted   atomic.Bool
+	soloed  atomic.Bool
+	effects effectChain
+
+	// level is the fader level as of the last block.
+	level atomicFloat32
+
+	// The following are owned by the audio thread. Parents always have a
+	// lower index than their children.
+	mixParent *Bus
+	index     int
+	removed   bool
+	fader     fader
+	buffer    []MediaFrame
+}
+
+// Parent returns the bus to which this bus routes its output. It returns
+// nil for the master bus.
+func (b *Bus) Parent() *Bus {
+	b.player.controlMU.Lock()
+	defer b.player.controlMU.Unlock()
+	return b.parent
+}
+
+// Gain returns the volume multiplier of the bus.
+func (b *Bus) Gain() float32 {
+	return b.gain.Load()
+}
+
+// SetGain specifies the volume multiplier of the bus. It affects all
+// playbacks and buses that route to this bus.
+func (b *Bus) SetGain(gain float32) {
+	b.gain.Store(max(gain, 0.0))
+}
+
+// Pan returns the stereo balance of the bus.
+func (b *Bus) Pan() float32 {
+	return b.pan.Load()
+}
+
+// SetPan specifies the stereo balance of the bus, in the range [-1, 1].
+func (b *Bus) SetPan(pan float32) {
+	b.pan.Store(max(min(pan, 1.0), -1.0))
+}
+
+// FadeLevel returns the current level of the fader of the bus. The fader
+// is applied on top of the gain of the bus.
+func (b *Bus) FadeLevel() float32 {
+	return b.level.Load()
+}
+
+// FadeTo ramps the fader of the bus from its current level to the
+// specified one over the specified duration. It replaces any fade that is
+// in progress.
+func (b *Bus) FadeTo(level float32, duration time.Duration, curve FadeCurve) {
+	b.player.commands.push(func() {
+		b.fader.fadeTo(level, duration, b.player.sampleRate, curve)
+	})
+}
+
+// Effects returns the effects that are applied to the bus, in order.
+// Effects are applied to the sum of the inputs of the bus, before its gain
+// and pan.
+func (b *Bus) Effects() []Effect {
+	return b.effects.list()
+}
+
+// SetEffects replaces the effects that are applied to the bus.
+func (b *Bus) SetEffects(effects ...Effect) {
+	b.player.controlMU.Lock()
+	defer b.player.controlMU.Unlock()
+	b.effects.set(effects)
+}
+
+// InsertEffect adds an effect at the specified position of the effect
+// chain of the bus. An index outside of the chain appends the effect.
+func (b *Bus) InsertEffect(index int, effect Effect) {
+	b.player.controlMU.Lock()
+	defer b.player.controlMU.Unlock()
+	b.effects.insert(index, effect)
+}
+
+// RemoveEffect removes an effect from the effect chain of the bus.
+func (b *Bus) RemoveEffect(effect Effect) {
+	b.player.controlMU.Lock()
+	defer b.player.controlMU.Unlock()
+	b.effects.remove(effect)
+}
+
+// Muted returns whether the bus is muted.
+func (b *Bus) Muted() bool {
+	return b.muted.Load()
+}
+
+// SetMuted specifies whether the output of the bus should be silenced.
+func (b *Bus) SetMuted(muted bool) {
+	b.muted.Store(muted)
+}
+
+// Soloed returns whether the bus is soloed.
+func (b *Bus) Soloed() bool {
+	return b.soloed.Load()
+}
+
+// SetSoloed specifies whether the bus is soloed. While any bus is soloed,
+// only playbacks on soloed buses, or on buses below a soloed bus, can be
+// heard.
+func (b *Bus) SetSoloed(soloed bool) {
+	b.soloed.Store(soloed)
+}
+
+// Delete removes the bus. Playbacks and buses that were routed to it are
+// routed to its parent instead. The master bus cannot be deleted.
+func (b *Bus) Delete() {
+	player := b.player
+	player.controlMU.Lock()
+	defer player.controlMU.Unlock()
+
+	if b.parent == nil || b.deleted {
+		return
+	}
+	for _, bus := range player.buses {
+		if bus.parent == b {
+			bus.parent = b.parent
+		}
+	}
+	player.buses = slices.DeleteFunc(player.buses, func(bus *Bus) bool {
+		return bus == b
+	})
+	b.deleted = true
+	player.commands.push(func() {
+		player.mixer.removeBus(b)
+	})
+}
+
+// busState is a snapshot of the settings of a bus that is taken once per
+// block.
+type busState struct {
+	gain    float32
+	pan     float32
+	muted   bool
+	soloed  bool
+	audible bool
+}
+
+// mixer sums the voices, which are the active playbacks, through the bus
+// hierarchy. It is owned by the audio thread and processes whole blocks of
+// frames at a time.
+type mixer struct {
+	voices  []*Playback
+	buses   []*Bus
+	states  []busState
+	scratch []MediaFrame
+	gains   []float32
+}
+
+// addVoice starts mixing the specified playback.
+func (m *mixer) addVoice(voice *Playback) {
+	m.voices = append(m.voices, voice)
+}
+
+// addBus starts mixing the specified bus. Its parent needs to be mixed
+// already.
+func (m *mixer) addBus(bus *Bus) {
+	bus.index = len(m.buses)
+	m.buses = append(m.buses, bus)
+}
+
+// removeBus stops mixing the specified bus and routes everything that was
+// routed to it to its parent instead.
+func (m *mixer) removeBus(bus *Bus) {
+	for _, candidate := range m.buses {
+		if candidate.mixParent == bus {
+			candidate.mixParent = bus.mixParent
+		}
+	}
+	for _, voice := range m.voices {
+		if voice.mixBus == bus {
+			voice.mixBus = bus.mixParent
+		}
+	}
+	m.buses = slices.DeleteFunc(m.buses, func(candidate *Bus) bool {
+		return candidate == bus
+	})
+	for i, candidate := range m.buses {
+		candidate.index = i
+	}
+	bus.removed = true
+}
+
+// resolve returns the bus that mixes what is routed to the specified bus,
+// which is an ancestor if the bus has been removed.
+func (m *mixer) resolve(bus *Bus) *Bus {
+	for bus.removed {
+		bus = bus.mixParent
+	}
+	return bus
+}
+
+// prepare takes a snapshot of the bus settings and clears the buffers of
+// the buses for a block of the specified size.
+func (m *mixer) prepare(size, sampleRate int) {
+	m.states = slices.Grow(m.states[:0], len(m.buses))[:len(m.buses)]
+	m.scratch = slices.Grow(m.scratch[:0], size)[:size]
+	m.gains = slices.Grow(m.gains[:0], size)[:size]
+
+	anySoloed := false
+	for i, bus := range m.buses {
+		soloed := bus.soloed.Load()
+		if bus.mixParent != nil && m.states[bus.mixParent.index].soloed {
+			// Buses below a soloed bus are soloed as well.
+			soloed = true
+		}
+		m.states[i] = busState{
+			gain:   bus.gain.Load(),
+			pan:    bus.pan.Load(),
+			muted:  bus.muted.Load(),
+			soloed: soloed,
+		}
+		anySoloed = anySoloed || soloed
+
+		bus.buffer = slices.Grow(bus.buffer[:0], size)[:size]
+		clear(bus.buffer)
+		bus.effects.prepare(sampleRate)
+	}
+	for i := range m.states {
+		// Buses that are not soloed still pass through the output of
+		// soloed buses below them but their own playbacks are silent.
+		m.states[i].audible = !anySoloed || m.states[i].soloed
+	}
+}
+
+// add sums a block of playback frames into the specified bus.
+func (m *mixer) add(bus *Bus, frames []MediaFrame) {
+	if m.states[bus.index].audible {
+		mixFrames(bus.buffer, frames)
+	}
+}
+
+// end routes every bus into its parent and returns the output of the
+// master bus.
+func (m *mixer) end() []MediaFrame {
+	for i := len(m.buses) - 1; i >= 0; i-- {
+		bus := m.buses[i]
+		state := m.states[i]
+		bus.effects.process(bus.buffer)
+		pan := newPanMatrix(state.pan)
+		for j := range bus.buffer {
+			level, _ := bus.fader.step()
+			if state.muted {
+				bus.buffer[j] = MediaFrame{}
+			} else {
+				bus.buffer[j].ApplyGain(state.gain * level)
+				bus.buffer[j] = pan.apply(bus.buffer[j])
+			}
+		}
+		bus.level.Store(bus.fader.level)
+		if parent := bus.mixParent; parent != nil {
+			mixFrames(parent.buffer, bus.buffer)
+		}
+	}
+	return m.buses[0].buffer
+}
+
+// mixFrames adds the source frames to the target frames.
+func mixFrames(target, source []MediaFrame) {
+	target = target[:len(source)]
+	for i, frame := range source {
+		target[i].Add(frame)
+	}
+}
+
+// atomicFloat32 is a float32 value that can be accessed atomically.
+type atomicFloat32 struct {
+	bits atomic.Uint32
+}
+
+func (f *atomicFloat32) Load() float32 {
+	return math.Float32frombits(f.bits.Load())
+}
+
+func (f *atomicFloat32) Store(value float32) {
+	f.bits.Store(math.Float32bits(value))
+}
@@ -0,0 +1,328 @@
+package internal
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"sync/atomic"
+	"time"
+
+	"github.com/gen2brain/malgo"
+	"github.com/mokiat/gblob"
+)
+
+const (
+	// defaultCaptureBufferDuration is the default amount of audio that a
+	// Capture keeps for Read.
+	defaultCaptureBufferDuration = time.Second
+
+	// captureLoopbackLatency is the amount of captured audio that is
+	// buffered before a loopback starts playing. It absorbs the differences
+	// in the periods and the clocks of the input and the output devices.
+	captureLoopbackLatency = 30 * time.Millisecond
+
+	// captureLoopbackBufferDuration is the size of the buffer between the
+	// input device and a loopback.
+	captureLoopbackBufferDuration = 250 * time.Millisecond
+)
+
+// CaptureConfig specifies the input device and the format in which audio
+// is captured. Zero values select defaults.
+type CaptureConfig struct {
+
+	// DeviceID selects the input device. An empty value selects the
+	// default device of the system.
+	DeviceID string
+
+	// SampleRate specifies the rate, in Hz, at which audio is captured.
+	// The default is the sample rate of the player.
+	SampleRate int
+
+	// Channels specifies the number of captured channels. The default is 1.
+	Channels int
+
+	// PeriodSize specifies the number of frames that are delivered at once.
+	// A zero value lets the audio backend decide.
+	PeriodSize int
+
+	// BufferSize specifies the number of frames that are kept for Read.
+	// Frames that do not fit are dropped. The default is one second.
+	BufferSize int
+}
+
+func (c CaptureConfig) withDefaults(sampleRate int) CaptureConfig {
+	if c.SampleRate <= 0 {
+		c.SampleRate = sampleRate
+	}
+	if c.Channels <= 0 {
+		c.Channels = 1
+	}
+	if c.BufferSize <= 0 {
+		c.BufferSize = durationToFrames(defaultCaptureBufferDuration, c.SampleRate)
+	}
+	return c
+}
+
+// CaptureDevices returns the audio input devices of the system. An offline
+// player has none.
+func (p *Player) CaptureDevices() ([]DeviceInfo, error) {
+	if p.offline {
+		return nil, nil
+	}
+	return listDevices(p.ctx.Context, malgo.Capture)
+}
+
+// OpenCapture starts capturing audio from the input device that is
+// specified by the config.
+func (p *Player) OpenCapture(config CaptureConfig) (*Capture, error) {
+	if p.offline {
+		return nil, fmt.Errorf("offline player has no device")
+	}
+
+	// The device mutex keeps the player from being closed meanwhile.
+	p.deviceMU.Lock()
+	defer p.deviceMU.Unlock()
+	if p.closing.Load() {
+		return nil, fmt.Errorf("player is closed")
+	}
+	config = config.withDefaults(p.sampleRate)
+
+	capture := &Capture{
+		player:  p,
+		config:  config,
+		samples: newRingBuffer[float32](config.BufferSize * config.Channels),
+	}
+	capture.peak.Store(gainToDecibels(0.0))
+	capture.level.Store(gainToDecibels(0.0))
+
+	deviceConfig := malgo.DefaultDeviceConfig(malgo.Capture)
+	deviceConfig.Capture.Format = malgo.FormatF32
+	deviceConfig.Capture.Channels = uint32(config.Channels)
+	deviceConfig.SampleRate = uint32(config.SampleRate)
+	deviceConfig.PeriodSizeInFrames = uint32(config.PeriodSize)
+	deviceConfig.Alsa.NoMMap = 1
+	if config.DeviceID != "" {
+		id, err := findDevice(p.ctx.Context, malgo.Capture, config.DeviceID)
+		if err != nil {
+			return nil, err
+		}
+		deviceConfig.Capture.DeviceID = id.Pointer()
+	}
+
+	deviceCallbacks := malgo.DeviceCallbacks{
+		Data: capture.onSamples,
+	}
+
+	device, err := malgo.InitDevice(p.ctx.Context, deviceConfig, deviceCallbacks)
+	if err != nil {
+		return nil, fmt.Errorf("error creating malgo capture device: %w", err)
+	}
+	if err := device.Start(); err != nil {
+		device.Uninit()
+		return nil, fmt.Errorf("error starting malgo capture device: %w", err)
+	}
+	capture.device = device
+
+	p.controlMU.Lock()
+	defer p.controlMU.Unlock()
+	p.captures = append(p.captures, capture)
+	return capture, nil
+}
+
+// Capture records audio from an input device. The captured samples can be
+// received through a callback or read from a buffer, as well as played
+// back through the mixer.
+//
+// The methods of a Capture can be called from any goroutine.
+type Capture struct {
+	player *Player
+	config CaptureConfig
+	device *malgo.Device
+	closed atomic.Bool
+
+	samples  *ringBuffer[float32]
+	callback atomic.Pointer[func(samples []float32)]
+	loopback atomic.Pointer[captureSource]
+	peak     atomicFloat32
+	level    atomicFloat32
+
+	// The following are owned by the capture thread.
+	block  []float32
+	frames []MediaFrame
+}
+
+// Config returns the configuration of the capture, with defaults applied.
+func (c *Capture) Config() CaptureConfig {
+	return c.config
+}
+
+// SetCallback specifies a callback that receives every block of captured
+// samples, interleaved by channel. The callback is invoked on the capture
+// thread, so it should not block, and the slice is only valid during the
+// call. A nil callback removes it.
+func (c *Capture) SetCallback(callback func(samples []float32)) {
+	if callback == nil {
+		c.callback.Store(nil)
+	} else {
+		c.callback.Store(&callback)
+	}
+}
+
+// Available returns the number of captured frames that can be read.
+func (c *Capture) Available() int {
+	return c.samples.len() / c.config.Channels
+}
+
+// Read moves captured samples, interleaved by channel, to the specified
+// slice and returns the number of frames that were read. Only whole frames
+// are read. Samples that are not read in time are dropped, once the buffer
+// is full.
+func (c *Capture) Read(samples []float32) int {
+	channels := c.config.Channels
+	return c.samples.read(samples[:len(samples)/channels*channels]) / channels
+}
+
+// Peak returns the highest sample level, in decibels, of the last block
+// of captured samples.
+func (c *Capture) Peak() float32 {
+	return c.peak.Load()
+}
+
+// Level returns the RMS level, in decibels, of the last block of captured
+// samples. It reflects the loudness of the input and can be compared
+// against a threshold to detect speech or blowing into the microphone.
+func (c *Capture) Level() float32 {
+	return c.level.Load()
+}
+
+// Loopback starts a playback of the captured audio on the specified bus.
+// A nil bus routes to the master bus. The playback can be controlled like
+// any other, including effects and emitters, and stopping it ends the
+// loopback. Starting a new loopback stops the previous one.
+//
+// The loopback is delayed by a few tens of milliseconds, which are needed
+// to keep the input and the output devices in sync.
+func (c *Capture) Loopback(bus *Bus) *Playback {
+	source := &captureSource{
+		frames:  newRingBuffer[MediaFrame](durationToFrames(captureLoopbackBufferDuration, c.config.SampleRate)),
+		latency: max(durationToFrames(captureLoopbackLatency, c.config.SampleRate), c.config.PeriodSize),
+	}
+	if previous := c.loopback.Swap(source); previous != nil {
+		previous.close()
+	}
+	if c.closed.Load() {
+		source.close()
+	}
+
+	player := c.player
+	playback := newPlayback(player, 1.0, 0.0, false)
+	playback.media = &Media{sampleRate: c.config.SampleRate}
+	playback.capture = source
+	playback.rateRatio = float32(c.config.SampleRate) / float32(player.sampleRate)
+	player.startPlayback(bus, playback)
+	return playback
+}
+
+// Close stops capturing and releases the input device. Any loopback ends.
+func (c *Capture) Close() {
+	if !c.closed.CompareAndSwap(false, true) {
+		return
+	}
+	c.device.Stop()
+	c.device.Uninit()
+	if source := c.loopback.Swap(nil); source != nil {
+		source.close()
+	}
+
+	player := c.player
+	player.controlMU.Lock()
+	defer player.controlMU.Unlock()
+	player.captures = slices.DeleteFunc(player.captures, func(candidate *Capture) bool {
+		return candidate == c
+	})
+}
+
+func (c *Capture) onSamples(pOutputSample, pInputSamples []byte, framecount uint32) {
+	channels := c.config.Channels
+	count := int(framecount) * channels
+	c.block = slices.Grow(c.block[:0], count)[:count]
+
+	input := gblob.LittleEndianBlock(pInputSamples)
+	var (
+		peak float32
+		sum  float64
+	)
+	for i := range c.block {
+		sample := input.Float32(i * 4)
+		c.block[i] = sample
+		peak = max(peak, abs(sample))
+		sum += float64(sample) * float64(sample)
+	}
+	c.peak.Store(gainToDecibels(peak))
+	c.level.Store(gainToDecibels(float32(math.Sqrt(sum / float64(max(count, 1))))))
+
+	if callback := c.callback.Load(); callback != nil {
+		(*callback)(c.block)
+	}
+	c.samples.write(c.block)
+
+	if source := c.loopback.Load(); source != nil && source.closed.Load() {
+		// The loopback playback has been stopped.
+		c.loopback.CompareAndSwap(source, nil)
+	} else if source != nil {
+		c.frames = slices.Grow(c.frames[:0], int(framecount))[:framecount]
+		for i := range c.frames {
+			frame := c.block[i*channels : (i+1)*channels]
+			if channels == 1 {
+				c.frames[i] = MediaFrame{Left: frame[0], Right: frame[0]}
+			} else {
+				c.frames[i] = MediaFrame{Left: frame[0], Right: frame[1]}
+			}
+		}
+		source.frames.write(c.frames)
+	}
+}
+
+// captureSource passes captured frames to a loopback playback. The capture
+// thread is the only writer and the audio thread is the only reader.
+type captureSource struct {
+	frames  *ringBuffer[MediaFrame]
+	latency int
+	closed  atomic.Bool
+
+	// playing is owned by the audio thread. It is false while the buffer
+	// fills up to the latency.
+	playing bool
+}
+
+// frame returns the next captured frame. It returns false once the capture
+// or the loopback has been closed.
+func (s *captureSource) frame() (MediaFrame, bool) {
+	if s.closed.Load() {
+		return MediaFrame{}, false
+	}
+	available := s.frames.len()
+	if !s.playing {
+		if available < s.latency {
+			return MediaFrame{}, true
+		}
+		s.playing = true
+	}
+	if available > 3*s.latency {
+		// The input device is ahead of the output device, so the delay
+		// is brought back to the latency.
+		s.frames.skip(available - s.latency)
+	}
+	var frame [1]MediaFrame
+	if s.frames.read(frame[:]) == 0 {
+		// The output device is ahead of the input device, so the buffer
+		// needs to fill up again.
+		s.playing = false
+		return MediaFrame{}, true
+	}
+	return frame[0], true
+}
+
+func (s *captureSource) close() {
+	s.closed.Store(true)
+}
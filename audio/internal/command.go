@@ -0,0 +1,80 @@
+package internal
+
+import "sync/atomic"
+
+// commandNode is an entry of a commandQueue.
+type commandNode struct {
+	next    atomic.Pointer[commandNode]
+	command func()
+}
+
+// commandQueue passes changes from other goroutines to the audio thread.
+// Any number of goroutines can push commands without blocking, while only
+// the audio thread pops them.
+//
+// This is the intrusive multi-producer single-consumer queue by Dmitry
+// Vyukov. Pushing is wait-free and popping never blocks, although it can
+// miss a command whose push is still in progress until the next period.
+type commandQueue struct {
+	head atomic.Pointer[commandNode]
+	tail *commandNode
+	stub commandNode
+}
+
+func (q *commandQueue) init() {
+	q.head.Store(&q.stub)
+	q.tail = &q.stub
+}
+
+// push schedules the command to be run by the audio thread.
+func (q *commandQueue) push(command func()) {
+	q.pushNode(&commandNode{
+		command: command,
+	})
+}
+
+func (q *commandQueue) pushNode(node *commandNode) {
+	node.next.Store(nil)
+	previous := q.head.Swap(node)
+	previous.next.Store(node)
+}
+
+// run executes all commands that have been pushed so far. It must only be
+// called by the audio thread, or while no audio thread is running.
+func (q *commandQueue) run() {
+	for {
+		node := q.pop()
+		if node == nil {
+			return
+		}
+		node.command()
+	}
+}
+
+func (q *commandQueue) pop() *commandNode {
+	tail := q.tail
+	next := tail.next.Load()
+	if tail == &q.stub {
+		if next == nil {
+			return nil
+		}
+		q.tail = next
+		tail = next
+		next = next.next.Load()
+	}
+	if next != nil {
+		q.tail = next
+		return tail
+	}
+	if tail != q.head.Load() {
+		// A push is in progress.
+		return nil
+	}
+	q.pushNode(&q.stub)
+	next = tail.next.Load()
+	if next != nil {
+		q.tail = next
+		return tail
+	}
+	return nil
+}
@@ -0,0 +1,139 @@
+package internal
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// NewCompressor creates a dynamic range compressor that reduces the level
+// of the signal above the specified threshold, in decibels, by the
+// specified ratio.
+func NewCompressor(threshold, ratio float32) *Compressor {
+	result := &Compressor{
+		envelope: -120.0,
+	}
+	result.threshold.Store(min(threshold, 0.0))
+	result.ratio.Store(max(ratio, 1.0))
+	result.attack.Store(int64(10 * time.Millisecond))
+	result.release.Store(int64(100 * time.Millisecond))
+	return result
+}
+
+// Compressor is an Effect that evens out the loudness of a signal by
+// reducing the level of loud passages.
+//
+// The setters of a Compressor can be called from any goroutine.
+type Compressor struct {
+	threshold atomicFloat32
+	ratio     atomicFloat32
+	makeup    atomicFloat32
+	attack    atomic.Int64
+	release   atomic.Int64
+	reduction atomicFloat32
+
+	// The following are owned by the audio thread.
+	envelope       float32
+	thresholdValue float32
+	slope          float32
+	makeupGain     float32
+	attackCoeff    float32
+	releaseCoeff   float32
+	peakReduction  float32
+}
+
+var _ Effect = (*Compressor)(nil)
+
+// Threshold returns the level above which the signal is compressed.
+func (c *Compressor) Threshold() float32 {
+	return c.threshold.Load()
+}
+
+// SetThreshold specifies the level, in decibels, above which the signal
+// is compressed.
+func (c *Compressor) SetThreshold(threshold float32) {
+	c.threshold.Store(min(threshold, 0.0))
+}
+
+// Ratio returns the compression ratio.
+func (c *Compressor) Ratio() float32 {
+	return c.ratio.Load()
+}
+
+// SetRatio specifies by how much the level above the threshold is reduced.
+// A ratio of 4 turns 4 dB above the threshold into 1 dB.
+func (c *Compressor) SetRatio(ratio float32) {
+	c.ratio.Store(max(ratio, 1.0))
+}
+
+// Makeup returns the gain that is applied after compression.
+func (c *Compressor) Makeup() float32 {
+	return c.makeup.Load()
+}
+
+// SetMakeup specifies the gain, in decibels, that is applied after
+// compression to compensate for the lost loudness.
+func (c *Compressor) SetMakeup(makeup float32) {
+	c.makeup.Store(makeup)
+}
+
+// Attack returns the time in which the compressor reacts to a louder
+// signal.
+func (c *Compressor) Attack() time.Duration {
+	return time.Duration(c.attack.Load())
+}
+
+// SetAttack specifies the time in which the compressor reacts to a louder
+// signal. The default is 10 ms.
+func (c *Compressor) SetAttack(attack time.Duration) {
+	c.attack.Store(int64(max(attack, 0)))
+}
+
+// Release returns the time in which the compressor recovers once the
+// signal gets quieter.
+func (c *Compressor) Release() time.Duration {
+	return time.Duration(c.release.Load())
+}
+
+// SetRelease specifies the time in which the compressor recovers once the
+// signal gets quieter. The default is 100 ms.
+func (c *Compressor) SetRelease(release time.Duration) {
+	c.release.Store(int64(max(release, 0)))
+}
+
+// Reduction returns the highest gain reduction, in decibels, that was
+// applied during the last block of frames. It can be used for metering.
+func (c *Compressor) Reduction() float32 {
+	return c.reduction.Load()
+}
+
+func (c *Compressor) Prepare(sampleRate int) {
+	c.reduction.Store(c.peakReduction)
+	c.peakReduction = 0.0
+	c.thresholdValue = c.threshold.Load()
+	c.slope = 1.0 - 1.0/c.ratio.Load()
+	c.makeupGain = decibelsToGain(c.makeup.Load())
+	c.attackCoeff = smoothingCoefficient(time.Duration(c.attack.Load()).Seconds(), sampleRate)
+	c.releaseCoeff = smoothingCoefficient(time.Duration(c.release.Load()).Seconds(), sampleRate)
+}
+
+func (c *Compressor) Process(frame MediaFrame) MediaFrame {
+	// The level is detected on the louder channel and both channels are
+	// reduced by the same amount, so that the stereo image is preserved.
+	level := gainToDecibels(max(abs(frame.Left), abs(frame.Right)))
+	if level > c.envelope {
+		c.envelope += (level - c.envelope) * c.attackCoeff
+	} else {
+		c.envelope += (level - c.envelope) * c.releaseCoeff
+	}
+	reduction := max(c.envelope-c.thresholdValue, 0.0) * c.slope
+	c.peakReduction = max(c.peakReduction, reduction)
+	frame.ApplyGain(decibelsToGain(-reduction) * c.makeupGain)
+	return frame
+}
+
+func abs(value float32) float32 {
+	if value < 0.0 {
+		return -value
+	}
+	return value
+}
@@ -0,0 +1,86 @@
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"io"
+)
+
+// decoder produces stereo frames from encoded audio data.
+type decoder interface {
+
+	// SampleRate returns the sample rate of the decoded audio.
+	SampleRate() int
+
+	// Length returns the total number of frames.
+	Length() int
+
+	// Read decodes frames into the specified slice. It returns io.EOF
+	// once all frames have been decoded.
+	Read(frames []MediaFrame) (int, error)
+
+	// Seek moves the decoding position to the specified frame.
+	Seek(frame int) error
+}
+
+// newDecoder creates a decoder for the specified encoded data. The format
+// is detected from the leading magic bytes.
+func newDecoder(data []byte) (decoder, error) {
+	// ID3v2 tags are mostly used by MP3 files, but some tools prepend them
+	// to other formats as well, so the format is detected after the tag.
+	body := skipID3v2(data)
+	switch {
+	case isWAV(body):
+		return newWAVDecoder(body)
+	case isFLAC(body):
+		return newFLACDecoder(body)
+	case isOgg(body):
+		return newOggDecoder(body)
+	case isMP3(data):
+		return newMP3Decoder(data)
+	default:
+		return nil, fmt.Errorf("unsupported audio format")
+	}
+}
+
+// skipID3v2 returns the data that follows the ID3v2 tag at the start of
+// the specified data. The data is returned unchanged if it has no tag.
+func skipID3v2(data []byte) []byte {
+	const headerSize = 10
+	if len(data) < headerSize || string(data[:3]) != "ID3" {
+		return data
+	}
+	// The size is stored as a synchsafe integer, with seven bits per byte,
+	// and excludes the header and the optional footer.
+	size := 0
+	for _, b := range data[6:headerSize] {
+		if b&0x80 != 0 {
+			return data
+		}
+		size = size<<7 | int(b)
+	}
+	size += headerSize
+	if data[5]&0x10 != 0 {
+		size += headerSize
+	}
+	if size > len(data) {
+		return data
+	}
+	return data[size:]
+}
+
+// decodeAll decodes all frames of the specified decoder.
+func decodeAll(dec decoder) ([]MediaFrame, error) {
+	result := make([]MediaFrame, 0, max(dec.Length(), 0))
+	chunk := make([]MediaFrame, 4096)
+	for {
+		count, err := dec.Read(chunk)
+		result = append(result, chunk[:count]...)
+		if errors.Is(err, io.EOF) {
+			return result, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package internal
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// NewDelay creates an echo effect that repeats the input after the
+// specified time. The feedback controls how much of every repetition is
+// repeated again and the mix controls the level of the repetitions
+// relative to the input.
+func NewDelay(delay time.Duration, feedback, mix float32) *Delay {
+	result := &Delay{}
+	result.SetDelay(delay)
+	result.feedback.Store(max(min(feedback, 0.99), 0.0))
+	result.mix.Store(max(min(mix, 1.0), 0.0))
+	return result
+}
+
+// Delay is an Effect that produces echoes.
+//
+// The setters of a Delay can be called from any goroutine.
+type Delay struct {
+	delay    atomic.Int64
+	feedback atomicFloat32
+	mix      atomicFloat32
+
+	// The buffer is allocated outside of the audio thread and is handed
+	// over through pending whenever a longer delay is requested.
+	bufferMU     sync.Mutex
+	bufferLength int
+	pending      atomic.Pointer[[]MediaFrame]
+
+	// The following are owned by the audio thread.
+	buffer        []MediaFrame
+	position      int
+	frames        int
+	feedbackValue float32
+	mixValue      float32
+}
+
+var _ Effect = (*Delay)(nil)
+
+// Delay returns the time after which the input is repeated.
+func (d *Delay) Delay() time.Duration {
+	return time.Duration(d.delay.Load())
+}
+
+// SetDelay specifies the time after which the input is repeated.
+func (d *Delay) SetDelay(delay time.Duration) {
+	delay = max(delay, 0)
+	length := max(durationToFrames(delay, maxEffectSampleRate), 1)
+
+	d.bufferMU.Lock()
+	if length > d.bufferLength {
+		buffer := make([]MediaFrame, length)
+		d.pending.Store(&buffer)
+		d.bufferLength = length
+	}
+	d.bufferMU.Unlock()
+
+	d.delay.Store(int64(delay))
+}
+
+// Feedback returns the portion of every repetition that is repeated again.
+func (d *Delay) Feedback() float32 {
+	return d.feedback.Load()
+}
+
+// SetFeedback specifies the portion of every repetition that is repeated
+// again, in the range [0, 0.99].
+func (d *Delay) SetFeedback(feedback float32) {
+	d.feedback.Store(max(min(feedback, 0.99), 0.0))
+}
+
+// Mix returns the level of the repetitions relative to the input.
+func (d *Delay) Mix() float32 {
+	return d.mix.Load()
+}
+
+// SetMix specifies the level of the repetitions relative to the input, in
+// the range [0, 1].
+func (d *Delay) SetMix(mix float32) {
+	d.mix.Store(max(min(mix, 1.0), 0.0))
+}
+
+func (d *Delay) Prepare(sampleRate int) {
+	if buffer := d.pending.Swap(nil); buffer != nil {
+		// The buffer only grows when a longer delay is requested, so
+		// that existing echoes are preserved.
+		copy(*buffer, d.buffer[d.position:])
+		copy((*buffer)[len(d.buffer)-d.position:], d.buffer[:d.position])
+		d.position = len(d.buffer)
+		d.buffer = *buffer
+	}
+	frames := durationToFrames(time.Duration(d.delay.Load()), sampleRate)
+	d.frames = max(min(frames, len(d.buffer)), 1)
+	d.feedbackValue = d.feedback.Load()
+	d.mixValue = d.mix.Load()
+}
+
+func (d *Delay) Process(frame MediaFrame) MediaFrame {
+	// The read position trails the write position by the delay.
+	read := d.position - d.frames
+	if read < 0 {
+		read += len(d.buffer)
+	}
+	echo := d.buffer[read]
+	d.buffer[d.position] = MediaFrame{
+		Left:  frame.Left + echo.Left*d.feedbackValue,
+		Right: frame.Right + echo.Right*d.feedbackValue,
+	}
+	d.position = (d.position + 1) % len(d.buffer)
+	return MediaFrame{
+		Left:  frame.Left + echo.Left*d.mixValue,
+		Right: frame.Right + echo.Right*d.mixValue,
+	}
+}
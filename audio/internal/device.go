@@ -0,0 +1,265 @@
+package internal
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/gen2brain/malgo"
+	"github.com/mokiat/gblob"
+)
+
+// SampleFormat specifies the format of the samples that are sent to the
+// output device.
+type SampleFormat int
+
+const (
+	// SampleFormatS16 uses signed 16-bit integer samples. This is the
+	// default.
+	SampleFormatS16 SampleFormat = iota
+
+	// SampleFormatS32 uses signed 32-bit integer samples.
+	SampleFormatS32
+
+	// SampleFormatF32 uses 32-bit floating point samples, which avoids
+	// quantization on devices that mix in floating point.
+	SampleFormatF32
+)
+
+// String returns the name of the sample format.
+func (f SampleFormat) String() string {
+	switch f {
+	case SampleFormatS16:
+		return "s16"
+	case SampleFormatS32:
+		return "s32"
+	case SampleFormatF32:
+		return "f32"
+	default:
+		return fmt.Sprintf("unknown(%d)", int(f))
+	}
+}
+
+// ParseSampleFormat returns the SampleFormat with the specified name.
+func ParseSampleFormat(name string) (SampleFormat, error) {
+	switch name {
+	case "s16":
+		return SampleFormatS16, nil
+	case "s32":
+		return SampleFormatS32, nil
+	case "f32":
+		return SampleFormatF32, nil
+	default:
+		return 0, fmt.Errorf("unknown sample format %q", name)
+	}
+}
+
+func (f SampleFormat) malgoFormat() malgo.FormatType {
+	switch f {
+	case SampleFormatS32:
+		return malgo.FormatS32
+	case SampleFormatF32:
+		return malgo.FormatF32
+	default:
+		return malgo.FormatS16
+	}
+}
+
+func (f SampleFormat) size() int {
+	switch f {
+	case SampleFormatS32, SampleFormatF32:
+		return 4
+	default:
+		return 2
+	}
+}
+
+const (
+	// deviceRecoveryMinDelay is the time to wait before trying to reopen
+	// a lost device. Disconnects are often followed by further system
+	// events, which is why the first attempt is not immediate.
+	deviceRecoveryMinDelay = 250 * time.Millisecond
+
+	// deviceRecoveryMaxDelay limits the time between attempts to reopen
+	// a lost device.
+	deviceRecoveryMaxDelay = 5 * time.Second
+)
+
+// DeviceEventType specifies the kind of a DeviceEvent.
+type DeviceEventType int
+
+const (
+	// DeviceEventLost indicates that the output device stopped
+	// unexpectedly (e.g. it got unplugged). Playbacks are paused until
+	// a device is recovered.
+	DeviceEventLost DeviceEventType = iota
+
+	// DeviceEventRecovered indicates that playbacks have resumed. This
+	// may be on the default device, if the configured one is no longer
+	// available.
+	DeviceEventRecovered
+)
+
+// DeviceEvent is a notification about a change in the availability of the
+// output device.
+type DeviceEvent struct {
+
+	// Type specifies the kind of change.
+	Type DeviceEventType
+
+	// DeviceID identifies the lost device or the device on which playback
+	// has resumed. An empty value stands for the system default device.
+	DeviceID string
+}
+
+// DeviceInfo describes an audio output device.
+type DeviceInfo struct {
+
+	// ID identifies the device. It can be used with DeviceConfig.DeviceID.
+	ID string
+
+	// Name is a human-readable name of the device.
+	Name string
+
+	// Default indicates whether this is the default device of the system.
+	Default bool
+}
+
+// DeviceConfig specifies the output device and the format in which audio
+// is sent to it. Zero values select defaults.
+type DeviceConfig struct {
+
+	// DeviceID selects the output device. An empty value selects the
+	// default device of the system.
+	DeviceID string
+
+	// SampleRate specifies the rate, in Hz, at which audio is mixed. Media
+	// is converted to this rate. The default is 44100.
+	SampleRate int
+
+	// Channels specifies the number of output channels. Mono output is a
+	// downmix and channels beyond the second are silent. The default is 2.
+	Channels int
+
+	// Format specifies the sample format of the output.
+	Format SampleFormat
+
+	// PeriodSize specifies the number of frames that are mixed at once.
+	// Smaller values reduce latency at the cost of CPU usage and of a
+	// higher risk of audible glitches. A zero value lets the audio
+	// backend decide.
+	PeriodSize int
+
+	// Periods specifies the number of periods that the device buffers. A
+	// zero value lets the audio backend decide.
+	Periods int
+}
+
+// DefaultDeviceConfig returns a DeviceConfig that uses the default device
+// with 16-bit stereo output at 44100 Hz.
+func DefaultDeviceConfig() DeviceConfig {
+	return DeviceConfig{
+		SampleRate: 44100,
+		Channels:   2,
+		Format:     SampleFormatS16,
+	}
+}
+
+func (c DeviceConfig) withDefaults() DeviceConfig {
+	defaults := DefaultDeviceConfig()
+	if c.SampleRate <= 0 {
+		c.SampleRate = defaults.SampleRate
+	}
+	if c.Channels <= 0 {
+		c.Channels = defaults.Channels
+	}
+	return c
+}
+
+// ListDevices returns the audio output devices of the system.
+func ListDevices() ([]DeviceInfo, error) {
+	ctx, err := malgo.InitContext(nil, malgo.ContextConfig{}, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error creating malgo context: %w", err)
+	}
+	defer func() {
+		ctx.Uninit()
+		ctx.Free()
+	}()
+	return listDevices(ctx.Context, malgo.Playback)
+}
+
+// ListCaptureDevices returns the audio input devices of the system.
+func ListCaptureDevices() ([]DeviceInfo, error) {
+	ctx, err := malgo.InitContext(nil, malgo.ContextConfig{}, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error creating malgo context: %w", err)
+	}
+	defer func() {
+		ctx.Uninit()
+		ctx.Free()
+	}()
+	return listDevices(ctx.Context, malgo.Capture)
+}
+
+func listDevices(ctx malgo.Context, kind malgo.DeviceType) ([]DeviceInfo, error) {
+	infos, err := ctx.Devices(kind)
+	if err != nil {
+		return nil, fmt.Errorf("error listing %s devices: %w", deviceKindName(kind), err)
+	}
+	result := make([]DeviceInfo, len(infos))
+	for i, info := range infos {
+		result[i] = DeviceInfo{
+			ID:      info.ID.String(),
+			Name:    info.Name(),
+			Default: info.IsDefault != 0,
+		}
+	}
+	return result, nil
+}
+
+func findDevice(ctx malgo.Context, kind malgo.DeviceType, id string) (malgo.DeviceID, error) {
+	infos, err := ctx.Devices(kind)
+	if err != nil {
+		return malgo.DeviceID{}, fmt.Errorf("error listing %s devices: %w", deviceKindName(kind), err)
+	}
+	for _, info := range infos {
+		if info.ID.String() == id {
+			return info.ID, nil
+		}
+	}
+	return malgo.DeviceID{}, fmt.Errorf("%s device %q not found", deviceKindName(kind), id)
+}
+
+func deviceKindName(kind malgo.DeviceType) string {
+	if kind == malgo.Capture {
+		return "capture"
+	}
+	return "playback"
+}
+
+// writeFrame stores a frame in the output buffer of the device, according
+// to the device format. The dither source is used for 16-bit samples.
+func writeFrame(buffer gblob.LittleEndianBlock, index int, frame MediaFrame, config DeviceConfig, dither *ditherSource) {
+	sampleSize := config.Format.size()
+	offset := index * config.Channels * sampleSize
+	for channel := range config.Channels {
+		var value float32
+		switch {
+		case config.Channels == 1:
+			value = (frame.Left + frame.Right) / 2.0
+		case channel == 0:
+			value = frame.Left
+		case channel == 1:
+			value = frame.Right
+		}
+		switch config.Format {
+		case SampleFormatS32:
+			buffer.SetInt32(offset, float32ToInt32(value))
+		case SampleFormatF32:
+			buffer.SetFloat32(offset, value)
+		default:
+			buffer.SetInt16(offset, float32ToInt16(value, dither))
+		}
+		offset += sampleSize
+	}
+}
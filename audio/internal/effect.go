@@ -0,0 +1,120 @@
+package internal
+
+import (
+	"math"
+	"slices"
+	"sync/atomic"
+)
+
+// maxEffectSampleRate is the highest sample rate for which the built-in
+// effects allocate their buffers up front. At higher rates, delay lines
+// are limited to the length that was allocated.
+const maxEffectSampleRate = 192000
+
+// Effect processes the audio of a playback or of a bus.
+//
+// The methods of an Effect are called on the audio thread, so they should
+// not block or allocate memory. Parameters that are changed from other
+// goroutines need to be synchronized by the effect. An effect keeps state
+// between frames and must not be attached to more than one playback or
+// bus at a time.
+type Effect interface {
+
+	// Prepare is called before every block of frames with the sample rate
+	// of the device. Effects can use it to pick up parameter changes.
+	Prepare(sampleRate int)
+
+	// Process returns the processed version of the specified frame.
+	Process(frame MediaFrame) MediaFrame
+}
+
+// effectChain is a sequence of effects that are applied one after the
+// other.
+//
+// The effects are replaced as a whole, so that the audio thread can pick
+// up changes without locking. Changes need to be serialized by the
+// control mutex of the player.
+type effectChain struct {
+	effects atomic.Pointer[[]Effect]
+
+	// active is the list of effects that is used by the audio thread
+	// during the current block.
+	active []Effect
+}
+
+// list returns a copy of the effects in the chain.
+func (c *effectChain) list() []Effect {
+	if effects := c.effects.Load(); effects != nil {
+		return slices.Clone(*effects)
+	}
+	return nil
+}
+
+// set replaces the effects in the chain.
+func (c *effectChain) set(effects []Effect) {
+	effects = slices.DeleteFunc(slices.Clone(effects), func(effect Effect) bool {
+		return effect == nil
+	})
+	c.effects.Store(&effects)
+}
+
+// insert adds an effect at the specified index of the chain. Indices
+// outside of the chain append the effect.
+func (c *effectChain) insert(index int, effect Effect) {
+	if effect == nil {
+		return
+	}
+	effects := c.list()
+	if index < 0 || index > len(effects) {
+		index = len(effects)
+	}
+	effects = slices.Insert(effects, index, effect)
+	c.effects.Store(&effects)
+}
+
+// remove removes the specified effect from the chain.
+func (c *effectChain) remove(effect Effect) {
+	effects := slices.DeleteFunc(c.list(), func(candidate Effect) bool {
+		return candidate == effect
+	})
+	c.effects.Store(&effects)
+}
+
+// prepare picks up the current effects of the chain for the next block.
+func (c *effectChain) prepare(sampleRate int) {
+	c.active = nil
+	if effects := c.effects.Load(); effects != nil {
+		c.active = *effects
+	}
+	for _, effect := range c.active {
+		effect.Prepare(sampleRate)
+	}
+}
+
+// process applies the effects to a block of frames in place.
+func (c *effectChain) process(frames []MediaFrame) {
+	for _, effect := range c.active {
+		for i, frame := range frames {
+			frames[i] = effect.Process(frame)
+		}
+	}
+}
+
+// decibelsToGain converts a level in decibels to a volume multiplier.
+func decibelsToGain(decibels float32) float32 {
+	return float32(math.Pow(10.0, float64(decibels)/20.0))
+}
+
+// gainToDecibels converts a volume multiplier to a level in decibels.
+func gainToDecibels(gain float32) float32 {
+	return float32(20.0 * math.Log10(max(float64(gain), 1e-9)))
+}
+
+// smoothingCoefficient returns the coefficient of a one-pole filter with
+// the specified time constant, in seconds.
+func smoothingCoefficient(seconds float64, sampleRate int) float32 {
+	if seconds <= 0.0 {
+		return 1.0
+	}
+	return float32(1.0 - math.Exp(-1.0/(seconds*float64(sampleRate))))
+}
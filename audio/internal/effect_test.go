@@ -0,0 +1,42 @@
+package internal
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDelayRepeatsInput(t *testing.T) {
+	delay := NewDelay(time.Millisecond, 0.0, 1.0)
+	delay.Prepare(44100)
+	delay.SetDelay(2 * time.Millisecond)
+	delay.Prepare(48000)
+
+	output := delay.Process(MediaFrame{Left: 1.0, Right: 0.5})
+	for i := 1; i < 96; i++ {
+		output = delay.Process(MediaFrame{})
+		if output.Left != 0.0 || output.Right != 0.0 {
+			t.Fatalf("expected silence at frame %d, got %v", i, output)
+		}
+	}
+	output = delay.Process(MediaFrame{})
+	if output.Left != 1.0 || output.Right != 0.5 {
+		t.Fatalf("expected echo at frame 96, got %v", output)
+	}
+}
+
+func TestEffectsPrepareWithoutAllocating(t *testing.T) {
+	delay := NewDelay(10*time.Millisecond, 0.5, 0.5)
+	reverb := NewReverb(0.5, 0.5, 0.3)
+	sampleRates := []int{44100, 48000, 96000}
+	allocs := testing.AllocsPerRun(10, func() {
+		for _, sampleRate := range sampleRates {
+			delay.Prepare(sampleRate)
+			delay.Process(MediaFrame{Left: 1.0})
+			reverb.Prepare(sampleRate)
+			reverb.Process(MediaFrame{Left: 1.0})
+		}
+	})
+	if allocs != 0 {
+		t.Fatalf("expected no allocations, got %f", allocs)
+	}
+}
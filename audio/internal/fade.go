@@ -0,0 +1,110 @@
+package internal
+
+import (
+	"math"
+	"time"
+)
+
+// fadeFloor is the level, about -60 dB, from and to which exponential
+// fades are performed, since an exponential curve never reaches zero.
+const fadeFloor = 0.001
+
+// FadeCurve specifies the shape of a fade.
+type FadeCurve int
+
+const (
+	// FadeCurveLinear changes the level at a constant rate.
+	FadeCurveLinear FadeCurve = iota
+
+	// FadeCurveExponential changes the level at a constant rate in
+	// decibels, which is perceived as a steady change in loudness.
+	FadeCurveExponential
+
+	// FadeCurveSCurve starts and ends the fade slowly, which is suitable
+	// for musical transitions.
+	FadeCurveSCurve
+
+	// FadeCurveEqualPower follows a quarter sine wave, so that two
+	// opposite fades keep a constant combined power. This is the curve of
+	// crossfades.
+	FadeCurveEqualPower
+)
+
+// newFade creates a fade between two levels over the specified number of
+// frames.
+func newFade(from, to float32, frames int, curve FadeCurve) fade {
+	return fade{
+		active: true,
+		curve:  curve,
+		from:   from,
+		to:     to,
+		length: frames,
+	}
+}
+
+// fade is a gain ramp that is evaluated once per frame by the audio
+// thread.
+type fade struct {
+	active   bool
+	curve    FadeCurve
+	from     float32
+	to       float32
+	length   int
+	position int
+
+	// stop indicates that the playback should be stopped once the fade
+	// is complete.
+	stop bool
+}
+
+// step advances the fade by one frame and returns the new level. The
+// second result indicates that the fade is complete.
+func (f *fade) step() (float32, bool) {
+	f.position++
+	if f.position >= f.length {
+		return f.to, true
+	}
+	t := float64(f.position) / float64(f.length)
+	from, to := float64(f.from), float64(f.to)
+	switch f.curve {
+	case FadeCurveExponential:
+		from, to = max(from, fadeFloor), max(to, fadeFloor)
+		return float32(from * math.Pow(to/from, t)), false
+	case FadeCurveSCurve:
+		t = (1.0 - math.Cos(math.Pi*t)) / 2.0
+	case FadeCurveEqualPower:
+		if to > from {
+			t = math.Sin(t * math.Pi / 2.0)
+		} else {
+			t = 1.0 - math.Cos(t*math.Pi/2.0)
+		}
+	}
+	return float32(from + (to-from)*t), false
+}
+
+// fader is a level that can be ramped with fades.
+type fader struct {
+	level float32
+	fade  fade
+}
+
+// fadeTo starts a fade from the current level to the specified one.
+func (f *fader) fadeTo(level float32, duration time.Duration, sampleRate int, curve FadeCurve) {
+	f.fade = newFade(f.level, max(level, 0.0), durationToFrames(duration, sampleRate), curve)
+}
+
+// step advances the active fade, if any, by one frame and returns the new
+// level. The second result indicates that a fade that requested a stop
+// has completed.
+func (f *fader) step() (float32, bool) {
+	if !f.fade.active {
+		return f.level, false
+	}
+	level, done := f.fade.step()
+	f.level = level
+	if done {
+		f.fade.active = false
+		return level, f.fade.stop
+	}
+	return level, false
+}
@@ -0,0 +1,197 @@
+package internal
+
+import (
+	"math"
+	"sync/atomic"
+)
+
+// FilterType specifies the frequency response of a BiquadFilter.
+type FilterType int
+
+const (
+	// FilterTypeLowPass attenuates frequencies above the cutoff frequency
+	// (e.g. for a muffled sound behind a wall or under water).
+	FilterTypeLowPass FilterType = iota
+
+	// FilterTypeHighPass attenuates frequencies below the cutoff frequency
+	// (e.g. for a thin radio voice).
+	FilterTypeHighPass
+
+	// FilterTypeBandPass attenuates frequencies away from the center
+	// frequency.
+	FilterTypeBandPass
+
+	// FilterTypeLowShelf boosts or cuts frequencies below the corner
+	// frequency by the gain of the filter.
+	FilterTypeLowShelf
+
+	// FilterTypeHighShelf boosts or cuts frequencies above the corner
+	// frequency by the gain of the filter.
+	FilterTypeHighShelf
+
+	// FilterTypePeaking boosts or cuts frequencies around the center
+	// frequency by the gain of the filter.
+	FilterTypePeaking
+)
+
+// biquadParams holds the parameters from which the coefficients of a
+// BiquadFilter are derived.
+type biquadParams struct {
+	filterType FilterType
+	frequency  float32
+	q          float32
+	gain       float32
+	sampleRate int
+}
+
+// NewBiquadFilter creates a second-order filter of the specified type,
+// frequency in Hz and quality factor. A quality factor of about 0.707
+// gives a flat response for low-pass and high-pass filters.
+func NewBiquadFilter(filterType FilterType, frequency, q float32) *BiquadFilter {
+	filter := &BiquadFilter{}
+	filter.filterType.Store(int32(filterType))
+	filter.frequency.Store(max(frequency, 1.0))
+	filter.q.Store(max(q, 0.01))
+	return filter
+}
+
+// BiquadFilter is an Effect that implements the second-order filters of
+// the Audio EQ Cookbook by Robert Bristow-Johnson.
+//
+// The setters of a BiquadFilter can be called from any goroutine.
+type BiquadFilter struct {
+	filterType atomic.Int32
+	frequency  atomicFloat32
+	q          atomicFloat32
+	gain       atomicFloat32
+
+	// The following are owned by the audio thread.
+	params      biquadParams
+	b0, b1, b2  float32
+	a1, a2      float32
+	left, right biquadState
+	prepared    bool
+}
+
+var _ Effect = (*BiquadFilter)(nil)
+
+// Type returns the type of the filter.
+func (f *BiquadFilter) Type() FilterType {
+	return FilterType(f.filterType.Load())
+}
+
+// SetType specifies the type of the filter.
+func (f *BiquadFilter) SetType(filterType FilterType) {
+	f.filterType.Store(int32(filterType))
+}
+
+// Frequency returns the cutoff, corner or center frequency of the filter.
+func (f *BiquadFilter) Frequency() float32 {
+	return f.frequency.Load()
+}
+
+// SetFrequency specifies the cutoff, corner or center frequency of the
+// filter, in Hz.
+func (f *BiquadFilter) SetFrequency(frequency float32) {
+	f.frequency.Store(max(frequency, 1.0))
+}
+
+// Q returns the quality factor of the filter.
+func (f *BiquadFilter) Q() float32 {
+	return f.q.Load()
+}
+
+// SetQ specifies the quality factor of the filter. Higher values give a
+// narrower band or a more resonant cutoff.
+func (f *BiquadFilter) SetQ(q float32) {
+	f.q.Store(max(q, 0.01))
+}
+
+// Gain returns the boost or cut of the filter.
+func (f *BiquadFilter) Gain() float32 {
+	return f.gain.Load()
+}
+
+// SetGain specifies the boost, or the cut when negative, in decibels, of
+// shelf and peaking filters.
+func (f *BiquadFilter) SetGain(decibels float32) {
+	f.gain.Store(decibels)
+}
+
+func (f *BiquadFilter) Prepare(sampleRate int) {
+	params := biquadParams{
+		filterType: FilterType(f.filterType.Load()),
+		frequency:  f.frequency.Load(),
+		q:          f.q.Load(),
+		gain:       f.gain.Load(),
+		sampleRate: sampleRate,
+	}
+	if f.prepared && params == f.params {
+		return
+	}
+	f.params = params
+	f.prepared = true
+
+	frequency := min(float64(params.frequency), 0.49*float64(sampleRate))
+	omega := 2.0 * math.Pi * frequency / float64(sampleRate)
+	cos, sin := math.Cos(omega), math.Sin(omega)
+	alpha := sin / (2.0 * float64(params.q))
+	amplitude := math.Pow(10.0, float64(params.gain)/40.0)
+
+	var b0, b1, b2, a0, a1, a2 float64
+	switch params.filterType {
+	case FilterTypeHighPass:
+		b0, b1, b2 = (1.0+cos)/2.0, -(1.0 + cos), (1.0+cos)/2.0
+		a0, a1, a2 = 1.0+alpha, -2.0*cos, 1.0-alpha
+	case FilterTypeBandPass:
+		b0, b1, b2 = alpha, 0.0, -alpha
+		a0, a1, a2 = 1.0+alpha, -2.0*cos, 1.0-alpha
+	case FilterTypeLowShelf:
+		root := 2.0 * math.Sqrt(amplitude) * alpha
+		b0 = amplitude * ((amplitude + 1.0) - (amplitude-1.0)*cos + root)
+		b1 = 2.0 * amplitude * ((amplitude - 1.0) - (amplitude+1.0)*cos)
+		b2 = amplitude * ((amplitude + 1.0) - (amplitude-1.0)*cos - root)
+		a0 = (amplitude + 1.0) + (amplitude-1.0)*cos + root
+		a1 = -2.0 * ((amplitude - 1.0) + (amplitude+1.0)*cos)
+		a2 = (amplitude + 1.0) + (amplitude-1.0)*cos - root
+	case FilterTypeHighShelf:
+		root := 2.0 * math.Sqrt(amplitude) * alpha
+		b0 = amplitude * ((amplitude + 1.0) + (amplitude-1.0)*cos + root)
+		b1 = -2.0 * amplitude * ((amplitude - 1.0) + (amplitude+1.0)*cos)
+		b2 = amplitude * ((amplitude + 1.0) + (amplitude-1.0)*cos - root)
+		a0 = (amplitude + 1.0) - (amplitude-1.0)*cos + root
+		a1 = 2.0 * ((amplitude - 1.0) - (amplitude+1.0)*cos)
+		a2 = (amplitude + 1.0) - (amplitude-1.0)*cos - root
+	case FilterTypePeaking:
+		b0, b1, b2 = 1.0+alpha*amplitude, -2.0*cos, 1.0-alpha*amplitude
+		a0, a1, a2 = 1.0+alpha/amplitude, -2.0*cos, 1.0-alpha/amplitude
+	default:
+		b0, b1, b2 = (1.0-cos)/2.0, 1.0-cos, (1.0-cos)/2.0
+		a0, a1, a2 = 1.0+alpha, -2.0*cos, 1.0-alpha
+	}
+	f.b0 = float32(b0 / a0)
+	f.b1 = float32(b1 / a0)
+	f.b2 = float32(b2 / a0)
+	f.a1 = float32(a1 / a0)
+	f.a2 = float32(a2 / a0)
+}
+
+func (f *BiquadFilter) Process(frame MediaFrame) MediaFrame {
+	return MediaFrame{
+		Left:  f.left.process(f, frame.Left),
+		Right: f.right.process(f, frame.Right),
+	}
+}
+
+// biquadState holds the delay line of one channel of a BiquadFilter, in
+// transposed direct form II.
+type biquadState struct {
+	z1, z2 float32
+}
+
+func (s *biquadState) process(f *BiquadFilter, input float32) float32 {
+	output := f.b0*input + s.z1
+	s.z1 = f.b1*input - f.a1*output + s.z2
+	s.z2 = f.b2*input - f.a2*output
+	return output
+}
@@ -0,0 +1,793 @@
+package internal
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/bits"
+)
+
+const (
+	flacMetadataStreamInfo    = 0
+	flacMetadataSeekTable     = 3
+	flacMetadataVorbisComment = 4
+
+	flacChannelsLeftSide  = 8
+	flacChannelsSideRight = 9
+	flacChannelsMidSide   = 10
+)
+
+// flacSeekLinearRange is the number of bytes below which seeking decodes
+// the blocks in between instead of bisecting further.
+const flacSeekLinearRange = 16 * 1024
+
+var errFLACBits = errors.New("unexpected end of flac data")
+
+// isFLAC returns whether the data starts with the FLAC stream marker.
+func isFLAC(data []byte) bool {
+	return len(data) >= 4 && string(data[:4]) == "fLaC"
+}
+
+func newFLACDecoder(data []byte) (*flacDecoder, error) {
+	result := &flacDecoder{
+		data: data,
+	}
+	offset := 4
+	hasStreamInfo := false
+	for {
+		if offset+4 > len(data) {
+			return nil, fmt.Errorf("flac metadata is truncated")
+		}
+		header := data[offset]
+		blockType := header & 0x7F
+		blockLength := int(data[offset+1])<<16 | int(data[offset+2])<<8 | int(data[offset+3])
+		blockStart := offset + 4
+		if blockStart+blockLength > len(data) {
+			return nil, fmt.Errorf("flac metadata block is truncated")
+		}
+		switch blockType {
+		case flacMetadataStreamInfo:
+			if blockLength < 34 {
+				return nil, fmt.Errorf("flac stream info is too short")
+			}
+			reader := bitReader{data: data[blockStart : blockStart+blockLength]}
+			reader.skip(16) // minimum block size
+			result.blockSize = int(reader.mustRead(16))
+			reader.skip(24 + 24) // frame size limits
+			result.sampleRate = int(reader.mustRead(20))
+			result.channels = int(reader.mustRead(3)) + 1
+			result.bitsPerSample = int(reader.mustRead(5)) + 1
+			result.length = int(reader.mustRead(36))
+			hasStreamInfo = true
+		case flacMetadataSeekTable:
+			result.seekPoints = parseFLACSeekTable(data[blockStart : blockStart+blockLength])
+		case flacMetadataVorbisComment:
+			comments, err := parseVorbisComments(data[blockStart : blockStart+blockLength])
+			if err != nil {
+				return nil, fmt.Errorf("error parsing flac comments: %w", err)
+			}
+			result.comments = comments
+		}
+		offset = blockStart + blockLength
+		if header&0x80 != 0 {
+			break
+		}
+	}
+	if !hasStreamInfo {
+		return nil, fmt.Errorf("flac stream info is missing")
+	}
+	if result.sampleRate == 0 {
+		return nil, fmt.Errorf("flac sample rate is not specified")
+	}
+	result.firstFrame = offset
+	result.offset = offset
+	return result, nil
+}
+
+// flacDecoder decodes FLAC data. Mono data is played on both channels
+// and channels beyond the second are ignored.
+type flacDecoder struct {
+	data          []byte
+	sampleRate    int
+	channels      int
+	bitsPerSample int
+	length        int
+	comments      []string
+	firstFrame    int
+
+	// blockSize is the maximum block size, which is the size of all but
+	// the last block in streams with a fixed block size.
+	blockSize int
+
+	// seekPoints holds the entries of the seek table, if there is one.
+	seekPoints []flacSeekPoint
+
+	// offset is the byte offset of the next frame in the data.
+	offset int
+
+	// position is the index of the next frame that Read returns.
+	position int
+
+	// pending holds decoded frames of the current block that have not been
+	// returned yet.
+	pending []MediaFrame
+
+	// channelSamples holds the decoded samples of each channel of the
+	// current block.
+	channelSamples [][]int32
+}
+
+func (d *flacDecoder) SampleRate() int {
+	return d.sampleRate
+}
+
+func (d *flacDecoder) Length() int {
+	return d.length
+}
+
+func (d *flacDecoder) Read(frames []MediaFrame) (int, error) {
+	count := 0
+	for count < len(frames) {
+		if len(d.pending) == 0 {
+			if err := d.decodeFrame(); err != nil {
+				return count, err
+			}
+		}
+		copied := copy(frames[count:], d.pending)
+		d.pending = d.pending[copied:]
+		d.position += copied
+		count += copied
+	}
+	return count, nil
+}
+
+func (d *flacDecoder) Seek(frame int) error {
+	if frame < d.position || frame >= d.position+len(d.pending) {
+		d.locate(frame)
+	}
+	for {
+		if skip := frame - d.position; skip < len(d.pending) {
+			d.pending = d.pending[skip:]
+			d.position = frame
+			return nil
+		}
+		d.position += len(d.pending)
+		d.pending = nil
+		if err := d.decodeFrame(); err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		}
+	}
+}
+
+// locate moves the decoding position to the start of a block that begins
+// at or before the specified frame. The seek table, if any, provides the
+// starting point, from which the blocks are bisected using the frame
+// numbers in their headers.
+func (d *flacDecoder) locate(frame int) {
+	offset, position := d.firstFrame, 0
+	for _, point := range d.seekPoints {
+		if point.frame > frame || d.firstFrame+point.offset >= len(d.data) {
+			break
+		}
+		offset, position = d.firstFrame+point.offset, point.frame
+	}
+
+	end := len(d.data)
+	for end-offset > flacSeekLinearRange {
+		middle := offset + (end-offset)/2
+		blockOffset, header, ok := d.findFrameHeader(middle, end)
+		if !ok || header.position > frame {
+			// No block that starts at or after the middle can be used.
+			end = middle
+			continue
+		}
+		offset, position = blockOffset, header.position
+	}
+
+	d.offset = offset
+	d.position = position
+	d.pending = nil
+}
+
+// findFrameHeader returns the first valid frame header that starts within
+// the specified byte range.
+func (d *flacDecoder) findFrameHeader(from, to int) (int, flacFrameHeader, bool) {
+	for offset := from; offset+1 < to; offset++ {
+		if d.data[offset] != 0xFF || d.data[offset+1]&0xFE != 0xF8 {
+			continue
+		}
+		if header, err := d.parseFrameHeader(d.data[offset:]); err == nil {
+			return offset, header, true
+		}
+	}
+	return 0, flacFrameHeader{}, false
+}
+
+func (d *flacDecoder) LoopRegion() (loopRegion, bool) {
+	return parseLoopTags(d.comments, d.length)
+}
+
+// decodeFrame decodes the next FLAC frame into the pending frames.
+func (d *flacDecoder) decodeFrame() error {
+	if d.offset+2 > len(d.data) {
+		return io.EOF
+	}
+	header, err := d.parseFrameHeader(d.data[d.offset:])
+	if err == nil {
+		reader := bitReader{data: d.data[d.offset+header.size:]}
+		if err = d.decodeFrameFrom(&reader, header); err == nil {
+			d.offset += header.size + reader.bytePosition()
+			return nil
+		}
+	}
+	if errors.Is(err, errFLACBits) {
+		// A truncated final frame is treated as the end of the data.
+		d.offset = len(d.data)
+		return io.EOF
+	}
+	return err
+}
+
+// flacFrameHeader holds the properties of a FLAC frame.
+type flacFrameHeader struct {
+	blockSize         int
+	bitsPerSample     int
+	channels          int
+	channelAssignment uint64
+
+	// position is the index of the first decoded frame of the block.
+	position int
+
+	// size is the length of the header in bytes.
+	size int
+}
+
+// parseFrameHeader parses the FLAC frame header at the start of the
+// specified data. The checksum of the header is verified, which allows
+// headers to be told apart from data that only resembles a sync code.
+func (d *flacDecoder) parseFrameHeader(data []byte) (flacFrameHeader, error) {
+	reader := bitReader{data: data}
+	sync, err := reader.read(14)
+	if err != nil {
+		return flacFrameHeader{}, err
+	}
+	if sync != 0x3FFE {
+		return flacFrameHeader{}, fmt.Errorf("invalid flac frame sync code")
+	}
+	reserved := reader.mustRead(1)
+	variableBlockSize := reader.mustRead(1) == 1
+	blockSizeCode := reader.mustRead(4)
+	sampleRateCode := reader.mustRead(4)
+	channelAssignment := reader.mustRead(4)
+	sampleSizeCode := reader.mustRead(3)
+	reserved |= reader.mustRead(1)
+	if reserved != 0 {
+		return flacFrameHeader{}, fmt.Errorf("reserved flac frame header bit is set")
+	}
+
+	// Blocks are numbered by their first frame when the block size is
+	// variable and by their index otherwise.
+	number, err := readFLACCodedNumber(&reader)
+	if err != nil {
+		return flacFrameHeader{}, err
+	}
+
+	var blockSize int
+	switch {
+	case blockSizeCode == 1:
+		blockSize = 192
+	case blockSizeCode >= 2 && blockSizeCode <= 5:
+		blockSize = 576 << (blockSizeCode - 2)
+	case blockSizeCode == 6:
+		value, err := reader.read(8)
+		if err != nil {
+			return flacFrameHeader{}, err
+		}
+		blockSize = int(value) + 1
+	case blockSizeCode == 7:
+		value, err := reader.read(16)
+		if err != nil {
+			return flacFrameHeader{}, err
+		}
+		blockSize = int(value) + 1
+	case blockSizeCode >= 8:
+		blockSize = 256 << (blockSizeCode - 8)
+	default:
+		return flacFrameHeader{}, fmt.Errorf("reserved flac block size")
+	}
+
+	switch sampleRateCode {
+	case 12:
+		reader.skip(8)
+	case 13, 14:
+		reader.skip(16)
+	case 15:
+		return flacFrameHeader{}, fmt.Errorf("invalid flac sample rate")
+	}
+
+	var bitsPerSample int
+	switch sampleSizeCode {
+	case 0:
+		bitsPerSample = d.bitsPerSample
+	case 1:
+		bitsPerSample = 8
+	case 2:
+		bitsPerSample = 12
+	case 4:
+		bitsPerSample = 16
+	case 5:
+		bitsPerSample = 20
+	case 6:
+		bitsPerSample = 24
+	case 7:
+		bitsPerSample = 32
+	default:
+		return flacFrameHeader{}, fmt.Errorf("reserved flac sample size")
+	}
+
+	var channels int
+	switch {
+	case channelAssignment < 8:
+		channels = int(channelAssignment) + 1
+	case channelAssignment <= flacChannelsMidSide:
+		channels = 2
+	default:
+		return flacFrameHeader{}, fmt.Errorf("reserved flac channel assignment")
+	}
+
+	size := reader.bytePosition()
+	checksum, err := reader.read(8)
+	if err != nil {
+		return flacFrameHeader{}, err
+	}
+	if uint8(checksum) != flacCRC8(data[:size]) {
+		return flacFrameHeader{}, fmt.Errorf("flac frame header checksum mismatch")
+	}
+
+	position := number
+	if !variableBlockSize {
+		position = number * d.blockSize
+	}
+	return flacFrameHeader{
+		blockSize:         blockSize,
+		bitsPerSample:     bitsPerSample,
+		channels:          channels,
+		channelAssignment: channelAssignment,
+		position:          position,
+		size:              size + 1,
+	}, nil
+}
+
+func (d *flacDecoder) decodeFrameFrom(reader *bitReader, header flacFrameHeader) error {
+	var (
+		blockSize         = header.blockSize
+		bitsPerSample     = header.bitsPerSample
+		channels          = header.channels
+		channelAssignment = header.channelAssignment
+	)
+	if len(d.channelSamples) < channels {
+		d.channelSamples = make([][]int32, channels)
+	}
+	for channel := range channels {
+		if cap(d.channelSamples[channel]) < blockSize {
+			d.channelSamples[channel] = make([]int32, blockSize)
+		}
+		samples := d.channelSamples[channel][:blockSize]
+		d.channelSamples[channel] = samples
+
+		// The side channel needs an extra bit.
+		subframeBits := bitsPerSample
+		switch {
+		case channelAssignment == flacChannelsLeftSide && channel == 1:
+			subframeBits++
+		case channelAssignment == flacChannelsSideRight && channel == 0:
+			subframeBits++
+		case channelAssignment == flacChannelsMidSide && channel == 1:
+			subframeBits++
+		}
+		if err := decodeFLACSubframe(reader, samples, subframeBits); err != nil {
+			return err
+		}
+	}
+
+	reader.align()
+	reader.skip(16) // frame CRC-16
+	if reader.err != nil {
+		return reader.err
+	}
+
+	switch channelAssignment {
+	case flacChannelsLeftSide:
+		left, side := d.channelSamples[0], d.channelSamples[1]
+		for i := range blockSize {
+			side[i] = left[i] - side[i]
+		}
+	case flacChannelsSideRight:
+		side, right := d.channelSamples[0], d.channelSamples[1]
+		for i := range blockSize {
+			side[i] = side[i] + right[i]
+		}
+	case flacChannelsMidSide:
+		mid, side := d.channelSamples[0], d.channelSamples[1]
+		for i := range blockSize {
+			sum := int64(mid[i])<<1 | int64(side[i]&1)
+			mid[i] = int32((sum + int64(side[i])) >> 1)
+			side[i] = int32((sum - int64(side[i])) >> 1)
+		}
+	}
+
+	left := d.channelSamples[0]
+	right := left
+	if channels > 1 {
+		right = d.channelSamples[1]
+	}
+	scale := 1.0 / float32(int64(1)<<(bitsPerSample-1))
+	if cap(d.pending) < blockSize {
+		d.pending = make([]MediaFrame, blockSize)
+	}
+	d.pending = d.pending[:blockSize]
+	for i := range blockSize {
+		d.pending[i] = MediaFrame{
+			Left:  float32(left[i]) * scale,
+			Right: float32(right[i]) * scale,
+		}
+	}
+	return nil
+}
+
+func decodeFLACSubframe(reader *bitReader, samples []int32, bitsPerSample int) error {
+	header, err := reader.read(8)
+	if err != nil {
+		return err
+	}
+	if header&0x80 != 0 {
+		return fmt.Errorf("invalid flac subframe padding")
+	}
+	subframeType := (header >> 1) & 0x3F
+
+	wastedBits := 0
+	if header&0x01 != 0 {
+		count, err := reader.readUnary()
+		if err != nil {
+			return err
+		}
+		wastedBits = count + 1
+		bitsPerSample -= wastedBits
+	}
+
+	switch {
+	case subframeType == 0:
+		value, err := reader.readSigned(bitsPerSample)
+		if err != nil {
+			return err
+		}
+		for i := range samples {
+			samples[i] = value
+		}
+	case subframeType == 1:
+		for i := range samples {
+			if samples[i], err = reader.readSigned(bitsPerSample); err != nil {
+				return err
+			}
+		}
+	case subframeType >= 8 && subframeType <= 12:
+		order := int(subframeType - 8)
+		if err := decodeFLACFixed(reader, samples, order, bitsPerSample); err != nil {
+			return err
+		}
+	case subframeType >= 32:
+		order := int(subframeType-32) + 1
+		if err := decodeFLACLPC(reader, samples, order, bitsPerSample); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("reserved flac subframe type %d", subframeType)
+	}
+
+	if wastedBits > 0 {
+		for i := range samples {
+			samples[i] <<= wastedBits
+		}
+	}
+	return nil
+}
+
+func decodeFLACFixed(reader *bitReader, samples []int32, order, bitsPerSample int) error {
+	if order > len(samples) {
+		return fmt.Errorf("flac predictor order exceeds block size")
+	}
+	for i := range order {
+		value, err := reader.readSigned(bitsPerSample)
+		if err != nil {
+			return err
+		}
+		samples[i] = value
+	}
+	if err := decodeFLACResidual(reader, samples, order); err != nil {
+		return err
+	}
+	for i := order; i < len(samples); i++ {
+		var prediction int64
+		switch order {
+		case 1:
+			prediction = int64(samples[i-1])
+		case 2:
+			prediction = 2*int64(samples[i-1]) - int64(samples[i-2])
+		case 3:
+			prediction = 3*int64(samples[i-1]) - 3*int64(samples[i-2]) + int64(samples[i-3])
+		case 4:
+			prediction = 4*int64(samples[i-1]) - 6*int64(samples[i-2]) + 4*int64(samples[i-3]) - int64(samples[i-4])
+		}
+		samples[i] = int32(int64(samples[i]) + prediction)
+	}
+	return nil
+}
+
+func decodeFLACLPC(reader *bitReader, samples []int32, order, bitsPerSample int) error {
+	if order > len(samples) {
+		return fmt.Errorf("flac predictor order exceeds block size")
+	}
+	for i := range order {
+		value, err := reader.readSigned(bitsPerSample)
+		if err != nil {
+			return err
+		}
+		samples[i] = value
+	}
+	precisionCode, err := reader.read(4)
+	if err != nil {
+		return err
+	}
+	if precisionCode == 0x0F {
+		return fmt.Errorf("invalid flac coefficient precision")
+	}
+	precision := int(precisionCode) + 1
+	shift, err := reader.readSigned(5)
+	if err != nil {
+		return err
+	}
+	if shift < 0 {
+		return fmt.Errorf("negative flac prediction shift")
+	}
+	var coefficients [32]int64
+	for i := range order {
+		value, err := reader.readSigned(precision)
+		if err != nil {
+			return err
+		}
+		coefficients[i] = int64(value)
+	}
+	if err := decodeFLACResidual(reader, samples, order); err != nil {
+		return err
+	}
+	for i := order; i < len(samples); i++ {
+		var prediction int64
+		for j := range order {
+			prediction += coefficients[j] * int64(samples[i-j-1])
+		}
+		samples[i] = int32(int64(samples[i]) + prediction>>shift)
+	}
+	return nil
+}
+
+// decodeFLACResidual decodes the Rice-coded residual into the samples
+// that follow the warm-up samples of the predictor.
+func decodeFLACResidual(reader *bitReader, samples []int32, order int) error {
+	method, err := reader.read(2)
+	if err != nil {
+		return err
+	}
+	var paramBits int
+	var escapeParam uint64
+	switch method {
+	case 0:
+		paramBits, escapeParam = 4, 0x0F
+	case 1:
+		paramBits, escapeParam = 5, 0x1F
+	default:
+		return fmt.Errorf("reserved flac residual coding method")
+	}
+	partitionOrder, err := reader.read(4)
+	if err != nil {
+		return err
+	}
+	partitions := 1 << partitionOrder
+	partitionSize := len(samples) >> partitionOrder
+	if partitionSize*partitions != len(samples) || partitionSize < order {
+		return fmt.Errorf("invalid flac partition order")
+	}
+
+	index := order
+	for partition := range partitions {
+		count := partitionSize
+		if partition == 0 {
+			count -= order
+		}
+		param, err := reader.read(paramBits)
+		if err != nil {
+			return err
+		}
+		if param == escapeParam {
+			bits, err := reader.read(5)
+			if err != nil {
+				return err
+			}
+			for range count {
+				value, err := reader.readSigned(int(bits))
+				if err != nil {
+					return err
+				}
+				samples[index] = value
+				index++
+			}
+			continue
+		}
+		for range count {
+			quotient, err := reader.readUnary()
+			if err != nil {
+				return err
+			}
+			remainder, err := reader.read(int(param))
+			if err != nil {
+				return err
+			}
+			value := uint32(quotient)<<param | uint32(remainder)
+			// Residuals are zig-zag encoded.
+			samples[index] = int32(value>>1) ^ -int32(value&1)
+			index++
+		}
+	}
+	return nil
+}
+
+// readFLACCodedNumber reads a frame or block number, which is coded like
+// a UTF-8 character, but with up to 36 bits.
+func readFLACCodedNumber(reader *bitReader) (int, error) {
+	first, err := reader.read(8)
+	if err != nil {
+		return 0, err
+	}
+	length := bits.LeadingZeros8(^uint8(first))
+	switch {
+	case length == 0:
+		return int(first), nil
+	case length == 1 || length > 7:
+		return 0, fmt.Errorf("invalid flac coded number")
+	}
+	value := first & (0xFF >> (length + 1))
+	for range length - 1 {
+		next, err := reader.read(8)
+		if err != nil {
+			return 0, err
+		}
+		if next&0xC0 != 0x80 {
+			return 0, fmt.Errorf("invalid flac coded number")
+		}
+		value = value<<6 | next&0x3F
+	}
+	return int(value), nil
+}
+
+// flacCRC8 computes the checksum of a frame header, with the polynomial
+// x^8 + x^2 + x + 1.
+func flacCRC8(data []byte) uint8 {
+	var crc uint8
+	for _, b := range data {
+		crc ^= b
+		for range 8 {
+			if crc&0x80 != 0 {
+				crc = crc<<1 ^ 0x07
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	return crc
+}
+
+// flacSeekPoint maps the first frame of a block to the byte offset of the
+// block, relative to the first block.
+type flacSeekPoint struct {
+	frame  int
+	offset int
+}
+
+// parseFLACSeekTable parses the points of a SEEKTABLE metadata block.
+func parseFLACSeekTable(data []byte) []flacSeekPoint {
+	const pointSize = 18
+	points := make([]flacSeekPoint, 0, len(data)/pointSize)
+	for ; len(data) >= pointSize; data = data[pointSize:] {
+		frame := binary.BigEndian.Uint64(data[0:8])
+		offset := binary.BigEndian.Uint64(data[8:16])
+		// Placeholder points have the largest possible frame number, which
+		// is excluded along with all other values that do not fit.
+		if frame > math.MaxInt || offset > math.MaxInt {
+			continue
+		}
+		points = append(points, flacSeekPoint{
+			frame:  int(frame),
+			offset: int(offset),
+		})
+	}
+	return points
+}
+
+// bitReader reads big-endian bit sequences from a byte slice.
+type bitReader struct {
+	data []byte
+	pos  int
+	err  error
+}
+
+func (r *bitReader) read(bits int) (uint64, error) {
+	if bits == 0 {
+		return 0, nil
+	}
+	if r.pos+bits > len(r.data)*8 {
+		r.err = errFLACBits
+		return 0, r.err
+	}
+	var result uint64
+	for bits > 0 {
+		byteIndex := r.pos >> 3
+		bitOffset := r.pos & 7
+		available := 8 - bitOffset
+		take := min(available, bits)
+		value := uint64(r.data[byteIndex]>>(available-take)) & (1<<take - 1)
+		result = result<<take | value
+		r.pos += take
+		bits -= take
+	}
+	return result, nil
+}
+
+func (r *bitReader) mustRead(bits int) uint64 {
+	value, _ := r.read(bits)
+	return value
+}
+
+func (r *bitReader) readSigned(bits int) (int32, error) {
+	if bits == 0 {
+		return 0, nil
+	}
+	value, err := r.read(bits)
+	if err != nil {
+		return 0, err
+	}
+	shift := 64 - bits
+	return int32(int64(value<<shift) >> shift), nil
+}
+
+// readUnary returns the number of zero bits before the next one bit.
+func (r *bitReader) readUnary() (int, error) {
+	count := 0
+	for {
+		if r.pos >= len(r.data)*8 {
+			r.err = errFLACBits
+			return 0, r.err
+		}
+		bit := r.data[r.pos>>3] >> (7 - r.pos&7) & 1
+		r.pos++
+		if bit == 1 {
+			return count, nil
+		}
+		count++
+	}
+}
+
+func (r *bitReader) skip(bits int) {
+	r.read(bits)
+}
+
+func (r *bitReader) align() {
+	r.pos = (r.pos + 7) &^ 7
+}
+
+func (r *bitReader) bytePosition() int {
+	return (r.pos + 7) >> 3
+}
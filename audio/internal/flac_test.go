@@ -0,0 +1,278 @@
+package internal
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+	"testing"
+)
+
+func TestFLACDecoderRoundTrip(t *testing.T) {
+	for name, options := range flacTestVariants() {
+		t.Run(name, func(t *testing.T) {
+			samples := flacTestSignal(5000)
+			dec, err := newFLACDecoder(encodeFLACTestStream(samples, options))
+			if err != nil {
+				t.Fatalf("failed to create decoder: %v", err)
+			}
+			if dec.Length() != len(samples[0]) {
+				t.Fatalf("expected length %d, got %d", len(samples[0]), dec.Length())
+			}
+			frames, err := decodeAll(dec)
+			if err != nil {
+				t.Fatalf("failed to decode: %v", err)
+			}
+			assertFLACFrames(t, frames, samples, 0)
+		})
+	}
+}
+
+func TestFLACDecoderSeek(t *testing.T) {
+	for name, options := range flacTestVariants() {
+		t.Run(name, func(t *testing.T) {
+			samples := flacTestSignal(20000)
+			dec, err := newFLACDecoder(encodeFLACTestStream(samples, options))
+			if err != nil {
+				t.Fatalf("failed to create decoder: %v", err)
+			}
+			chunk := make([]MediaFrame, 300)
+			for _, target := range []int{15000, 3, 4096, 19990, 0, 9999} {
+				if err := dec.Seek(target); err != nil {
+					t.Fatalf("failed to seek to %d: %v", target, err)
+				}
+				count, err := dec.Read(chunk)
+				if err != nil && !errors.Is(err, io.EOF) {
+					t.Fatalf("failed to read at %d: %v", target, err)
+				}
+				if expected := min(len(chunk), len(samples[0])-target); count != expected {
+					t.Fatalf("expected %d frames at %d, got %d", expected, target, count)
+				}
+				assertFLACFrames(t, chunk[:count], samples, target)
+			}
+		})
+	}
+}
+
+func TestFLACDecoderLocateBisects(t *testing.T) {
+	samples := flacTestSignal(20000)
+	dec, err := newFLACDecoder(encodeFLACTestStream(samples, flacTestOptions{}))
+	if err != nil {
+		t.Fatalf("failed to create decoder: %v", err)
+	}
+	dec.locate(19000)
+	if dec.position > 19000 || dec.position == 0 {
+		t.Fatalf("expected a block close to the target, got position %d", dec.position)
+	}
+	if dec.offset <= dec.firstFrame || len(dec.data)-dec.offset > 2*flacSeekLinearRange {
+		t.Fatalf("expected a block close to the target, got offset %d of %d", dec.offset, len(dec.data))
+	}
+}
+
+func TestNewDecoderDetectsFLACAfterID3Tag(t *testing.T) {
+	samples := flacTestSignal(1000)
+	tag := []byte{'I', 'D', '3', 4, 0, 0, 0, 0, 0, 5, 'p', 'a', 'd', 0, 0}
+	data := append(tag, encodeFLACTestStream(samples, flacTestOptions{})...)
+	if isMP3(data) {
+		t.Fatalf("expected tagged flac data not to be detected as mp3")
+	}
+	dec, err := newDecoder(data)
+	if err != nil {
+		t.Fatalf("failed to create decoder: %v", err)
+	}
+	if _, ok := dec.(*flacDecoder); !ok {
+		t.Fatalf("expected flac decoder, got %T", dec)
+	}
+}
+
+type flacTestOptions struct {
+	variableBlockSize bool
+	seekTable         bool
+}
+
+func flacTestVariants() map[string]flacTestOptions {
+	return map[string]flacTestOptions{
+		"fixed":      {},
+		"variable":   {variableBlockSize: true},
+		"seek-table": {seekTable: true},
+	}
+}
+
+// flacTestSignal returns the 16-bit samples of a stereo test signal.
+func flacTestSignal(length int) [2][]int32 {
+	var samples [2][]int32
+	for channel := range samples {
+		samples[channel] = make([]int32, length)
+		for i := range length {
+			phase := 2.0 * math.Pi * float64(i) * float64(220*(channel+1)) / 44100.0
+			samples[channel][i] = int32(12000.0*math.Sin(phase)) + int32(i*7919%61) - 30
+		}
+	}
+	return samples
+}
+
+func assertFLACFrames(t *testing.T, frames []MediaFrame, samples [2][]int32, offset int) {
+	t.Helper()
+	for i, frame := range frames {
+		left := float32(samples[0][offset+i]) / 32768.0
+		right := float32(samples[1][offset+i]) / 32768.0
+		if frame.Left != left || frame.Right != right {
+			t.Fatalf("expected (%f, %f) at frame %d, got %v", left, right, offset+i, frame)
+		}
+	}
+}
+
+// encodeFLACTestStream encodes 16-bit stereo samples at 44100 Hz. Blocks
+// alternate between verbatim and fixed-predictor subframes. When the block
+// size is variable, block sizes alternate as well.
+func encodeFLACTestStream(samples [2][]int32, options flacTestOptions) []byte {
+	const blockSize = 1024
+	length := len(samples[0])
+
+	var blocks [][2]int // first frame and size of each block
+	for start, index := 0, 0; start < length; index++ {
+		size := blockSize
+		if options.variableBlockSize && index%2 == 1 {
+			size = blockSize / 2
+		}
+		size = min(size, length-start)
+		blocks = append(blocks, [2]int{start, size})
+		start += size
+	}
+
+	var frames []byte
+	offsets := make([]int, len(blocks))
+	for index, block := range blocks {
+		offsets[index] = len(frames)
+		number := index
+		if options.variableBlockSize {
+			number = block[0]
+		}
+		frames = append(frames, encodeFLACTestFrame(samples, block[0], block[1], number, options.variableBlockSize, index%2 == 0)...)
+	}
+
+	data := []byte("fLaC")
+	streamInfo := flacTestBitWriter{}
+	streamInfo.write(blockSize/2, 16)
+	streamInfo.write(blockSize, 16)
+	streamInfo.write(0, 24)
+	streamInfo.write(0, 24)
+	streamInfo.write(44100, 20)
+	streamInfo.write(2-1, 3)
+	streamInfo.write(16-1, 5)
+	streamInfo.write(uint64(length), 36)
+	streamInfo.bytes = append(streamInfo.bytes, make([]byte, 16)...) // MD5
+	lastFlag := byte(0x80)
+	if options.seekTable {
+		lastFlag = 0
+	}
+	data = appendFLACTestMetadata(data, lastFlag|flacMetadataStreamInfo, streamInfo.bytes)
+
+	if options.seekTable {
+		var table []byte
+		for index := 0; index < len(blocks); index += 4 {
+			table = binary.BigEndian.AppendUint64(table, uint64(blocks[index][0]))
+			table = binary.BigEndian.AppendUint64(table, uint64(offsets[index]))
+			table = binary.BigEndian.AppendUint16(table, uint16(blocks[index][1]))
+		}
+		// Placeholder point.
+		table = binary.BigEndian.AppendUint64(table, math.MaxUint64)
+		table = append(table, make([]byte, 10)...)
+		data = appendFLACTestMetadata(data, 0x80|flacMetadataSeekTable, table)
+	}
+	return append(data, frames...)
+}
+
+func appendFLACTestMetadata(data []byte, header byte, block []byte) []byte {
+	data = append(data, header, byte(len(block)>>16), byte(len(block)>>8), byte(len(block)))
+	return append(data, block...)
+}
+
+func encodeFLACTestFrame(samples [2][]int32, start, size, number int, variable, verbatim bool) []byte {
+	writer := flacTestBitWriter{}
+	writer.write(0x3FFE, 14)
+	writer.write(0, 1)
+	if variable {
+		writer.write(1, 1)
+	} else {
+		writer.write(0, 1)
+	}
+	writer.write(7, 4) // 16-bit block size at end of header
+	writer.write(9, 4) // 44100 Hz
+	writer.write(1, 4) // independent stereo
+	writer.write(4, 3) // 16 bits per sample
+	writer.write(0, 1)
+	writer.writeCodedNumber(uint64(number))
+	writer.write(uint64(size-1), 16)
+	writer.write(uint64(flacCRC8(writer.bytes)), 8)
+
+	for channel := range samples {
+		block := samples[channel][start : start+size]
+		if verbatim || size < 2 {
+			writer.write(1<<1, 8)
+			for _, sample := range block {
+				writer.writeSigned(sample, 16)
+			}
+			continue
+		}
+		// Fixed predictor of order 1, with a single Rice partition.
+		writer.write((8+1)<<1, 8)
+		writer.writeSigned(block[0], 16)
+		writer.write(0, 2)
+		writer.write(0, 4)
+		const param = 8
+		writer.write(param, 4)
+		for i := 1; i < size; i++ {
+			residual := block[i] - block[i-1]
+			value := uint32(residual<<1) ^ uint32(residual>>31)
+			for range value >> param {
+				writer.write(0, 1)
+			}
+			writer.write(1, 1)
+			writer.write(uint64(value&(1<<param-1)), param)
+		}
+	}
+	writer.align()
+	writer.write(0, 16) // frame CRC-16, which the decoder does not verify
+	return writer.bytes
+}
+
+type flacTestBitWriter struct {
+	bytes []byte
+	bits  int
+}
+
+func (w *flacTestBitWriter) write(value uint64, count int) {
+	for i := count - 1; i >= 0; i-- {
+		if w.bits%8 == 0 {
+			w.bytes = append(w.bytes, 0)
+		}
+		if value>>i&1 != 0 {
+			w.bytes[len(w.bytes)-1] |= 0x80 >> (w.bits % 8)
+		}
+		w.bits++
+	}
+}
+
+func (w *flacTestBitWriter) writeSigned(value int32, count int) {
+	w.write(uint64(value)&(1<<count-1), count)
+}
+
+func (w *flacTestBitWriter) writeCodedNumber(value uint64) {
+	if value < 0x80 {
+		w.write(value, 8)
+		return
+	}
+	length := 2
+	for value >= 1<<(5*length+1) {
+		length++
+	}
+	w.write(uint64(0xFF00>>length)&0xFF|value>>(6*(length-1)), 8)
+	for i := length - 2; i >= 0; i-- {
+		w.write(0x80|value>>(6*i)&0x3F, 8)
+	}
+}
+
+func (w *flacTestBitWriter) align() {
+	w.bits = len(w.bytes) * 8
+}
@@ -0,0 +1,202 @@
+package internal
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// limiterLookahead is the time by which the limiter delays the output,
+	// so that it can reduce the gain before a peak arrives. This is what
+	// allows the limiter to avoid clipping without distortion.
+	limiterLookahead = 5 * time.Millisecond
+
+	// defaultLimiterCeiling is the default highest output level, in
+	// decibels.
+	defaultLimiterCeiling = -0.3
+
+	// defaultLimiterRelease is the default time in which the gain
+	// recovers after a peak.
+	defaultLimiterRelease = 100 * time.Millisecond
+)
+
+func newLimiter(sampleRate int) *Limiter {
+	length := max(durationToFrames(limiterLookahead, sampleRate), 1)
+	limiter := &Limiter{
+		sampleRate: sampleRate,
+		delay:      make([]MediaFrame, length),
+		holdValues: make([]float32, length+1),
+		holdTimes:  make([]int, length+1),
+		average:    make([]float32, length),
+		released:   1.0,
+	}
+	for i := range limiter.average {
+		limiter.average[i] = 1.0
+	}
+	limiter.averageSum = float64(length)
+	limiter.enabled.Store(true)
+	limiter.ceiling.Store(defaultLimiterCeiling)
+	limiter.release.Store(int64(defaultLimiterRelease))
+	return limiter
+}
+
+// Limiter is a look-ahead peak limiter on the master output. It keeps the
+// output below a ceiling by smoothly reducing the gain ahead of peaks,
+// instead of clipping them, which would produce harsh distortion when many
+// playbacks sum up.
+//
+// The setters of a Limiter can be called from any goroutine.
+type Limiter struct {
+	enabled   atomic.Bool
+	ceiling   atomicFloat32
+	release   atomic.Int64
+	reduction atomicFloat32
+
+	// The following are owned by the audio thread.
+	sampleRate     int
+	ceilingValue   float32
+	releaseCoeff   float32
+	peakReduction  float32
+	enabledValue   bool
+	time           int
+	delay          []MediaFrame
+	delayPosition  int
+	released       float32
+	average        []float32
+	averagePos     int
+	averageSum     float64
+	holdValues     []float32
+	holdTimes      []int
+	holdFirst      int
+	holdCount      int
+	recomputeCount int
+}
+
+// Enabled returns whether the limiter is active.
+func (l *Limiter) Enabled() bool {
+	return l.enabled.Load()
+}
+
+// SetEnabled specifies whether the limiter is active. When disabled, the
+// output is hard clipped at full scale instead. The limiter is enabled by
+// default.
+func (l *Limiter) SetEnabled(enabled bool) {
+	l.enabled.Store(enabled)
+}
+
+// Ceiling returns the highest output level of the limiter.
+func (l *Limiter) Ceiling() float32 {
+	return l.ceiling.Load()
+}
+
+// SetCeiling specifies the highest output level, in decibels. It cannot
+// be above 0 dB and the default is -0.3 dB.
+func (l *Limiter) SetCeiling(ceiling float32) {
+	l.ceiling.Store(min(ceiling, 0.0))
+}
+
+// Release returns the time in which the gain recovers after a peak.
+func (l *Limiter) Release() time.Duration {
+	return time.Duration(l.release.Load())
+}
+
+// SetRelease specifies the time in which the gain recovers after a peak.
+// Short times make the limiting more transparent but can cause pumping.
+// The default is 100 ms.
+func (l *Limiter) SetRelease(release time.Duration) {
+	l.release.Store(int64(max(release, 0)))
+}
+
+// Reduction returns the highest gain reduction, in decibels, that was
+// applied during the last device period. It can be used for metering.
+func (l *Limiter) Reduction() float32 {
+	return l.reduction.Load()
+}
+
+// prepare picks up parameter changes. It is called once per device
+// period.
+func (l *Limiter) prepare() {
+	l.reduction.Store(l.peakReduction)
+	l.peakReduction = 0.0
+	l.enabledValue = l.enabled.Load()
+	l.ceilingValue = decibelsToGain(l.ceiling.Load())
+	l.releaseCoeff = smoothingCoefficient(time.Duration(l.release.Load()).Seconds(), l.sampleRate)
+}
+
+// process returns the limited frame that was passed in one look-ahead
+// period earlier.
+func (l *Limiter) process(frame MediaFrame) MediaFrame {
+	// The gain that would bring the incoming frame down to the ceiling.
+	required := float32(1.0)
+	if l.enabledValue {
+		if peak := max(abs(frame.Left), abs(frame.Right)); peak > l.ceilingValue {
+			required = l.ceilingValue / peak
+		}
+	}
+
+	// The lowest required gain within the look-ahead window, and one frame
+	// more, is held, so that the gain is already reduced when a peak
+	// leaves the delay line.
+	hold := l.hold(required)
+
+	// The gain drops right away but recovers slowly.
+	if hold < l.released {
+		l.released = hold
+	} else {
+		l.released += (hold - l.released) * l.releaseCoeff
+	}
+
+	// A moving average over the look-ahead window turns the drop into a
+	// ramp that never exceeds the required gain of a delayed frame.
+	l.averageSum += float64(l.released - l.average[l.averagePos])
+	l.average[l.averagePos] = l.released
+	l.averagePos = (l.averagePos + 1) % len(l.average)
+	l.recomputeCount++
+	if l.recomputeCount >= 1<<16 {
+		// The running sum is recomputed now and then, so that rounding
+		// errors do not accumulate.
+		l.recomputeCount = 0
+		l.averageSum = 0.0
+		for _, value := range l.average {
+			l.averageSum += float64(value)
+		}
+	}
+	gain := float32(l.averageSum / float64(len(l.average)))
+
+	delayed := l.delay[l.delayPosition]
+	l.delay[l.delayPosition] = frame
+	l.delayPosition = (l.delayPosition + 1) % len(l.delay)
+
+	if gain < 1.0 {
+		l.peakReduction = max(l.peakReduction, -gainToDecibels(gain))
+	}
+	delayed.ApplyGain(min(gain, 1.0))
+	return delayed
+}
+
+// hold returns the lowest of the values that were passed during the
+// look-ahead window. It uses a monotonic queue of the candidates.
+func (l *Limiter) hold(value float32) float32 {
+	size := len(l.holdValues)
+	l.time++
+
+	// Candidates that have left the window are dropped from the front.
+	if l.holdCount > 0 && l.holdTimes[l.holdFirst] <= l.time-size {
+		l.holdFirst = (l.holdFirst + 1) % size
+		l.holdCount--
+	}
+	// Candidates that are not lower than the new value can never be the
+	// minimum again and are dropped from the back.
+	for l.holdCount > 0 {
+		last := (l.holdFirst + l.holdCount - 1) % size
+		if l.holdValues[last] < value {
+			break
+		}
+		l.holdCount--
+	}
+	next := (l.holdFirst + l.holdCount) % size
+	l.holdValues[next] = value
+	l.holdTimes[next] = l.time
+	l.holdCount++
+	return l.holdValues[l.holdFirst]
+}
@@ -9,6 +9,7 @@ import (
 type Media struct {
 	sampleRate   int
 	length       int
+	loop         loopRegion
 	leftChannel  Channel
 	rightChannel Channel
 }
@@ -30,6 +31,31 @@ func (m *Media) Delete() {
 	m.rightChannel.samples = nil
 }
 
+// StreamingMedia holds encoded audio data that is decoded incrementally
+// during playback, which keeps memory usage low for long tracks.
+type StreamingMedia struct {
+	data       []byte
+	quality    ResampleQuality
+	sampleRate int
+	length     int
+	loop       loopRegion
+}
+
+func (m *StreamingMedia) Length() time.Duration {
+	if m == nil {
+		return 0
+	}
+	return time.Duration(m.length) * time.Second / time.Duration(m.sampleRate)
+}
+
+func (m *StreamingMedia) Delete() {
+	if m == nil {
+		return
+	}
+	m.data = nil
+	m.length = 0
+}
+
 type Channel struct {
 	samples []float32
 }
@@ -74,6 +100,60 @@ func (f *MediaFrame) ApplyPan(pan float32) {
 	f.Right = newRight
 }
 
+// panMatrix holds the channel gains of a stereo pan, so that the pan can be
+// applied to many frames without evaluating trigonometric functions.
+type panMatrix struct {
+	leftToLeft   float32
+	rightToLeft  float32
+	leftToRight  float32
+	rightToRight float32
+}
+
+// newPanMatrix returns the matrix that applies the same pan as ApplyPan.
+func newPanMatrix(pan float32) panMatrix {
+	pan = max(min(pan, 1.0), -1.0)
+
+	angleFraction := pan
+	if angleFraction < 0.0 {
+		angleFraction += 1.0
+	}
+
+	leftGain := sprec.Cos(sprec.Radians(angleFraction * sprec.Pi / 2.0))
+	rightGain := sprec.Sin(sprec.Radians(angleFraction * sprec.Pi / 2.0))
+
+	if pan >= 0.0 {
+		return panMatrix{
+			leftToLeft:   leftGain,
+			leftToRight:  rightGain,
+			rightToRight: 1.0,
+		}
+	}
+	return panMatrix{
+		leftToLeft:   1.0,
+		rightToLeft:  leftGain,
+		rightToRight: rightGain,
+	}
+}
+
+// lerp returns the matrix that is at the specified fraction between this
+// matrix and the target one.
+func (m panMatrix) lerp(target panMatrix, fraction float32) panMatrix {
+	return panMatrix{
+		leftToLeft:   m.leftToLeft + (target.leftToLeft-m.leftToLeft)*fraction,
+		rightToLeft:  m.rightToLeft + (target.rightToLeft-m.rightToLeft)*fraction,
+		leftToRight:  m.leftToRight + (target.leftToRight-m.leftToRight)*fraction,
+		rightToRight: m.rightToRight + (target.rightToRight-m.rightToRight)*fraction,
+	}
+}
+
+// apply returns the panned version of the specified frame.
+func (m panMatrix) apply(frame MediaFrame) MediaFrame {
+	return MediaFrame{
+		Left:  frame.Left*m.leftToLeft + frame.Right*m.rightToLeft,
+		Right: frame.Left*m.leftToRight + frame.Right*m.rightToRight,
+	}
+}
+
 func (f *MediaFrame) Clamp() {
 	f.Left = max(min(f.Left, 1.0), -1.0)
 	f.Right = max(min(f.Right, 1.0), -1.0)
@@ -0,0 +1,71 @@
+package internal
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/hajimehoshi/go-mp3"
+	"github.com/mokiat/gblob"
+)
+
+// isMP3 returns whether the data starts with an MPEG audio frame header,
+// optionally preceded by an ID3v2 tag.
+func isMP3(data []byte) bool {
+	data = skipID3v2(data)
+	return len(data) >= 2 && data[0] == 0xFF && data[1]&0xE0 == 0xE0
+}
+
+func newMP3Decoder(data []byte) (*mp3Decoder, error) {
+	delegate, err := mp3.NewDecoder(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("error creating mp3 decoder: %w", err)
+	}
+	return &mp3Decoder{
+		delegate: delegate,
+	}, nil
+}
+
+// mp3Decoder adapts the go-mp3 decoder, which produces interleaved
+// 16-bit little-endian stereo samples.
+type mp3Decoder struct {
+	delegate *mp3.Decoder
+	buffer   []byte
+}
+
+func (d *mp3Decoder) SampleRate() int {
+	return d.delegate.SampleRate()
+}
+
+func (d *mp3Decoder) Length() int {
+	return int(d.delegate.Length() / 4)
+}
+
+func (d *mp3Decoder) Read(frames []MediaFrame) (int, error) {
+	size := len(frames) * 4
+	if cap(d.buffer) < size {
+		d.buffer = make([]byte, size)
+	}
+	data := d.buffer[:size]
+	count, err := io.ReadFull(d.delegate, data)
+	if errors.Is(err, io.ErrUnexpectedEOF) {
+		err = io.EOF
+	}
+	buffer := gblob.LittleEndianBlock(data)
+	frameCount := count / 4
+	for i := range frameCount {
+		frames[i] = MediaFrame{
+			Left:  int16ToFloat32(buffer.Int16(i*4 + 0)),
+			Right: int16ToFloat32(buffer.Int16(i*4 + 2)),
+		}
+	}
+	return frameCount, err
+}
+
+func (d *mp3Decoder) Seek(frame int) error {
+	if _, err := d.delegate.Seek(int64(frame)*4, io.SeekStart); err != nil {
+		return fmt.Errorf("error seeking mp3 decoder: %w", err)
+	}
+	return nil
+}
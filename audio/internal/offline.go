@@ -0,0 +1,62 @@
+package internal
+
+import (
+	"slices"
+	"time"
+)
+
+// offlineBlockSize is the number of frames that an offline player mixes at
+// once, unless the config specifies a period size.
+const offlineBlockSize = 512
+
+// NewOfflinePlayer creates a Player that is not connected to a device.
+// Instead, audio is mixed on demand by Advance, against a virtual clock
+// that only moves when Advance is called. This makes the output
+// deterministic, which is useful for tests and for rendering audio that
+// needs to be in sync with recorded video. The device ID of the config is
+// ignored.
+func NewOfflinePlayer(config DeviceConfig) *Player {
+	config = config.withDefaults()
+	player := newPlayer(config)
+	player.config = config
+	player.offline = true
+	return player
+}
+
+// Time returns the virtual clock of an offline player, which is the total
+// duration by which it has been advanced.
+func (p *Player) Time() time.Duration {
+	p.renderMU.Lock()
+	defer p.renderMU.Unlock()
+	return p.clock
+}
+
+// Advance moves the virtual clock of an offline player forward by the
+// specified duration and returns the audio of that period. Changes that
+// are made between calls take effect at the start of the next call.
+//
+// Like on a device, the output lags behind by the look-ahead of the
+// limiter, which is 5 ms.
+func (p *Player) Advance(duration time.Duration) []MediaFrame {
+	p.renderMU.Lock()
+	defer p.renderMU.Unlock()
+	if !p.offline || p.closing.Load() || duration <= 0 {
+		return nil
+	}
+
+	// The frame count is derived from the clock, so that rounding errors do
+	// not accumulate over many short calls.
+	start := durationToFrames(p.clock, p.sampleRate)
+	p.clock += duration
+	end := durationToFrames(p.clock, p.sampleRate)
+
+	result := make([]MediaFrame, end-start)
+	blockSize := p.config.PeriodSize
+	if blockSize <= 0 {
+		blockSize = offlineBlockSize
+	}
+	for block := range slices.Chunk(result, blockSize) {
+		p.mix(block)
+	}
+	return result
+}
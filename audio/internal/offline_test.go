@@ -0,0 +1,116 @@
+package internal
+
+import (
+	"bytes"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/mokiat/lacking/audio"
+)
+
+func TestOfflinePlayerAdvancePlaysTone(t *testing.T) {
+	player := NewOfflinePlayer(DeviceConfig{
+		SampleRate: 44100,
+	})
+	defer player.Close()
+
+	media := newToneMedia(t, player, 440.0, time.Second)
+	player.Play(media, audio.PlayInfo{
+		Gain: 1.0,
+	})
+
+	frames := player.Advance(500 * time.Millisecond)
+	if len(frames) != 22050 {
+		t.Fatalf("expected 22050 frames, got %d", len(frames))
+	}
+	if player.Time() != 500*time.Millisecond {
+		t.Fatalf("expected clock at 500ms, got %v", player.Time())
+	}
+
+	// The limiter delays the output by its 5 ms look-ahead.
+	latency := 220
+	for i, frame := range frames[:latency] {
+		if frame.Left != 0.0 || frame.Right != 0.0 {
+			t.Fatalf("expected silence during limiter latency at frame %d, got %v", i, frame)
+		}
+	}
+	tone := toneFrames(44100, 440.0, time.Second)
+	for i, frame := range frames[latency:] {
+		expected := tone[i].Left
+		if math.Abs(float64(frame.Left-expected)) > 1e-4 || math.Abs(float64(frame.Right-expected)) > 1e-4 {
+			t.Fatalf("expected %f at frame %d, got %v", expected, latency+i, frame)
+		}
+	}
+}
+
+func TestOfflinePlayerWAVIsDeterministic(t *testing.T) {
+	render := func() []byte {
+		player := NewOfflinePlayer(DeviceConfig{
+			SampleRate: 44100,
+			Format:     SampleFormatS16,
+		})
+		defer player.Close()
+
+		media := newToneMedia(t, player, 440.0, 100*time.Millisecond)
+		player.Play(media, audio.PlayInfo{
+			Gain: 0.25,
+		})
+		var output bytes.Buffer
+		if err := WriteWAV(&output, player.Advance(100*time.Millisecond), player.DeviceConfig()); err != nil {
+			t.Fatalf("failed to write wav: %v", err)
+		}
+		return output.Bytes()
+	}
+
+	first, second := render(), render()
+	if !bytes.Equal(first, second) {
+		t.Fatalf("expected identical output from identical renders")
+	}
+}
+
+// toneFrames returns a sine wave with an amplitude of 0.5 and the
+// specified frequency and duration.
+func toneFrames(sampleRate int, frequency float64, duration time.Duration) []MediaFrame {
+	frames := make([]MediaFrame, durationToFrames(duration, sampleRate))
+	for i := range frames {
+		value := float32(0.5 * math.Sin(2.0*math.Pi*frequency*float64(i)/float64(sampleRate)))
+		frames[i] = MediaFrame{
+			Left:  value,
+			Right: value,
+		}
+	}
+	return frames
+}
+
+// newToneMedia creates a media that holds a tone with the specified
+// frequency and duration, at the sample rate of the player.
+func newToneMedia(tb testing.TB, player *Player, frequency float64, duration time.Duration) *Media {
+	tb.Helper()
+	var data bytes.Buffer
+	err := WriteWAV(&data, toneFrames(player.sampleRate, frequency, duration), DeviceConfig{
+		SampleRate: player.sampleRate,
+		Format:     SampleFormatF32,
+	})
+	if err != nil {
+		tb.Fatalf("failed to write tone: %v", err)
+	}
+	media := player.CreateMedia(audio.MediaInfo{
+		Data: data.Bytes(),
+	})
+	if media == nil {
+		tb.Fatalf("failed to create tone media")
+	}
+	return media
+}
+
+func TestOfflinePlayerCloseTwice(t *testing.T) {
+	player := NewOfflinePlayer(DeviceConfig{
+		SampleRate: 44100,
+	})
+	player.Close()
+	player.Close()
+	if frames := player.Advance(10 * time.Millisecond); len(frames) != 0 {
+		t.Fatalf("expected no frames after close, got %d", len(frames))
+	}
+}
@@ -0,0 +1,122 @@
+package internal
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/jfreymuth/oggvorbis"
+)
+
+// isOgg returns whether the data starts with an Ogg page.
+func isOgg(data []byte) bool {
+	return len(data) >= 4 && string(data[:4]) == "OggS"
+}
+
+// newOggDecoder creates a decoder for the first logical stream of the Ogg
+// data, depending on the codec that the stream uses.
+func newOggDecoder(data []byte) (decoder, error) {
+	packet := oggFirstPacket(data)
+	switch {
+	case bytes.HasPrefix(packet, []byte("\x01vorbis")):
+		return newVorbisDecoder(data)
+	case bytes.HasPrefix(packet, []byte("OpusHead")):
+		return newOpusDecoder(data)
+	default:
+		return nil, fmt.Errorf("unsupported ogg codec")
+	}
+}
+
+// oggFirstPacket returns the beginning of the first packet of the Ogg
+// data, which identifies the codec.
+func oggFirstPacket(data []byte) []byte {
+	const headerSize = 27
+	if len(data) < headerSize {
+		return nil
+	}
+	segmentCount := int(data[headerSize-1])
+	offset := headerSize + segmentCount
+	if offset > len(data) {
+		return nil
+	}
+	return data[offset:]
+}
+
+func newVorbisDecoder(data []byte) (*vorbisDecoder, error) {
+	delegate, err := oggvorbis.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("error creating vorbis decoder: %w", err)
+	}
+	channels := delegate.Channels()
+	if channels < 1 {
+		return nil, fmt.Errorf("vorbis stream has no channels")
+	}
+	return &vorbisDecoder{
+		delegate:     delegate,
+		channels:     channels,
+		rightChannel: vorbisRightChannel(channels),
+	}, nil
+}
+
+// vorbisDecoder adapts the oggvorbis reader, which produces interleaved
+// float samples. Mono data is played on both channels and only the front
+// left and right channels of surround data are used.
+type vorbisDecoder struct {
+	delegate     *oggvorbis.Reader
+	channels     int
+	rightChannel int
+	buffer       []float32
+}
+
+func (d *vorbisDecoder) SampleRate() int {
+	return d.delegate.SampleRate()
+}
+
+func (d *vorbisDecoder) Length() int {
+	return int(d.delegate.Length())
+}
+
+func (d *vorbisDecoder) Read(frames []MediaFrame) (int, error) {
+	size := len(frames) * d.channels
+	if cap(d.buffer) < size {
+		d.buffer = make([]float32, size)
+	}
+	count, err := d.delegate.Read(d.buffer[:size])
+	if errors.Is(err, io.ErrUnexpectedEOF) {
+		err = io.EOF
+	}
+	frameCount := count / d.channels
+	for i := range frameCount {
+		samples := d.buffer[i*d.channels:]
+		frames[i] = MediaFrame{
+			Left:  samples[0],
+			Right: samples[d.rightChannel],
+		}
+	}
+	return frameCount, err
+}
+
+func (d *vorbisDecoder) Seek(frame int) error {
+	if err := d.delegate.SetPosition(int64(frame)); err != nil {
+		return fmt.Errorf("error seeking vorbis decoder: %w", err)
+	}
+	return nil
+}
+
+func (d *vorbisDecoder) LoopRegion() (loopRegion, bool) {
+	return parseLoopTags(d.delegate.CommentHeader().Comments, d.Length())
+}
+
+// vorbisRightChannel returns the index of the front right channel for the
+// specified channel count, according to the Vorbis channel order.
+func vorbisRightChannel(channels int) int {
+	switch channels {
+	case 1:
+		return 0
+	case 2, 4:
+		return 1
+	default:
+		return 2
+	}
+}
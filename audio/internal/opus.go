@@ -0,0 +1,260 @@
+package internal
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+
+	"github.com/pion/opus"
+	"github.com/pion/opus/pkg/oggreader"
+)
+
+const (
+	// opusSampleRate is the rate at which Opus data is always decoded,
+	// regardless of the input rate that is stored in the header.
+	opusSampleRate = 48000
+
+	// opusMaxPacketSamples is the largest number of samples per channel
+	// that a single Opus packet can hold (120 ms).
+	opusMaxPacketSamples = 5760
+
+	// opusSeekPreRoll is the number of samples that need to be decoded
+	// before a seek position for the decoder to converge, as recommended
+	// by RFC 7845.
+	opusSeekPreRoll = 3840
+)
+
+func newOpusDecoder(data []byte) (*opusDecoder, error) {
+	reader, header, err := oggreader.NewWith(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("error reading opus header: %w", err)
+	}
+	if header.ChannelMap != 0 {
+		return nil, fmt.Errorf("opus channel mapping family %d is not supported", header.ChannelMap)
+	}
+	channels := int(header.Channels)
+	if channels < 1 || channels > 2 {
+		return nil, fmt.Errorf("invalid opus channel count %d", channels)
+	}
+
+	result := &opusDecoder{
+		channels: channels,
+		preSkip:  int(header.PreSkip),
+		gain:     1.0,
+	}
+	if header.OutputGain != 0 {
+		// The output gain is a Q7.8 value in decibels.
+		decibels := float64(int16(header.OutputGain)) / 256.0
+		result.gain = float32(math.Pow(10.0, decibels/20.0))
+	}
+
+	tags, _, err := reader.ParseNextPacket()
+	if err != nil {
+		return nil, fmt.Errorf("error reading opus tags: %w", err)
+	}
+	if !bytes.HasPrefix(tags, []byte("OpusTags")) {
+		return nil, fmt.Errorf("opus tags are missing")
+	}
+	result.comments, err = parseVorbisComments(tags[8:])
+	if err != nil {
+		return nil, fmt.Errorf("error parsing opus comments: %w", err)
+	}
+
+	// Packets are indexed upfront, since seeking requires knowing where
+	// each packet starts and the length is only known from the last page.
+	var (
+		offset   int
+		granule  int
+		hasPages bool
+	)
+	for {
+		packet, pageHeader, err := reader.ParseNextPacket()
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error reading opus packet: %w", err)
+		}
+		if len(packet) == 0 {
+			continue
+		}
+		result.packets = append(result.packets, opusPacket{
+			data:   packet,
+			offset: offset,
+		})
+		offset += opusPacketSamples(packet)
+		granule = int(pageHeader.GranulePosition)
+		hasPages = true
+	}
+
+	// The granule position of the last page marks the end of the audio,
+	// which allows the final packet to be trimmed.
+	end := offset
+	if hasPages && granule > 0 {
+		end = min(end, granule)
+	}
+	result.length = max(end-result.preSkip, 0)
+
+	result.delegate, err = opus.NewDecoderWithOutput(opusSampleRate, channels)
+	if err != nil {
+		return nil, fmt.Errorf("error creating opus decoder: %w", err)
+	}
+	result.buffer = make([]float32, opusMaxPacketSamples*channels)
+	result.decoded = make([]MediaFrame, opusMaxPacketSamples)
+	result.skip = result.preSkip
+	return result, nil
+}
+
+// opusDecoder decodes Opus data that is stored in an Ogg container. Mono
+// data is played on both channels.
+type opusDecoder struct {
+	delegate opus.Decoder
+	channels int
+	preSkip  int
+	gain     float32
+	length   int
+	comments []string
+	packets  []opusPacket
+	buffer   []float32
+	decoded  []MediaFrame
+
+	// next is the index of the next packet to be decoded.
+	next int
+
+	// skip is the number of decoded samples that need to be discarded
+	// before frames are returned, due to pre-skip or seeking.
+	skip int
+
+	// position is the index of the next frame that Read returns.
+	position int
+
+	// pending holds decoded frames of the current packet that have not
+	// been returned yet.
+	pending []MediaFrame
+}
+
+// opusPacket is an encoded Opus packet and the sample at which it starts,
+// including the pre-skip samples.
+type opusPacket struct {
+	data   []byte
+	offset int
+}
+
+func (d *opusDecoder) SampleRate() int {
+	return opusSampleRate
+}
+
+func (d *opusDecoder) Length() int {
+	return d.length
+}
+
+func (d *opusDecoder) Read(frames []MediaFrame) (int, error) {
+	count := 0
+	for count < len(frames) {
+		if d.position >= d.length {
+			return count, io.EOF
+		}
+		if len(d.pending) == 0 {
+			if err := d.decodePacket(); err != nil {
+				return count, err
+			}
+			continue
+		}
+		pending := d.pending[:min(len(d.pending), d.length-d.position)]
+		copied := copy(frames[count:], pending)
+		d.pending = d.pending[copied:]
+		d.position += copied
+		count += copied
+	}
+	return count, nil
+}
+
+func (d *opusDecoder) Seek(frame int) error {
+	frame = max(min(frame, d.length), 0)
+	target := frame + d.preSkip
+
+	// Decoding starts a bit earlier than the target, so that the decoder
+	// state has converged once the target is reached.
+	start := max(target-opusSeekPreRoll, 0)
+	d.next = 0
+	for d.next+1 < len(d.packets) && d.packets[d.next+1].offset <= start {
+		d.next++
+	}
+	if err := d.delegate.Init(opusSampleRate, d.channels); err != nil {
+		return fmt.Errorf("error resetting opus decoder: %w", err)
+	}
+	d.skip = target
+	if d.next < len(d.packets) {
+		d.skip -= d.packets[d.next].offset
+	}
+	d.position = frame
+	d.pending = nil
+	return nil
+}
+
+func (d *opusDecoder) LoopRegion() (loopRegion, bool) {
+	return parseLoopTags(d.comments, d.length)
+}
+
+// decodePacket decodes the next Opus packet into the pending frames.
+func (d *opusDecoder) decodePacket() error {
+	if d.next >= len(d.packets) {
+		return io.EOF
+	}
+	packet := d.packets[d.next]
+	d.next++
+
+	count, err := d.delegate.DecodeToFloat32(packet.data, d.buffer)
+	if err != nil {
+		return fmt.Errorf("error decoding opus packet: %w", err)
+	}
+	skipped := min(d.skip, count)
+	d.skip -= skipped
+
+	rightChannel := d.channels - 1
+	for i := skipped; i < count; i++ {
+		samples := d.buffer[i*d.channels:]
+		d.decoded[i-skipped] = MediaFrame{
+			Left:  samples[0] * d.gain,
+			Right: samples[rightChannel] * d.gain,
+		}
+	}
+	d.pending = d.decoded[:count-skipped]
+	return nil
+}
+
+// opusPacketSamples returns the number of samples per channel that the
+// specified packet holds, based on its table of contents byte.
+func opusPacketSamples(packet []byte) int {
+	if len(packet) == 0 {
+		return 0
+	}
+	toc := packet[0]
+	config := toc >> 3
+
+	var frameSamples int
+	switch {
+	case config < 12: // SILK
+		frameSamples = [...]int{480, 960, 1920, 2880}[config%4]
+	case config < 16: // Hybrid
+		frameSamples = [...]int{480, 960}[config%2]
+	default: // CELT
+		frameSamples = [...]int{120, 240, 480, 960}[config%4]
+	}
+
+	var frameCount int
+	switch toc & 0x03 {
+	case 0:
+		frameCount = 1
+	case 1, 2:
+		frameCount = 2
+	default:
+		if len(packet) < 2 {
+			return 0
+		}
+		frameCount = int(packet[1] & 0x3F)
+	}
+	return frameSamples * frameCount
+}
@@ -0,0 +1,232 @@
+package internal
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+	"testing"
+
+	"github.com/pion/opus"
+)
+
+// opusTestPacket is a 20 ms wideband SILK packet, taken from the test data
+// of github.com/pion/opus (MIT license).
+var opusTestPacket = []byte{
+	0x48, 0x83, 0xca, 0xde, 0x8a, 0xe5, 0x67, 0xd5,
+	0x1c, 0xac, 0xa2, 0x54, 0xfa, 0xff, 0xbf,
+}
+
+const (
+	opusTestPackets = 10
+	opusTestPreSkip = 312
+	opusTestTrim    = 100
+	opusTestLength  = opusTestPackets*960 - opusTestTrim - opusTestPreSkip
+)
+
+func TestOpusDecoderAppliesPreSkipAndEndTrim(t *testing.T) {
+	dec, err := newOggDecoder(newOpusTestStream(0, nil))
+	if err != nil {
+		t.Fatalf("failed to create decoder: %v", err)
+	}
+	if dec.SampleRate() != 48000 {
+		t.Fatalf("expected sample rate 48000, got %d", dec.SampleRate())
+	}
+	if dec.Length() != opusTestLength {
+		t.Fatalf("expected length %d, got %d", opusTestLength, dec.Length())
+	}
+
+	frames, err := decodeAll(dec)
+	if err != nil {
+		t.Fatalf("failed to decode: %v", err)
+	}
+	if len(frames) != opusTestLength {
+		t.Fatalf("expected %d frames, got %d", opusTestLength, len(frames))
+	}
+
+	// The first packet, decoded directly, starts with the pre-skip samples.
+	reference, err := opus.NewDecoderWithOutput(48000, 1)
+	if err != nil {
+		t.Fatalf("failed to create reference decoder: %v", err)
+	}
+	samples := make([]float32, opusMaxPacketSamples)
+	count, err := reference.DecodeToFloat32(opusTestPacket, samples)
+	if err != nil {
+		t.Fatalf("failed to decode reference: %v", err)
+	}
+	audible := false
+	for i := opusTestPreSkip; i < count; i++ {
+		frame := frames[i-opusTestPreSkip]
+		if frame.Left != samples[i] || frame.Right != samples[i] {
+			t.Fatalf("expected %f at frame %d, got %v", samples[i], i-opusTestPreSkip, frame)
+		}
+		audible = audible || frame.Left != 0.0
+	}
+	if !audible {
+		t.Fatalf("expected the fixture to decode to audible frames")
+	}
+}
+
+func TestOpusDecoderSeek(t *testing.T) {
+	dec, err := newOggDecoder(newOpusTestStream(0, nil))
+	if err != nil {
+		t.Fatalf("failed to create decoder: %v", err)
+	}
+	frames, err := decodeAll(dec)
+	if err != nil {
+		t.Fatalf("failed to decode: %v", err)
+	}
+
+	// Seeking within the pre-roll of the first packet restarts decoding
+	// from the beginning, which reproduces the same frames.
+	if err := dec.Seek(1000); err != nil {
+		t.Fatalf("failed to seek: %v", err)
+	}
+	chunk := make([]MediaFrame, 500)
+	if count, err := dec.Read(chunk); err != nil || count != len(chunk) {
+		t.Fatalf("expected %d frames, got %d (%v)", len(chunk), count, err)
+	}
+	for i, frame := range chunk {
+		if frame != frames[1000+i] {
+			t.Fatalf("expected %v at frame %d, got %v", frames[1000+i], 1000+i, frame)
+		}
+	}
+
+	// Seeking further starts from a later packet and still ends exactly at
+	// the trimmed end of the stream.
+	if err := dec.Seek(8000); err != nil {
+		t.Fatalf("failed to seek: %v", err)
+	}
+	remaining := make([]MediaFrame, opusTestLength)
+	count, err := dec.Read(remaining)
+	if !errors.Is(err, io.EOF) {
+		t.Fatalf("expected end of stream, got %v", err)
+	}
+	if count != opusTestLength-8000 {
+		t.Fatalf("expected %d frames after seek, got %d", opusTestLength-8000, count)
+	}
+}
+
+func TestOpusDecoderAppliesOutputGain(t *testing.T) {
+	plain, err := newOggDecoder(newOpusTestStream(0, nil))
+	if err != nil {
+		t.Fatalf("failed to create decoder: %v", err)
+	}
+	amplified, err := newOggDecoder(newOpusTestStream(6*256, nil))
+	if err != nil {
+		t.Fatalf("failed to create decoder: %v", err)
+	}
+	plainFrames, _ := decodeAll(plain)
+	amplifiedFrames, _ := decodeAll(amplified)
+
+	gain := float32(math.Pow(10.0, 6.0/20.0))
+	for i := range plainFrames {
+		expected := plainFrames[i].Left * gain
+		if math.Abs(float64(amplifiedFrames[i].Left-expected)) > 1e-6 {
+			t.Fatalf("expected %f at frame %d, got %f", expected, i, amplifiedFrames[i].Left)
+		}
+	}
+}
+
+func TestOpusDecoderLoopTags(t *testing.T) {
+	dec, err := newOggDecoder(newOpusTestStream(0, []string{"LOOPSTART=480", "LOOPLENGTH=4800"}))
+	if err != nil {
+		t.Fatalf("failed to create decoder: %v", err)
+	}
+	if region := decoderLoopRegion(dec); region != (loopRegion{start: 480, end: 5280}) {
+		t.Fatalf("unexpected loop region: %+v", region)
+	}
+}
+
+func TestOpusPacketSamples(t *testing.T) {
+	testCases := []struct {
+		name   string
+		packet []byte
+		want   int
+	}{
+		{name: "empty", packet: nil, want: 0},
+		{name: "silk 10ms", packet: []byte{0 << 3}, want: 480},
+		{name: "silk 60ms", packet: []byte{3 << 3}, want: 2880},
+		{name: "hybrid 20ms", packet: []byte{13 << 3}, want: 960},
+		{name: "celt 2.5ms", packet: []byte{16 << 3}, want: 120},
+		{name: "celt 20ms", packet: []byte{31 << 3}, want: 960},
+		{name: "two equal frames", packet: []byte{31<<3 | 1}, want: 1920},
+		{name: "two different frames", packet: []byte{31<<3 | 2}, want: 1920},
+		{name: "arbitrary frames", packet: []byte{31<<3 | 3, 0x80 | 6}, want: 5760},
+		{name: "truncated arbitrary frames", packet: []byte{31<<3 | 3}, want: 0},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := opusPacketSamples(tc.packet); got != tc.want {
+				t.Fatalf("expected %d samples, got %d", tc.want, got)
+			}
+		})
+	}
+}
+
+// newOpusTestStream returns an Ogg Opus stream that holds the test packet
+// repeated several times, with one packet per page. The final page trims
+// the last packet.
+func newOpusTestStream(outputGain int16, comments []string) []byte {
+	head := []byte("OpusHead")
+	head = append(head, 1, 1) // version and channel count
+	head = binary.LittleEndian.AppendUint16(head, opusTestPreSkip)
+	head = binary.LittleEndian.AppendUint32(head, 48000)
+	head = binary.LittleEndian.AppendUint16(head, uint16(outputGain))
+	head = append(head, 0) // channel mapping family
+
+	tags := []byte("OpusTags")
+	tags = binary.LittleEndian.AppendUint32(tags, 4)
+	tags = append(tags, "test"...)
+	tags = binary.LittleEndian.AppendUint32(tags, uint32(len(comments)))
+	for _, comment := range comments {
+		tags = binary.LittleEndian.AppendUint32(tags, uint32(len(comment)))
+		tags = append(tags, comment...)
+	}
+
+	var result []byte
+	result = appendOggPage(result, 0x02, 0, 0, head)
+	result = appendOggPage(result, 0x00, 0, 1, tags)
+	for i := range opusTestPackets {
+		granule := uint64((i + 1) * 960)
+		headerType := byte(0x00)
+		if i == opusTestPackets-1 {
+			granule -= opusTestTrim
+			headerType = 0x04
+		}
+		result = appendOggPage(result, headerType, granule, uint32(i+2), opusTestPacket)
+	}
+	return result
+}
+
+// appendOggPage appends an Ogg page that holds a single packet.
+func appendOggPage(data []byte, headerType byte, granule uint64, sequence uint32, packet []byte) []byte {
+	var page []byte
+	page = append(page, "OggS"...)
+	page = append(page, 0, headerType)
+	page = binary.LittleEndian.AppendUint64(page, granule)
+	page = binary.LittleEndian.AppendUint32(page, 1) // serial number
+	page = binary.LittleEndian.AppendUint32(page, sequence)
+	page = binary.LittleEndian.AppendUint32(page, 0) // checksum
+	segments := len(packet)/255 + 1
+	page = append(page, byte(segments))
+	for range segments - 1 {
+		page = append(page, 255)
+	}
+	page = append(page, byte(len(packet)%255))
+	page = append(page, packet...)
+
+	var checksum uint32
+	for _, value := range page {
+		checksum ^= uint32(value) << 24
+		for range 8 {
+			if checksum&0x80000000 != 0 {
+				checksum = checksum<<1 ^ 0x04C11DB7
+			} else {
+				checksum <<= 1
+			}
+		}
+	}
+	binary.LittleEndian.PutUint32(page[22:], checksum)
+	return append(data, page...)
+}
@@ -1,43 +1,591 @@
 package internal
 
-import "sync/atomic"
+import (
+	"math"
+	"sync/atomic"
+	"time"
+)
 
+const (
+	// playbackRampDuration is the time over which a playback fades out
+	// before it is paused, seeked or stopped, and fades in afterwards, so
+	// that the waveform does not jump.
+	playbackRampDuration = 5 * time.Millisecond
+
+	// playbackSmoothingDuration is the time constant with which gain and
+	// pan changes are applied.
+	playbackSmoothingDuration = 5 * time.Millisecond
+
+	// playbackSmoothingEpsilon is the difference below which a smoothed
+	// value is considered to have reached its target.
+	playbackSmoothingEpsilon = 0.0001
+)
+
+// PlaybackState specifies whether a Playback is audible.
+type PlaybackState int
+
+const (
+	// PlaybackStatePlaying indicates that the playback is producing audio.
+	PlaybackStatePlaying PlaybackState = iota
+
+	// PlaybackStatePaused indicates that the playback is paused and can be
+	// resumed.
+	PlaybackStatePaused
+
+	// PlaybackStateStopped indicates that the playback has ended or has
+	// been stopped. It cannot be resumed.
+	PlaybackStateStopped
+)
+
+func newPlayback(player *Player, gain, pan float32, loop bool) *Playback {
+	sampleRate := player.sampleRate
+	playback := &Playback{
+		player:    player,
+		rateRatio: 1.0,
+		fader:     fader{level: 1.0},
+		envelope:  1.0,
+		spatial:   noSpatialState,
+		current:   noSpatialState,
+		rampStep:  float32(1.0 / max(float64(durationToFrames(playbackRampDuration, sampleRate)), 1.0)),
+		smoothing: smoothingCoefficient(playbackSmoothingDuration.Seconds(), sampleRate),
+	}
+	playback.gain.Store(max(gain, 0.0))
+	playback.pan.Store(max(min(pan, 1.0), -1.0))
+	playback.current.gain = playback.gain.Load()
+	playback.current.pan = playback.pan.Load()
+	playback.loop.Store(loop)
+	playback.seekTarget.Store(noSeek)
+	playback.level.Store(1.0)
+	return playback
+}
+
+// Playback is an active playback of a media.
+//
+// The methods of a Playback can be called from any goroutine and never
+// block the audio thread. Changes are applied by the audio thread, which
+// ramps them in over a few milliseconds to avoid clicks.
 type Playback struct {
-	media *Media
+	player  *Player
+	media   *Media
+	stream  *stream
+	capture *captureSource
+
+	// rateRatio is the ratio between the sample rate of the source and the
+	// one of the player, for sources that are not resampled in advance.
+	rateRatio float32
+
+	loop       atomic.Bool
+	gain       atomicFloat32
+	pan        atomicFloat32
+	paused     atomic.Bool
+	stopping   atomic.Bool
+	seekTarget atomic.Int64
+	emitter    atomic.Pointer[Emitter]
+	effects    effectChain
+
+	// The following are published by the audio thread once per block.
+	done     atomic.Bool
+	position atomic.Int64
+	level    atomicFloat32
+
+	// The following are owned by the audio thread.
+	mixBus    *Bus
+	fader     fader
+	offset    int
+	controls  playbackControls
+	spatial   spatialState
+	current   spatialState
+	envelope  float32
+	rampStep  float32
+	smoothing float32
 
-	loop bool
-	gain float32
-	pan  float32
+	// blockSmoothing is the smoothing that corresponds to a whole block.
+	blockSmoothing float32
 
-	offset int64
+	// The following are used to change the pitch of the playback, once
+	// that becomes necessary. The output is interpolated between the
+	// previous and the following frames of the media. Once the original
+	// pitch is restored, the frames that were read ahead are played out
+	// while draining, after which the media is read directly again.
+	interpolating bool
+	draining      bool
+	ended         bool
+	phase         float32
+	previous      MediaFrame
+	following     MediaFrame
 }
 
-func (p *Playback) Frame() (MediaFrame, bool) {
-	offset := atomic.LoadInt64(&p.offset)
-	if offset == -1 {
-		return MediaFrame{}, false
+// playbackControls is a snapshot of the settings of a playback that is
+// taken once per block.
+type playbackControls struct {
+	gain       float32
+	pan        float32
+	loop       bool
+	paused     bool
+	stopping   bool
+	seeking    bool
+	seekTarget int64
+}
+
+// Gain returns the volume multiplier of the playback.
+func (p *Playback) Gain() float32 {
+	return p.gain.Load()
+}
+
+// SetGain specifies the volume multiplier of the playback.
+func (p *Playback) SetGain(gain float32) {
+	p.gain.Store(max(gain, 0.0))
+}
+
+// Pan returns the stereo balance of the playback.
+func (p *Playback) Pan() float32 {
+	return p.pan.Load()
+}
+
+// SetPan specifies the stereo balance of the playback, in the range
+// [-1, 1].
+func (p *Playback) SetPan(pan float32) {
+	p.pan.Store(max(min(pan, 1.0), -1.0))
+}
+
+// Loop returns whether the playback repeats the media.
+func (p *Playback) Loop() bool {
+	return p.loop.Load()
+}
+
+// SetLoop specifies whether the playback should repeat the media, or the
+// loop region of the media, once it reaches the end of it.
+func (p *Playback) SetLoop(loop bool) {
+	p.loop.Store(loop)
+	if p.stream != nil {
+		p.stream.SetLoop(loop)
 	}
+}
 
-	if offset >= int64(p.media.length) {
-		if !p.loop {
-			atomic.CompareAndSwapInt64(&p.offset, offset, -1)
-			return MediaFrame{}, false
-		}
-		atomic.CompareAndSwapInt64(&p.offset, offset, 0)
-		offset = 0
+// FadeLevel returns the current level of the fader of the playback. The
+// fader is applied on top of the gain of the playback.
+func (p *Playback) FadeLevel() float32 {
+	return p.level.Load()
+}
+
+// FadeTo ramps the fader of the playback from its current level to the
+// specified one over the specified duration. It replaces any fade that
+// is in progress.
+func (p *Playback) FadeTo(level float32, duration time.Duration, curve FadeCurve) {
+	p.player.commands.push(func() {
+		p.fader.fadeTo(level, duration, p.player.sampleRate, curve)
+	})
+}
+
+// FadeOut ramps the fader of the playback down to silence over the
+// specified duration and then stops the playback.
+func (p *Playback) FadeOut(duration time.Duration, curve FadeCurve) {
+	p.player.commands.push(func() {
+		p.fader.fadeTo(0.0, duration, p.player.sampleRate, curve)
+		p.fader.fade.stop = true
+	})
+}
+
+// Emitter returns the emitter of the playback. The second result is false
+// if the playback is not positioned in 3D space.
+func (p *Playback) Emitter() (Emitter, bool) {
+	if emitter := p.emitter.Load(); emitter != nil {
+		return *emitter, true
+	}
+	return Emitter{}, false
+}
+
+// SetEmitter positions the playback in 3D space. The volume, stereo
+// balance and pitch of the playback are then derived from the emitter and
+// the listener of the player, on top of the gain and pan of the playback.
+// This is typically called once per frame for moving emitters.
+func (p *Playback) SetEmitter(emitter Emitter) {
+	p.emitter.Store(&emitter)
+}
+
+// ClearEmitter removes the playback from 3D space.
+func (p *Playback) ClearEmitter() {
+	p.emitter.Store(nil)
+}
+
+// Effects returns the effects that are applied to the playback, in order.
+// Effects are applied before the gain, pan and emitter of the playback.
+func (p *Playback) Effects() []Effect {
+	return p.effects.list()
+}
+
+// SetEffects replaces the effects that are applied to the playback.
+func (p *Playback) SetEffects(effects ...Effect) {
+	p.player.controlMU.Lock()
+	defer p.player.controlMU.Unlock()
+	p.effects.set(effects)
+}
+
+// InsertEffect adds an effect at the specified position of the effect
+// chain of the playback. An index outside of the chain appends the effect.
+func (p *Playback) InsertEffect(index int, effect Effect) {
+	p.player.controlMU.Lock()
+	defer p.player.controlMU.Unlock()
+	p.effects.insert(index, effect)
+}
+
+// RemoveEffect removes an effect from the effect chain of the playback.
+func (p *Playback) RemoveEffect(effect Effect) {
+	p.player.controlMU.Lock()
+	defer p.player.controlMU.Unlock()
+	p.effects.remove(effect)
+}
+
+// Pause suspends the playback until Resume is called.
+func (p *Playback) Pause() {
+	p.paused.Store(true)
+}
+
+// Resume continues a paused playback.
+func (p *Playback) Resume() {
+	p.paused.Store(false)
+}
+
+// Seek moves the playback to the specified position within the media.
+func (p *Playback) Seek(position time.Duration) {
+	frame := max(min(durationToFrames(position, p.sampleRate()), p.length()), 0)
+	p.seekTarget.Store(int64(frame))
+}
+
+// Position returns the current position of the playback within the media.
+func (p *Playback) Position() time.Duration {
+	sampleRate := p.sampleRate()
+	if sampleRate == 0 {
+		return 0
 	}
+	return framesToDuration(p.currentPosition(), sampleRate)
+}
 
-	atomic.CompareAndSwapInt64(&p.offset, offset, offset+1)
-	return MediaFrame{
-		Left:  p.media.leftChannel.samples[offset],
-		Right: p.media.rightChannel.samples[offset],
-	}, true
+// State returns whether the playback is playing, paused or stopped.
+func (p *Playback) State() PlaybackState {
+	switch {
+	case p.IsDone():
+		return PlaybackStateStopped
+	case p.paused.Load():
+		return PlaybackStatePaused
+	default:
+		return PlaybackStatePlaying
+	}
 }
 
+// Stop ends the playback. The playback fades out over a few milliseconds
+// but is reported as done right away.
 func (p *Playback) Stop() {
-	atomic.StoreInt64(&p.offset, -1)
+	p.stopping.Store(true)
 }
 
 func (p *Playback) IsDone() bool {
-	return atomic.LoadInt64(&p.offset) == -1
+	return p.stopping.Load() || p.done.Load()
+}
+
+// prepare takes a snapshot of the settings of the playback and updates
+// its effects and spatial parameters for a block of the specified size.
+func (p *Playback) prepare(listener listenerState, size int) {
+	p.controls = playbackControls{
+		gain:       p.gain.Load(),
+		pan:        p.pan.Load(),
+		loop:       p.loop.Load(),
+		paused:     p.paused.Load(),
+		stopping:   p.stopping.Load(),
+		seeking:    p.stream != nil && p.stream.seekTarget.Load() != noSeek,
+		seekTarget: p.seekTarget.Load(),
+	}
+	p.effects.prepare(p.player.sampleRate)
+	if emitter := p.emitter.Load(); emitter != nil {
+		p.spatial = listener.spatialize(*emitter)
+	} else {
+		p.spatial = noSpatialState
+	}
+	p.blockSmoothing = 1.0 - float32(math.Pow(float64(1.0-p.smoothing), float64(size)))
+}
+
+// render produces the next block of frames of the playback. The gain and
+// pan of the playback are applied. It returns false once the playback has
+// ended, in which case the remaining frames are silent.
+//
+// The gains slice is used as scratch space and needs to be as long as
+// the frames slice.
+func (p *Playback) render(frames []MediaFrame, gains []float32) bool {
+	// Gain, pan and pitch are smoothed once per block and interpolated
+	// linearly across the block.
+	start := p.current
+	gain := p.controls.gain * p.spatial.gain
+	pan := max(min(p.controls.pan+p.spatial.pan, 1.0), -1.0)
+	p.current.gain = smooth(p.current.gain, gain, p.blockSmoothing)
+	p.current.pan = smooth(p.current.pan, pan, p.blockSmoothing)
+	p.current.pitch = smooth(p.current.pitch, p.spatial.pitch, p.blockSmoothing)
+
+	var alive bool
+	if p.steady(start) {
+		alive = p.renderSteady(frames, gains)
+	} else {
+		alive = p.renderChanging(frames, gains, start.pitch)
+	}
+
+	p.effects.process(frames)
+
+	step := 1.0 / float32(len(frames))
+	if start.pan == p.current.pan {
+		pan := newPanMatrix(p.current.pan)
+		for i := range frames {
+			frames[i].ApplyGain((start.gain + (p.current.gain-start.gain)*float32(i+1)*step) * gains[i])
+			frames[i] = pan.apply(frames[i])
+		}
+	} else {
+		startPan := newPanMatrix(start.pan)
+		endPan := newPanMatrix(p.current.pan)
+		for i := range frames {
+			t := float32(i+1) * step
+			frames[i].ApplyGain((start.gain + (p.current.gain-start.gain)*t) * gains[i])
+			frames[i] = startPan.lerp(endPan, t).apply(frames[i])
+		}
+	}
+
+	p.level.Store(p.fader.level)
+	p.position.Store(int64(min(p.offset, p.length())))
+	return alive
+}
+
+// smooth moves the value towards the target by the specified coefficient.
+// The target is reached once the difference becomes inaudible, so that the
+// playback can be considered steady.
+func smooth(value, target, coefficient float32) float32 {
+	value += (target - value) * coefficient
+	if abs(target-value) < playbackSmoothingEpsilon {
+		return target
+	}
+	return value
+}
+
+// steady returns whether the playback is playing at full volume and at
+// its original pitch, without any pending changes, in which case frames can
+// be copied from the media as they are.
+func (p *Playback) steady(start spatialState) bool {
+	controls := &p.controls
+	if controls.paused || controls.stopping || controls.seeking || controls.seekTarget != noSeek {
+		return false
+	}
+	return p.envelope == 1.0 && !p.fader.fade.active && !p.interpolating &&
+		start.pitch == 1.0 && p.current.pitch == 1.0 && p.rateRatio == 1.0
+}
+
+// renderSteady fills the frames with the media and the gains with the
+// fader level.
+func (p *Playback) renderSteady(frames []MediaFrame, gains []float32) bool {
+	for i := range frames {
+		frame, ok := p.read()
+		if !ok {
+			clear(frames[i:])
+			clear(gains[i:])
+			return false
+		}
+		frames[i] = frame
+		gains[i] = p.fader.level
+	}
+	return true
+}
+
+// renderChanging fills the frames with the media and the gains with the
+// envelope and the fader level, while any of them or the pitch changes.
+func (p *Playback) renderChanging(frames []MediaFrame, gains []float32, startPitch float32) bool {
+	step := 1.0 / float32(len(frames))
+	for i := range frames {
+		t := float32(i+1) * step
+		frame, envelope, ok := p.next((startPitch + (p.current.pitch-startPitch)*t) * p.rateRatio)
+		if !ok {
+			clear(frames[i:])
+			clear(gains[i:])
+			return false
+		}
+		level := p.fader.level
+		if envelope > 0.0 {
+			var stop bool
+			if level, stop = p.fader.step(); stop {
+				p.stopping.Store(true)
+				p.controls.stopping = true
+			}
+		}
+		frames[i] = frame
+		gains[i] = envelope * level
+	}
+	return true
+}
+
+// next returns the next frame of the media, together with the envelope
+// that needs to be applied to it. It returns false once the playback has
+// ended.
+func (p *Playback) next(pitch float32) (MediaFrame, float32, bool) {
+	controls := &p.controls
+	if controls.paused || controls.stopping || controls.seeking || controls.seekTarget != noSeek {
+		p.envelope = max(p.envelope-p.rampStep, 0.0)
+		if p.envelope > 0.0 {
+			frame, ok := p.pitchedFrame(pitch)
+			return frame, p.envelope, ok
+		}
+		if controls.stopping {
+			return MediaFrame{}, 0.0, false
+		}
+		if target := controls.seekTarget; target != noSeek {
+			p.seekTarget.CompareAndSwap(target, noSeek)
+			p.seek(int(target))
+			controls.seekTarget = noSeek
+			controls.seeking = p.stream != nil && p.stream.seekTarget.Load() != noSeek
+			p.interpolating = false
+		}
+		// The playback stays silent and does not advance until the
+		// frames after the pause or seek are available.
+		return MediaFrame{}, 0.0, true
+	}
+	p.envelope = min(p.envelope+p.rampStep, 1.0)
+	frame, ok := p.pitchedFrame(pitch)
+	return frame, p.envelope, ok
+}
+
+// pitchedFrame returns the next frame of the media at the specified pitch.
+func (p *Playback) pitchedFrame(pitch float32) (MediaFrame, bool) {
+	if !p.interpolating {
+		if pitch == 1.0 {
+			return p.read()
+		}
+		p.interpolating = true
+		p.draining = false
+		p.ended = false
+		p.phase = 0.0
+		var ok bool
+		if p.previous, ok = p.read(); !ok {
+			return MediaFrame{}, false
+		}
+		if p.following, ok = p.read(); !ok {
+			p.following = MediaFrame{}
+			p.ended = true
+		}
+	}
+	if p.ended && p.phase > 0.0 {
+		return MediaFrame{}, false
+	}
+	if pitch == 1.0 {
+		frame := p.previous
+		if p.draining || p.ended {
+			p.interpolating = false
+			p.draining = false
+		} else {
+			p.previous = p.following
+			p.draining = true
+		}
+		p.phase = 0.0
+		return frame, true
+	}
+	if p.draining {
+		// The pitch changed again before the frames were played out.
+		p.draining = false
+		var ok bool
+		if p.following, ok = p.read(); !ok {
+			p.following = MediaFrame{}
+			p.ended = true
+		}
+	}
+
+	frame := MediaFrame{
+		Left:  p.previous.Left + (p.following.Left-p.previous.Left)*p.phase,
+		Right: p.previous.Right + (p.following.Right-p.previous.Right)*p.phase,
+	}
+	p.phase += pitch
+	for p.phase >= 1.0 && !p.ended {
+		p.phase -= 1.0
+		p.previous = p.following
+		next, ok := p.read()
+		if !ok {
+			next = MediaFrame{}
+			p.ended = true
+		}
+		p.following = next
+	}
+	return frame, true
+}
+
+// read returns the next frame of the media. It returns false once the
+// end of the media has been reached.
+func (p *Playback) read() (MediaFrame, bool) {
+	if p.stream != nil {
+		return p.stream.Frame()
+	}
+	if p.capture != nil {
+		return p.capture.frame()
+	}
+	if p.controls.loop && p.offset >= p.media.loop.end {
+		p.offset = p.media.loop.start
+	}
+	if p.offset >= p.media.length {
+		return MediaFrame{}, false
+	}
+	frame := MediaFrame{
+		Left:  p.media.leftChannel.samples[p.offset],
+		Right: p.media.rightChannel.samples[p.offset],
+	}
+	p.offset++
+	return frame, true
+}
+
+func (p *Playback) seek(frame int) {
+	if p.stream != nil {
+		p.stream.Seek(frame)
+		return
+	}
+	p.offset = frame
+}
+
+// stop releases the resources of the playback once it is no longer mixed.
+func (p *Playback) stop() {
+	p.done.Store(true)
+	if p.stream != nil {
+		p.stream.Close()
+	}
+	if p.capture != nil {
+		p.capture.close()
+	}
+}
+
+func (p *Playback) currentPosition() int {
+	if target := p.seekTarget.Load(); target != noSeek {
+		return int(target)
+	}
+	if p.stream != nil {
+		if target := p.stream.seekTarget.Load(); target != noSeek {
+			return int(target)
+		}
+		return p.stream.Position()
+	}
+	return int(p.position.Load())
+}
+
+// sampleRate returns the sample rate of the played media. It returns zero
+// for playbacks without media, which are created stopped when the media
+// cannot be played.
+func (p *Playback) sampleRate() int {
+	switch {
+	case p.stream != nil:
+		return p.stream.sampleRate
+	case p.media != nil:
+		return p.media.sampleRate
+	default:
+		return 0
+	}
+}
+
+// length returns the number of frames of the played media. It returns
+// zero for playbacks without media.
+func (p *Playback) length() int {
+	switch {
+	case p.stream != nil:
+		return p.stream.length
+	case p.media != nil:
+		return p.media.length
+	default:
+		return 0
+	}
 }
@@ -0,0 +1,67 @@
+package internal
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mokiat/gomath/sprec"
+	"github.com/mokiat/lacking/audio"
+)
+
+func TestPlaybackOfUndecodableStreamIsStopped(t *testing.T) {
+	player := NewOfflinePlayer(DeviceConfig{
+		SampleRate: 44100,
+	})
+	defer player.Close()
+
+	media := &StreamingMedia{
+		data: []byte("not an audio file"),
+	}
+	playback := player.Play(media, audio.PlayInfo{
+		Gain: 1.0,
+	})
+	if playback.State() != PlaybackStateStopped {
+		t.Fatalf("expected playback to be stopped, got %v", playback.State())
+	}
+
+	playback.Seek(time.Second)
+	if position := playback.Position(); position != 0 {
+		t.Fatalf("expected zero position, got %v", position)
+	}
+	player.Advance(10 * time.Millisecond)
+}
+
+func TestPlaybackResumesSteadyPitchAfterEmitterIsCleared(t *testing.T) {
+	player := NewOfflinePlayer(DeviceConfig{
+		SampleRate: 44100,
+	})
+	defer player.Close()
+
+	media := newToneMedia(t, player, 440.0, 5*time.Second)
+	playback := player.Play(media, audio.PlayInfo{
+		Gain: 1.0,
+	})
+	playback.SetEmitter(Emitter{
+		Position:    sprec.NewVec3(0.0, 0.0, -10.0),
+		Velocity:    sprec.NewVec3(0.0, 0.0, 50.0),
+		MinDistance: 100.0,
+		MaxDistance: 1000.0,
+		Rolloff:     1.0,
+	})
+	player.Advance(200 * time.Millisecond)
+	if !playback.interpolating {
+		t.Fatalf("expected the pitch of the playback to change")
+	}
+
+	playback.ClearEmitter()
+	player.Advance(time.Second)
+	if playback.interpolating {
+		t.Fatalf("expected the playback to stop interpolating")
+	}
+
+	position := playback.currentPosition()
+	player.Advance(100 * time.Millisecond)
+	if advanced := playback.currentPosition() - position; advanced != 4410 {
+		t.Fatalf("expected the playback to advance by 4410 frames, got %d", advanced)
+	}
+}
@@ -1,22 +1,21 @@
 package internal
 
 import (
-	"bytes"
 	"fmt"
-	"io"
+	"slices"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/gen2brain/malgo"
-	"github.com/hajimehoshi/go-mp3"
 	"github.com/mokiat/gblob"
 	"github.com/mokiat/lacking/audio"
 	"github.com/mokiat/lacking/debug/log"
 )
 
-func NewPlayer() (*Player, error) {
-	player := &Player{
-		playbacks: make(map[*Playback]struct{}),
-	}
+func NewPlayer(config DeviceConfig) (*Player, error) {
+	config = config.withDefaults()
+	player := newPlayer(config)
 
 	ctx, err := malgo.InitContext(nil, malgo.ContextConfig{}, nil)
 	if err != nil {
@@ -24,133 +23,531 @@ func NewPlayer() (*Player, error) {
 	}
 	player.ctx = ctx
 
+	if err := player.openDevice(config); err != nil {
+		ctx.Uninit()
+		ctx.Free()
+		return nil, err
+	}
+	return player, nil
+}
+
+func newPlayer(config DeviceConfig) *Player {
+	player := &Player{
+		sampleRate: config.SampleRate,
+		closeCh:    make(chan struct{}),
+		dither:     newDitherSource(),
+	}
+	player.resampleQuality.Store(int32(ResampleQualityMedium))
+	player.commands.init()
+	player.master = newBus(player, nil)
+	player.buses = []*Bus{player.master}
+	player.mixer.addBus(player.master)
+	player.listener = newListener(player)
+	player.limiter = newLimiter(config.SampleRate)
+	return player
+}
+
+type Player struct {
+	ctx    *malgo.AllocatedContext
+	device *malgo.Device
+	config DeviceConfig
+
+	// deviceMU serializes the opening and closing of devices.
+	deviceMU sync.Mutex
+
+	// switching indicates that the device is being replaced, in which case
+	// playbacks should survive the stopping of the old device.
+	switching  atomic.Bool
+	recovering atomic.Bool
+	closing    atomic.Bool
+	closeCh    chan struct{}
+
+	eventCallback atomic.Pointer[func(DeviceEvent)]
+
+	sampleRate int
+
+	// resampleQuality holds the ResampleQuality of new media. It can be
+	// changed from any goroutine.
+	resampleQuality atomic.Int32
+
+	// controlMU guards the configuration and the bus hierarchy, as seen
+	// by other goroutines. It is never locked by the audio thread, which
+	// receives changes through the command queue instead.
+	controlMU sync.Mutex
+	commands  commandQueue
+	master    *Bus
+	buses     []*Bus
+	listener  *Listener
+	limiter   *Limiter
+	captures  []*Capture
+
+	// The following are owned by the audio thread.
+	mixer  mixer
+	output []MediaFrame
+	dither ditherSource
+
+	// offline indicates that the player has no device and that audio is
+	// mixed on demand instead. The caller of Advance then acts as the audio
+	// thread, which is why renderMU serializes it.
+	offline  bool
+	renderMU sync.Mutex
+	clock    time.Duration
+}
+
+// MasterBus returns the bus that is sent to the device.
+func (p *Player) MasterBus() *Bus {
+	return p.master
+}
+
+// Listener returns the listener from which the emitters of playbacks are
+// heard.
+func (p *Player) Listener() *Listener {
+	return p.listener
+}
+
+// Limiter returns the limiter of the master output.
+func (p *Player) Limiter() *Limiter {
+	return p.limiter
+}
+
+// CreateBus creates a new bus that routes to the specified parent bus. A
+// nil parent routes to the master bus.
+func (p *Player) CreateBus(parent *Bus) *Bus {
+	p.controlMU.Lock()
+	defer p.controlMU.Unlock()
+	if parent == nil || parent.deleted {
+		parent = p.master
+	}
+	bus := newBus(p, parent)
+	p.buses = append(p.buses, bus)
+	p.commands.push(func() {
+		p.mixer.addBus(bus)
+	})
+	return bus
+}
+
+// Devices returns the audio output devices of the system. An offline
+// player has none.
+func (p *Player) Devices() ([]DeviceInfo, error) {
+	if p.offline {
+		return nil, nil
+	}
+	return listDevices(p.ctx.Context, malgo.Playback)
+}
+
+// DeviceConfig returns the configuration of the current output device.
+func (p *Player) DeviceConfig() DeviceConfig {
+	p.controlMU.Lock()
+	defer p.controlMU.Unlock()
+	return p.config
+}
+
+// SetDevice switches playback to the device with the specified ID. An
+// empty ID selects the default device of the system. Active playbacks
+// continue on the new device. If the new device cannot be opened, the
+// previous one is restored.
+func (p *Player) SetDevice(deviceID string) error {
+	if p.offline {
+		return fmt.Errorf("offline player has no device")
+	}
+	p.deviceMU.Lock()
+	defer p.deviceMU.Unlock()
+
+	previous := p.DeviceConfig()
+	config := previous
+	config.DeviceID = deviceID
+
+	p.switching.Store(true)
+	defer p.switching.Store(false)
+
+	p.closeDevice()
+	if err := p.openDevice(config); err != nil {
+		if restoreErr := p.openDevice(previous); restoreErr != nil {
+			log.Error("Error restoring audio device: %v", restoreErr)
+		}
+		return err
+	}
+	return nil
+}
+
+// SetDeviceEventCallback specifies a callback that is notified when the
+// output device is lost or recovered. The callback is invoked from a
+// background goroutine.
+func (p *Player) SetDeviceEventCallback(callback func(event DeviceEvent)) {
+	if callback == nil {
+		p.eventCallback.Store(nil)
+	} else {
+		p.eventCallback.Store(&callback)
+	}
+}
+
+func (p *Player) notifyDeviceEvent(event DeviceEvent) {
+	if callback := p.eventCallback.Load(); callback != nil {
+		(*callback)(event)
+	}
+}
+
+// recoverDevice replaces a device that has stopped unexpectedly. It keeps
+// retrying, with increasing delays, until a device is opened or the
+// player is closed.
+func (p *Player) recoverDevice() {
+	defer p.recovering.Store(false)
+
+	lost := p.DeviceConfig()
+	log.Warn("Audio device stopped unexpectedly")
+	p.notifyDeviceEvent(DeviceEvent{
+		Type:     DeviceEventLost,
+		DeviceID: lost.DeviceID,
+	})
+
+	delay := deviceRecoveryMinDelay
+	for {
+		select {
+		case <-p.closeCh:
+			return
+		case <-time.After(delay):
+		}
+		config, recovered, err := p.reopenDevice()
+		if err == nil {
+			if recovered {
+				log.Info("Audio device recovered")
+				p.notifyDeviceEvent(DeviceEvent{
+					Type:     DeviceEventRecovered,
+					DeviceID: config.DeviceID,
+				})
+			}
+			return
+		}
+		log.Warn("Error recovering audio device: %v", err)
+		delay = min(delay*2, deviceRecoveryMaxDelay)
+	}
+}
+
+// reopenDevice opens the configured device again, falling back to the
+// default device. It returns false if there was nothing to recover, since
+// the player got closed or the device got replaced in the meantime.
+func (p *Player) reopenDevice() (DeviceConfig, bool, error) {
+	p.deviceMU.Lock()
+	defer p.deviceMU.Unlock()
+
+	if p.closing.Load() {
+		return DeviceConfig{}, false, nil
+	}
+	if p.device != nil && p.device.IsStarted() {
+		return p.config, false, nil
+	}
+
+	p.switching.Store(true)
+	defer p.switching.Store(false)
+
+	p.closeDevice()
+	config := p.config
+	err := p.openDevice(config)
+	if err != nil && config.DeviceID != "" {
+		config.DeviceID = ""
+		err = p.openDevice(config)
+	}
+	return config, true, err
+}
+
+func (p *Player) openDevice(config DeviceConfig) error {
 	deviceConfig := malgo.DefaultDeviceConfig(malgo.Playback)
-	deviceConfig.Playback.Format = malgo.FormatS16
-	deviceConfig.Playback.Channels = 2
-	deviceConfig.SampleRate = 44100
+	deviceConfig.Playback.Format = config.Format.malgoFormat()
+	deviceConfig.Playback.Channels = uint32(config.Channels)
+	deviceConfig.SampleRate = uint32(config.SampleRate)
+	deviceConfig.PeriodSizeInFrames = uint32(config.PeriodSize)
+	deviceConfig.Periods = uint32(config.Periods)
 	deviceConfig.Alsa.NoMMap = 1
+	if config.DeviceID != "" {
+		id, err := findDevice(p.ctx.Context, malgo.Playback, config.DeviceID)
+		if err != nil {
+			return err
+		}
+		deviceConfig.Playback.DeviceID = id.Pointer()
+	}
 
 	deviceCallbacks := malgo.DeviceCallbacks{
-		Data: player.onSamples,
-		Stop: player.onStop,
+		Data: p.onSamples,
+		Stop: p.onStop,
 	}
 
-	device, err := malgo.InitDevice(ctx.Context, deviceConfig, deviceCallbacks)
+	device, err := malgo.InitDevice(p.ctx.Context, deviceConfig, deviceCallbacks)
 	if err != nil {
-		return nil, fmt.Errorf("error creating malgo device: %w", err)
+		return fmt.Errorf("error creating malgo device: %w", err)
 	}
-	player.device = device
+
+	// The audio thread reads the configuration without locking, which is
+	// safe since it only changes while no device is running.
+	p.controlMU.Lock()
+	p.config = config
+	p.controlMU.Unlock()
 
 	if err := device.Start(); err != nil {
-		return nil, fmt.Errorf("error starting malgo device: %w", err)
+		device.Uninit()
+		return fmt.Errorf("error starting malgo device: %w", err)
 	}
-
-	return player, nil
+	p.device = device
+	return nil
 }
 
-type Player struct {
-	ctx    *malgo.AllocatedContext
-	device *malgo.Device
+func (p *Player) closeDevice() {
+	if p.device == nil {
+		return
+	}
+	p.device.Stop()
+	p.device.Uninit()
+	p.device = nil
+}
 
-	playbackMU sync.Mutex
-	playbacks  map[*Playback]struct{}
+// SetResampleQuality specifies the quality of the conversion of media
+// whose sample rate differs from the one of the device. It affects media
+// and streaming playbacks that are created afterwards.
+func (p *Player) SetResampleQuality(quality ResampleQuality) {
+	p.resampleQuality.Store(int32(quality))
 }
 
 func (p *Player) CreateMedia(info audio.MediaInfo) *Media {
-	decoder, err := mp3.NewDecoder(bytes.NewReader(info.Data))
+	quality := ResampleQuality(p.resampleQuality.Load())
+	dec, err := p.newDecoder(info.Data, quality)
 	if err != nil {
 		log.Error("Error creating decoder: %v", err)
 		return nil
 	}
 
-	if decoder.SampleRate() != 44100 {
-		//  TODO: Handle resample in the future.
-		log.Error("Unsupported sample rate: %d", decoder.SampleRate())
-		return nil
-	}
-
-	data, err := io.ReadAll(decoder)
+	frames, err := decodeAll(dec)
 	if err != nil {
 		log.Error("Error reading decoder: %v", err)
 		return nil
 	}
-	buffer := gblob.LittleEndianBlock(data)
 
-	length := len(data) / 4
+	length := len(frames)
 	leftChannel := Channel{
 		samples: make([]float32, length),
 	}
 	rightChannel := Channel{
 		samples: make([]float32, length),
 	}
-	for i := 0; i < length; i++ {
-		leftInt16 := buffer.Int16(i*4 + 0)
-		rightInt16 := buffer.Int16(i*4 + 2)
-		leftChannel.samples[i] = int16ToFloat32(leftInt16)
-		rightChannel.samples[i] = int16ToFloat32(rightInt16)
+	for i, frame := range frames {
+		leftChannel.samples[i] = frame.Left
+		rightChannel.samples[i] = frame.Right
 	}
 
 	return &Media{
-		sampleRate:   44100,
+		sampleRate:   p.sampleRate,
 		length:       length,
+		loop:         clampLoopRegion(decoderLoopRegion(dec), length),
 		leftChannel:  leftChannel,
 		rightChannel: rightChannel,
 	}
 }
 
-func (p *Player) Play(media *Media, info audio.PlayInfo) *Playback {
-	p.playbackMU.Lock()
-	defer p.playbackMU.Unlock()
+func (p *Player) CreateStreamingMedia(info audio.MediaInfo) *StreamingMedia {
+	quality := ResampleQuality(p.resampleQuality.Load())
+	dec, err := p.newDecoder(info.Data, quality)
+	if err != nil {
+		log.Error("Error creating decoder: %v", err)
+		return nil
+	}
+
+	length := dec.Length()
+	return &StreamingMedia{
+		data:       info.Data,
+		quality:    quality,
+		sampleRate: dec.SampleRate(),
+		length:     length,
+		loop:       clampLoopRegion(decoderLoopRegion(dec), length),
+	}
+}
+
+func (p *Player) Play(media audio.Media, info audio.PlayInfo) *Playback {
+	return p.PlayOnBus(p.master, media, info)
+}
+
+// PlayOnBus starts a playback that routes to the specified bus. A nil bus
+// routes to the master bus.
+func (p *Player) PlayOnBus(bus *Bus, media audio.Media, info audio.PlayInfo) *Playback {
+	playback := p.createPlayback(media, info)
+	p.startPlayback(bus, playback)
+	return playback
+}
 
-	playback := &Playback{
-		media: media,
+// PlayWithFadeIn starts a playback that routes to the specified bus and
+// fades in from silence over the specified duration.
+func (p *Player) PlayWithFadeIn(bus *Bus, media audio.Media, info audio.PlayInfo, duration time.Duration, curve FadeCurve) *Playback {
+	playback := p.createPlayback(media, info)
+	playback.fader.level = 0.0
+	playback.fader.fadeTo(1.0, duration, p.sampleRate, curve)
+	p.startPlayback(bus, playback)
+	return playback
+}
 
-		loop: info.Loop,
-		gain: float32(info.Gain),
-		pan:  float32(info.Pan),
+// Crossfade starts a playback of the specified media on the bus of the
+// from playback and performs an equal-power crossfade between the two over
+// the specified duration. The from playback is stopped once the crossfade
+// is complete. Both fades start on the same frame.
+func (p *Player) Crossfade(from *Playback, media audio.Media, info audio.PlayInfo, duration time.Duration) *Playback {
+	playback := p.createPlayback(media, info)
+	playback.fader.level = 0.0
+	playback.fader.fadeTo(1.0, duration, p.sampleRate, FadeCurveEqualPower)
 
-		offset: 0,
+	// A single command starts both fades, so that they are applied by the
+	// audio thread on the same frame.
+	p.commands.push(func() {
+		bus := p.master
+		if from != nil && from.mixBus != nil {
+			bus = p.mixer.resolve(from.mixBus)
+			from.fader.fadeTo(0.0, duration, p.sampleRate, FadeCurveEqualPower)
+			from.fader.fade.stop = true
+		}
+		if !playback.done.Load() {
+			playback.mixBus = bus
+			p.mixer.addVoice(playback)
+		}
+	})
+	return playback
+}
+
+func (p *Player) createPlayback(media audio.Media, info audio.PlayInfo) *Playback {
+	playback := newPlayback(p, float32(info.Gain), float32(info.Pan), info.Loop)
+	switch media := media.(type) {
+	case *Media:
+		playback.media = media
+	case *StreamingMedia:
+		// The stream starts decoding right away, so that frames are
+		// available by the time the audio thread needs them.
+		dec, err := p.newDecoder(media.data, media.quality)
+		if err != nil {
+			log.Error("Error creating stream decoder: %v", err)
+			playback.done.Store(true)
+			return playback
+		}
+		playback.stream = newStream(dec, media, info.Loop, p.offline)
+	default:
+		log.Error("Unsupported media type %T", media)
+		playback.done.Store(true)
 	}
-	p.playbacks[playback] = struct{}{}
 	return playback
 }
 
+// startPlayback schedules the playback to be added to the mix.
+func (p *Player) startPlayback(bus *Bus, playback *Playback) {
+	if playback.done.Load() {
+		return
+	}
+	p.controlMU.Lock()
+	defer p.controlMU.Unlock()
+	if bus == nil || bus.deleted {
+		// Deleted buses route to the master bus as well.
+		bus = p.master
+	}
+	// The command is pushed while the mutex is held, so that it cannot be
+	// overtaken by the removal of the bus.
+	playback.mixBus = bus
+	p.commands.push(func() {
+		p.mixer.addVoice(playback)
+	})
+}
+
+// newDecoder creates a decoder that produces frames at the sample rate of
+// the device, resampling the data if necessary.
+func (p *Player) newDecoder(data []byte, quality ResampleQuality) (decoder, error) {
+	dec, err := newDecoder(data)
+	if err != nil {
+		return nil, err
+	}
+	if dec.SampleRate() != p.sampleRate {
+		return newResampler(dec, p.sampleRate, quality), nil
+	}
+	return dec, nil
+}
+
+// Close releases the device and the audio context. Calling it more than
+// once has no effect.
 func (p *Player) Close() {
-	p.device.Stop()
-	p.device.Uninit()
-	p.ctx.Uninit()
-	p.ctx.Free()
+	if !p.closing.CompareAndSwap(false, true) {
+		return
+	}
+	close(p.closeCh)
+
+	p.deviceMU.Lock()
+	defer p.deviceMU.Unlock()
+
+	p.controlMU.Lock()
+	captures := slices.Clone(p.captures)
+	p.controlMU.Unlock()
+	for _, capture := range captures {
+		capture.Close()
+	}
+
+	p.closeDevice()
+	if p.ctx != nil {
+		p.ctx.Uninit()
+		p.ctx.Free()
+	}
+
+	// The audio thread is no longer running, so the pending commands and
+	// the voices can be handled here.
+	p.renderMU.Lock()
+	defer p.renderMU.Unlock()
+	p.commands.run()
+	for _, voice := range p.mixer.voices {
+		voice.stop()
+	}
+	clear(p.mixer.voices)
+	p.mixer.voices = p.mixer.voices[:0]
 }
 
 func (p *Player) onSamples(pOutputSample, pInputSamples []byte, framecount uint32) {
-	p.playbackMU.Lock()
-	defer p.playbackMU.Unlock()
+	p.output = slices.Grow(p.output[:0], int(framecount))[:framecount]
+	p.mix(p.output)
 
 	buffer := gblob.LittleEndianBlock(pOutputSample)
+	for i, frame := range p.output {
+		writeFrame(buffer, i, frame, p.config, &p.dither)
+	}
+}
 
-	for i := 0; i < int(framecount); i++ {
-		var aggFrame MediaFrame
+// mix renders the next block of the master output into the specified
+// frames. It is called by the audio thread.
+func (p *Player) mix(frames []MediaFrame) {
+	p.commands.run()
+	p.mixer.prepare(len(frames), p.sampleRate)
+	p.limiter.prepare()
 
-		for playback := range p.playbacks {
-			frame, ok := playback.Frame()
-			if !ok {
-				delete(p.playbacks, playback)
-				continue
-			}
-			frame.ApplyGain(playback.gain)
-			frame.ApplyPan(playback.pan)
+	listener := p.listener.state()
+	scratch, gains := p.mixer.scratch, p.mixer.gains
 
-			aggFrame.Add(frame)
+	// Voices that have ended are removed by compacting the slice in place.
+	alive := 0
+	for _, voice := range p.mixer.voices {
+		voice.prepare(listener, len(frames))
+		ok := voice.render(scratch, gains)
+		p.mixer.add(voice.mixBus, scratch)
+		if ok {
+			p.mixer.voices[alive] = voice
+			alive++
+		} else {
+			voice.stop()
 		}
+	}
+	clear(p.mixer.voices[alive:])
+	p.mixer.voices = p.mixer.voices[:alive]
 
-		aggFrame.Clamp()
-		buffer.SetInt16(i*4+0, float32ToInt16(aggFrame.Left))
-		buffer.SetInt16(i*4+2, float32ToInt16(aggFrame.Right))
+	for i, frame := range p.mixer.end() {
+		frame = p.limiter.process(frame)
+		frame.Clamp()
+		frames[i] = frame
 	}
 }
 
 func (p *Player) onStop() {
-	p.playbackMU.Lock()
-	defer p.playbackMU.Unlock()
-	clear(p.playbacks)
+	if p.switching.Load() || p.closing.Load() {
+		return
+	}
+	// The device stopped on its own, which usually means that it got
+	// disconnected. Playbacks are kept, so that they can resume from
+	// where they were once a device is available again.
+	if p.recovering.CompareAndSwap(false, true) {
+		go p.recoverDevice()
+	}
 }
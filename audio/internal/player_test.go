@@ -0,0 +1,80 @@
+package internal
+
+import (
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/mokiat/lacking/audio"
+)
+
+func BenchmarkMix(b *testing.B) {
+	const periodSize = 512
+	for _, voices := range []int{64, 256, 512} {
+		b.Run(fmt.Sprintf("voices=%d", voices), func(b *testing.B) {
+			player := NewOfflinePlayer(DeviceConfig{
+				SampleRate: 44100,
+				PeriodSize: periodSize,
+			})
+			media := newToneMedia(b, player, 440.0, time.Second)
+			for range voices {
+				player.Play(media, audio.PlayInfo{
+					Loop: true,
+					Gain: 1.0 / float64(voices),
+				})
+			}
+			// The first period starts the playbacks.
+			player.Advance(time.Millisecond)
+			if count := len(player.mixer.voices); count != voices {
+				b.Fatalf("expected %d voices, got %d", voices, count)
+			}
+
+			frames := make([]MediaFrame, periodSize)
+			b.ReportAllocs()
+			for b.Loop() {
+				player.mix(frames)
+			}
+		})
+	}
+}
+
+func BenchmarkCommandQueue(b *testing.B) {
+	command := func() {}
+
+	b.Run("push-pop", func(b *testing.B) {
+		var queue commandQueue
+		queue.init()
+		b.ReportAllocs()
+		for b.Loop() {
+			queue.push(command)
+			queue.run()
+		}
+	})
+
+	b.Run("concurrent-push", func(b *testing.B) {
+		var queue commandQueue
+		queue.init()
+
+		// A single consumer drains the queue while producers push, like
+		// the audio thread does.
+		var stop atomic.Bool
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			for !stop.Load() {
+				queue.run()
+			}
+		}()
+
+		b.ReportAllocs()
+		b.RunParallel(func(pb *testing.PB) {
+			for pb.Next() {
+				queue.push(command)
+			}
+		})
+		stop.Store(true)
+		<-done
+		queue.run()
+	})
+}
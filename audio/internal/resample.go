@@ -0,0 +1,267 @@
+package internal
+
+import (
+	"errors"
+	"io"
+	"math"
+)
+
+// ResampleQuality controls the trade-off between the accuracy and the CPU
+// cost of sample rate conversion.
+type ResampleQuality int
+
+const (
+	// ResampleQualityLow uses a short filter, which is cheap but lets some
+	// aliasing through.
+	ResampleQualityLow ResampleQuality = iota
+
+	// ResampleQualityMedium is suitable for most content.
+	ResampleQualityMedium
+
+	// ResampleQualityHigh uses a long filter with a steep transition band.
+	ResampleQualityHigh
+)
+
+// resampleFilter describes the windowed-sinc filter of a quality level.
+type resampleFilter struct {
+	// halfTaps is the number of filter taps on each side of the
+	// interpolated position.
+	halfTaps int
+
+	// phases is the number of fractional positions for which the filter
+	// is precomputed. Positions in-between are interpolated linearly.
+	phases int
+
+	// beta is the Kaiser window parameter.
+	beta float64
+
+	// rolloff is the cutoff frequency relative to the Nyquist frequency
+	// of the lower of the two sample rates.
+	rolloff float64
+}
+
+func (q ResampleQuality) filter() resampleFilter {
+	switch q {
+	case ResampleQualityLow:
+		return resampleFilter{halfTaps: 8, phases: 128, beta: 5.0, rolloff: 0.90}
+	case ResampleQualityHigh:
+		return resampleFilter{halfTaps: 32, phases: 512, beta: 9.0, rolloff: 0.97}
+	default:
+		return resampleFilter{halfTaps: 16, phases: 256, beta: 7.0, rolloff: 0.94}
+	}
+}
+
+// newResampler creates a decoder that converts the frames of the source
+// decoder to the specified sample rate.
+func newResampler(source decoder, sampleRate int, quality ResampleQuality) *resampler {
+	filter := quality.filter()
+	sourceRate := source.SampleRate()
+	cutoff := filter.rolloff * min(1.0, float64(sampleRate)/float64(sourceRate))
+
+	taps := 2 * filter.halfTaps
+	table := make([]float32, (filter.phases+1)*taps)
+	for phase := 0; phase <= filter.phases; phase++ {
+		fraction := float64(phase) / float64(filter.phases)
+		coefficients := table[phase*taps : (phase+1)*taps]
+		sum := 0.0
+		values := make([]float64, taps)
+		for j := range taps {
+			// Tap j applies to the source frame at offset j-halfTaps+1
+			// from the integer part of the interpolated position.
+			distance := fraction - float64(j-filter.halfTaps+1)
+			values[j] = cutoff * sinc(cutoff*distance) * kaiser(distance/float64(filter.halfTaps), filter.beta)
+			sum += values[j]
+		}
+		// Normalizing each phase avoids a ripple at low frequencies.
+		for j := range taps {
+			coefficients[j] = float32(values[j] / sum)
+		}
+	}
+
+	return &resampler{
+		source:     source,
+		sourceRate: sourceRate,
+		targetRate: sampleRate,
+		halfTaps:   filter.halfTaps,
+		phases:     filter.phases,
+		table:      table,
+		input:      make([]MediaFrame, filter.halfTaps-1),
+		inputStart: -(filter.halfTaps - 1),
+		chunk:      make([]MediaFrame, 1024),
+		sourceEnd:  -1,
+	}
+}
+
+// resampler is a polyphase windowed-sinc resampler that converts the
+// output of a decoder on the fly.
+type resampler struct {
+	source     decoder
+	sourceRate int
+	targetRate int
+	halfTaps   int
+	phases     int
+	table      []float32
+
+	// input holds the source frames around the current position, starting
+	// with the source frame at inputStart.
+	input      []MediaFrame
+	inputStart int
+	chunk      []MediaFrame
+
+	// sourceEnd is the number of source frames, once the source has ended,
+	// and -1 otherwise.
+	sourceEnd int
+
+	// index and phase are the integer part and the fractional part, in
+	// units of 1/targetRate, of the position within the source.
+	index int
+	phase int
+}
+
+func (r *resampler) SampleRate() int {
+	return r.targetRate
+}
+
+func (r *resampler) Length() int {
+	return r.toTarget(r.source.Length())
+}
+
+func (r *resampler) Read(frames []MediaFrame) (int, error) {
+	r.compact()
+	taps := 2 * r.halfTaps
+	for count := range frames {
+		if r.sourceEnd >= 0 && r.index >= r.sourceEnd {
+			return count, io.EOF
+		}
+		if err := r.fill(r.index + r.halfTaps + 1); err != nil {
+			return count, err
+		}
+		if r.sourceEnd >= 0 && r.index >= r.sourceEnd {
+			return count, io.EOF
+		}
+
+		// Interpolate between the two nearest precomputed phases.
+		position := float64(r.phase) * float64(r.phases) / float64(r.targetRate)
+		phase := int(position)
+		weight := float32(position - float64(phase))
+		lower := r.table[phase*taps : (phase+1)*taps]
+		upper := r.table[(phase+1)*taps : (phase+2)*taps]
+
+		first := r.index - r.halfTaps + 1 - r.inputStart
+		var frame MediaFrame
+		for j, sample := range r.input[first : first+taps] {
+			coefficient := lower[j] + (upper[j]-lower[j])*weight
+			frame.Left += sample.Left * coefficient
+			frame.Right += sample.Right * coefficient
+		}
+		frames[count] = frame
+
+		r.phase += r.sourceRate
+		r.index += r.phase / r.targetRate
+		r.phase %= r.targetRate
+	}
+	return len(frames), nil
+}
+
+func (r *resampler) Seek(frame int) error {
+	frame = max(frame, 0)
+	sourcePos := int64(frame) * int64(r.sourceRate)
+	index := int(sourcePos / int64(r.targetRate))
+	start := max(index-r.halfTaps+1, 0)
+	if err := r.source.Seek(start); err != nil {
+		return err
+	}
+	r.input = r.input[:0]
+	for i := index - r.halfTaps + 1; i < start; i++ {
+		r.input = append(r.input, MediaFrame{})
+	}
+	r.inputStart = index - r.halfTaps + 1
+	r.sourceEnd = -1
+	r.index = index
+	r.phase = int(sourcePos % int64(r.targetRate))
+	return nil
+}
+
+func (r *resampler) LoopRegion() (loopRegion, bool) {
+	tagger, ok := r.source.(loopTagger)
+	if !ok {
+		return loopRegion{}, false
+	}
+	region, ok := tagger.LoopRegion()
+	if !ok {
+		return loopRegion{}, false
+	}
+	return loopRegion{
+		start: r.toTarget(region.start),
+		end:   r.toTarget(region.end),
+	}, true
+}
+
+// toTarget converts a source frame to the first target frame that is not
+// before it.
+func (r *resampler) toTarget(frame int) int {
+	value := int64(frame) * int64(r.targetRate)
+	return int((value + int64(r.sourceRate) - 1) / int64(r.sourceRate))
+}
+
+// fill makes sure that the input holds all source frames before the
+// specified one. Frames past the end of the source are silent.
+func (r *resampler) fill(end int) error {
+	for r.inputStart+len(r.input) < end {
+		if r.sourceEnd >= 0 {
+			r.input = append(r.input, MediaFrame{})
+			continue
+		}
+		count, err := r.source.Read(r.chunk)
+		r.input = append(r.input, r.chunk[:count]...)
+		if errors.Is(err, io.EOF) {
+			r.sourceEnd = r.inputStart + len(r.input)
+			continue
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// compact drops the input frames that are no longer needed.
+func (r *resampler) compact() {
+	unused := r.index - r.halfTaps + 1 - r.inputStart
+	if unused <= 0 {
+		return
+	}
+	unused = min(unused, len(r.input))
+	r.input = r.input[:copy(r.input, r.input[unused:])]
+	r.inputStart += unused
+}
+
+func sinc(x float64) float64 {
+	if x == 0.0 {
+		return 1.0
+	}
+	return math.Sin(math.Pi*x) / (math.Pi * x)
+}
+
+// kaiser evaluates the Kaiser window at x, which is in the range [-1, 1].
+func kaiser(x, beta float64) float64 {
+	if x <= -1.0 || x >= 1.0 {
+		return 0.0
+	}
+	return besselI0(beta*math.Sqrt(1.0-x*x)) / besselI0(beta)
+}
+
+// besselI0 evaluates the zeroth order modified Bessel function of the
+// first kind.
+func besselI0(x float64) float64 {
+	result := 1.0
+	term := 1.0
+	for k := 1; k < 50; k++ {
+		term *= (x / (2.0 * float64(k))) * (x / (2.0 * float64(k)))
+		result += term
+		if term < result*1e-12 {
+			break
+		}
+	}
+	return result
+}
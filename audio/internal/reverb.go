@@ -0,0 +1,230 @@
+package internal
+
+const (
+	// reverbStereoSpread is the difference, in frames at 44100 Hz, between
+	// the filter lengths of the left and the right channel.
+	reverbStereoSpread = 23
+
+	// reverbInputGain scales the input of the comb filters, which would
+	// otherwise sum to a very loud signal.
+	reverbInputGain = 0.015
+)
+
+// reverbCombLengths and reverbAllPassLengths are the filter lengths, in
+// frames at 44100 Hz, of the Freeverb algorithm.
+var (
+	reverbCombLengths    = [...]int{1116, 1188, 1277, 1356, 1422, 1491, 1557, 1617}
+	reverbAllPassLengths = [...]int{556, 441, 341, 225}
+)
+
+// NewReverb creates a reverberation effect with the specified room size
+// and damping, both in the range [0, 1], and wet level.
+func NewReverb(roomSize, damping, wet float32) *Reverb {
+	result := &Reverb{
+		left:  newReverbChannel(0),
+		right: newReverbChannel(reverbStereoSpread),
+	}
+	result.roomSize.Store(clampUnit(roomSize))
+	result.damping.Store(clampUnit(damping))
+	result.wet.Store(max(wet, 0.0))
+	result.dry.Store(1.0)
+	result.width.Store(1.0)
+	return result
+}
+
+// Reverb is an Effect that simulates the reflections of a room, based on
+// the Freeverb algorithm by Jezar at Dreampoint.
+//
+// The setters of a Reverb can be called from any goroutine.
+type Reverb struct {
+	roomSize atomicFloat32
+	damping  atomicFloat32
+	wet      atomicFloat32
+	dry      atomicFloat32
+	width    atomicFloat32
+
+	// The following are owned by the audio thread.
+	sampleRate int
+	left       reverbChannel
+	right      reverbChannel
+	feedback   float32
+	damp       float32
+	wetLeft    float32
+	wetRight   float32
+	dryLevel   float32
+}
+
+var _ Effect = (*Reverb)(nil)
+
+// RoomSize returns the size of the simulated room.
+func (r *Reverb) RoomSize() float32 {
+	return r.roomSize.Load()
+}
+
+// SetRoomSize specifies the size of the simulated room, in the range
+// [0, 1]. Larger rooms have a longer decay.
+func (r *Reverb) SetRoomSize(size float32) {
+	r.roomSize.Store(clampUnit(size))
+}
+
+// Damping returns the absorption of high frequencies.
+func (r *Reverb) Damping() float32 {
+	return r.damping.Load()
+}
+
+// SetDamping specifies the absorption of high frequencies by the walls of
+// the simulated room, in the range [0, 1].
+func (r *Reverb) SetDamping(damping float32) {
+	r.damping.Store(clampUnit(damping))
+}
+
+// Wet returns the level of the reverberation.
+func (r *Reverb) Wet() float32 {
+	return r.wet.Load()
+}
+
+// SetWet specifies the level of the reverberation.
+func (r *Reverb) SetWet(wet float32) {
+	r.wet.Store(max(wet, 0.0))
+}
+
+// Dry returns the level of the unprocessed input.
+func (r *Reverb) Dry() float32 {
+	return r.dry.Load()
+}
+
+// SetDry specifies the level of the unprocessed input. The default is 1.
+func (r *Reverb) SetDry(dry float32) {
+	r.dry.Store(max(dry, 0.0))
+}
+
+// Width returns the stereo width of the reverberation.
+func (r *Reverb) Width() float32 {
+	return r.width.Load()
+}
+
+// SetWidth specifies the stereo width of the reverberation, in the range
+// [0, 1]. The default is 1.
+func (r *Reverb) SetWidth(width float32) {
+	r.width.Store(clampUnit(width))
+}
+
+func (r *Reverb) Prepare(sampleRate int) {
+	if sampleRate != r.sampleRate {
+		r.sampleRate = sampleRate
+		r.left.resize(sampleRate, 0)
+		r.right.resize(sampleRate, reverbStereoSpread)
+	}
+	// The scaling of the parameters follows the original implementation.
+	r.feedback = r.roomSize.Load()*0.28 + 0.7
+	r.damp = r.damping.Load() * 0.4
+	wet := r.wet.Load() * 3.0
+	width := r.width.Load()
+	r.wetLeft = wet * (width/2.0 + 0.5)
+	r.wetRight = wet * ((1.0 - width) / 2.0)
+	r.dryLevel = r.dry.Load()
+}
+
+func (r *Reverb) Process(frame MediaFrame) MediaFrame {
+	input := (frame.Left + frame.Right) * reverbInputGain
+	left := r.left.process(input, r.feedback, r.damp)
+	right := r.right.process(input, r.feedback, r.damp)
+	return MediaFrame{
+		Left:  frame.Left*r.dryLevel + left*r.wetLeft + right*r.wetRight,
+		Right: frame.Right*r.dryLevel + right*r.wetLeft + left*r.wetRight,
+	}
+}
+
+// reverbChannel holds the filters of one channel of a Reverb.
+type reverbChannel struct {
+	combs     [len(reverbCombLengths)]reverbComb
+	allPasses [len(reverbAllPassLengths)]reverbAllPass
+}
+
+// newReverbChannel allocates the filters of a channel for the highest
+// supported sample rate, so that changing the sample rate on the audio
+// thread does not allocate.
+func newReverbChannel(spread int) reverbChannel {
+	var result reverbChannel
+	for i, length := range reverbCombLengths {
+		result.combs[i] = reverbComb{
+			buffer: make([]float32, reverbFilterLength(length, spread, maxEffectSampleRate)),
+		}
+	}
+	for i, length := range reverbAllPassLengths {
+		result.allPasses[i] = reverbAllPass{
+			buffer: make([]float32, reverbFilterLength(length, spread, maxEffectSampleRate)),
+		}
+	}
+	return result
+}
+
+// resize adjusts the filter lengths to the sample rate and clears the
+// state of the filters.
+func (c *reverbChannel) resize(sampleRate, spread int) {
+	for i, length := range reverbCombLengths {
+		comb := &c.combs[i]
+		comb.buffer = comb.buffer[:min(reverbFilterLength(length, spread, sampleRate), cap(comb.buffer))]
+		clear(comb.buffer)
+		comb.position = 0
+		comb.store = 0.0
+	}
+	for i, length := range reverbAllPassLengths {
+		allPass := &c.allPasses[i]
+		allPass.buffer = allPass.buffer[:min(reverbFilterLength(length, spread, sampleRate), cap(allPass.buffer))]
+		clear(allPass.buffer)
+		allPass.position = 0
+	}
+}
+
+// reverbFilterLength scales a filter length, in frames at 44100 Hz, to
+// the specified sample rate.
+func reverbFilterLength(length, spread, sampleRate int) int {
+	return max((length+spread)*sampleRate/44100, 1)
+}
+
+func (c *reverbChannel) process(input, feedback, damp float32) float32 {
+	var output float32
+	for i := range c.combs {
+		output += c.combs[i].process(input, feedback, damp)
+	}
+	for i := range c.allPasses {
+		output = c.allPasses[i].process(output)
+	}
+	return output
+}
+
+// reverbComb is a feedback comb filter with a low-pass filter in the
+// feedback path.
+type reverbComb struct {
+	buffer   []float32
+	position int
+	store    float32
+}
+
+func (c *reverbComb) process(input, feedback, damp float32) float32 {
+	output := c.buffer[c.position]
+	c.store = output*(1.0-damp) + c.store*damp
+	c.buffer[c.position] = input + c.store*feedback
+	c.position = (c.position + 1) % len(c.buffer)
+	return output
+}
+
+// reverbAllPass is a Schroeder all-pass filter, which diffuses the echoes
+// of the comb filters.
+type reverbAllPass struct {
+	buffer   []float32
+	position int
+}
+
+func (a *reverbAllPass) process(input float32) float32 {
+	buffered := a.buffer[a.position]
+	a.buffer[a.position] = input + buffered*0.5
+	a.position = (a.position + 1) % len(a.buffer)
+	return buffered - input
+}
+
+// clampUnit limits the value to the range [0, 1].
+func clampUnit(value float32) float32 {
+	return max(min(value, 1.0), 0.0)
+}
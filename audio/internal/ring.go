@@ -0,0 +1,63 @@
+package internal
+
+import "sync/atomic"
+
+func newRingBuffer[T any](size int) *ringBuffer[T] {
+	return &ringBuffer[T]{
+		values: make([]T, max(size, 1)),
+	}
+}
+
+// ringBuffer is a queue of values with a single writer and a single
+// reader, which allows it to be lock-free.
+//
+// Positions are monotonically increasing counters that are mapped to
+// slots by the modulo of the size.
+type ringBuffer[T any] struct {
+	values []T
+
+	// readPos is owned by the reader and writePos by the writer.
+	readPos  atomic.Uint64
+	writePos atomic.Uint64
+}
+
+// len returns the number of values that can be read.
+func (b *ringBuffer[T]) len() int {
+	return int(b.writePos.Load() - b.readPos.Load())
+}
+
+// write appends the values if there is room for all of them. It returns
+// false if the values were dropped instead.
+func (b *ringBuffer[T]) write(values []T) bool {
+	write := b.writePos.Load()
+	if int(write-b.readPos.Load())+len(values) > len(b.values) {
+		return false
+	}
+	size := uint64(len(b.values))
+	for i, value := range values {
+		b.values[(write+uint64(i))%size] = value
+	}
+	b.writePos.Store(write + uint64(len(values)))
+	return true
+}
+
+// read removes values from the front of the buffer and stores them in the
+// specified slice. It returns the number of values that were read.
+func (b *ringBuffer[T]) read(values []T) int {
+	read := b.readPos.Load()
+	count := min(len(values), int(b.writePos.Load()-read))
+	size := uint64(len(b.values))
+	for i := range count {
+		values[i] = b.values[(read+uint64(i))%size]
+	}
+	b.readPos.Store(read + uint64(count))
+	return count
+}
+
+// skip removes the specified number of values from the front of the
+// buffer without reading them.
+func (b *ringBuffer[T]) skip(count int) {
+	read := b.readPos.Load()
+	count = min(count, int(b.writePos.Load()-read))
+	b.readPos.Store(read + uint64(max(count, 0)))
+}
@@ -0,0 +1,301 @@
+package internal
+
+import (
+	"sync/atomic"
+
+	"github.com/mokiat/gomath/sprec"
+)
+
+const (
+	// defaultSpeedOfSound is the speed of sound in air, in units per
+	// second, assuming that a unit is a meter.
+	defaultSpeedOfSound = 343.0
+
+	// minDopplerPitch and maxDopplerPitch limit the pitch shift that is
+	// caused by the Doppler effect.
+	minDopplerPitch = 0.25
+	maxDopplerPitch = 4.0
+
+	// spatialEpsilon is the distance below which an emitter is considered
+	// to be at the position of the listener.
+	spatialEpsilon = 0.0001
+)
+
+// AttenuationModel specifies how the volume of an emitter decreases with
+// the distance to the listener.
+type AttenuationModel int
+
+const (
+	// AttenuationInverse decreases the volume in inverse proportion to the
+	// distance, which matches the propagation of sound in air.
+	AttenuationInverse AttenuationModel = iota
+
+	// AttenuationLinear decreases the volume linearly, reaching silence at
+	// the maximum distance when the rolloff is 1.
+	AttenuationLinear
+
+	// AttenuationExponential decreases the volume exponentially with the
+	// distance.
+	AttenuationExponential
+)
+
+// Emitter describes the position and the directivity of a playback in 3D
+// space. All values are in world space.
+type Emitter struct {
+
+	// Position specifies the location of the emitter.
+	Position sprec.Vec3
+
+	// Velocity specifies the movement of the emitter, in units per second.
+	// It is used for the Doppler effect.
+	Velocity sprec.Vec3
+
+	// Direction specifies where the emitter is facing. A zero vector makes
+	// the emitter omnidirectional.
+	Direction sprec.Vec3
+
+	// Attenuation specifies the distance attenuation model.
+	Attenuation AttenuationModel
+
+	// MinDistance specifies the distance up to which the emitter is heard
+	// at full volume.
+	MinDistance float32
+
+	// MaxDistance specifies the distance beyond which the volume does not
+	// decrease any further.
+	MaxDistance float32
+
+	// Rolloff specifies how quickly the volume decreases with the
+	// distance.
+	Rolloff float32
+
+	// ConeInnerAngle specifies the angle of the cone, around the direction
+	// of the emitter, within which the emitter is heard at full volume.
+	ConeInnerAngle sprec.Angle
+
+	// ConeOuterAngle specifies the angle of the cone, around the direction
+	// of the emitter, outside of which the volume is scaled by
+	// ConeOuterGain. The volume is interpolated between the two cones.
+	ConeOuterAngle sprec.Angle
+
+	// ConeOuterGain specifies the volume multiplier outside of the outer
+	// cone.
+	ConeOuterGain float32
+}
+
+// DefaultEmitter returns an omnidirectional Emitter at the origin that uses
+// inverse distance attenuation.
+func DefaultEmitter() Emitter {
+	return Emitter{
+		Attenuation:    AttenuationInverse,
+		MinDistance:    1.0,
+		MaxDistance:    100.0,
+		Rolloff:        1.0,
+		ConeInnerAngle: sprec.Degrees(360.0),
+		ConeOuterAngle: sprec.Degrees(360.0),
+		ConeOuterGain:  0.0,
+	}
+}
+
+// attenuation returns the volume multiplier at the specified distance.
+func (e Emitter) attenuation(distance float32) float32 {
+	minDistance := max(e.MinDistance, spatialEpsilon)
+	maxDistance := max(e.MaxDistance, minDistance)
+	distance = sprec.Clamp(distance, minDistance, maxDistance)
+	switch e.Attenuation {
+	case AttenuationLinear:
+		if maxDistance == minDistance {
+			return 1.0
+		}
+		return max(1.0-e.Rolloff*(distance-minDistance)/(maxDistance-minDistance), 0.0)
+	case AttenuationExponential:
+		return sprec.Pow(distance/minDistance, -e.Rolloff)
+	default:
+		return minDistance / (minDistance + e.Rolloff*(distance-minDistance))
+	}
+}
+
+// coneGain returns the volume multiplier for a listener that is at the
+// specified angle from the direction of the emitter.
+func (e Emitter) coneGain(angle sprec.Angle) float32 {
+	inner := e.ConeInnerAngle.Radians() / 2.0
+	outer := max(e.ConeOuterAngle.Radians()/2.0, inner)
+	switch radians := angle.Radians(); {
+	case radians <= inner:
+		return 1.0
+	case radians >= outer:
+		return e.ConeOuterGain
+	default:
+		return sprec.Mix(1.0, e.ConeOuterGain, (radians-inner)/(outer-inner))
+	}
+}
+
+func newListener(player *Player) *Listener {
+	listener := &Listener{
+		player: player,
+	}
+	listener.settings.Store(&listenerSettings{
+		transform:     sprec.IdentityMat4(),
+		speedOfSound:  defaultSpeedOfSound,
+		dopplerFactor: 1.0,
+	})
+	return listener
+}
+
+// Listener is the point in 3D space from which emitters are heard. Its
+// transform follows the usual camera convention, where the X axis points
+// to the right, the Y axis points up and the listener faces the negative
+// Z axis.
+//
+// The setters of a Listener can be called from any goroutine.
+type Listener struct {
+	player *Player
+
+	// settings are replaced as a whole, so that the audio thread can read
+	// them without locking. Changes are serialized by the control mutex
+	// of the player.
+	settings atomic.Pointer[listenerSettings]
+}
+
+// listenerSettings holds the configuration of a Listener.
+type listenerSettings struct {
+	transform     sprec.Mat4
+	velocity      sprec.Vec3
+	speedOfSound  float32
+	dopplerFactor float32
+}
+
+// Transform returns the position and orientation of the listener.
+func (l *Listener) Transform() sprec.Mat4 {
+	return l.settings.Load().transform
+}
+
+// SetTransform specifies the position and orientation of the listener.
+// The transform should not be scaled.
+func (l *Listener) SetTransform(transform sprec.Mat4) {
+	l.update(func(settings *listenerSettings) {
+		settings.transform = transform
+	})
+}
+
+// Velocity returns the movement of the listener.
+func (l *Listener) Velocity() sprec.Vec3 {
+	return l.settings.Load().velocity
+}
+
+// SetVelocity specifies the movement of the listener, in units per
+// second. It is used for the Doppler effect.
+func (l *Listener) SetVelocity(velocity sprec.Vec3) {
+	l.update(func(settings *listenerSettings) {
+		settings.velocity = velocity
+	})
+}
+
+// SpeedOfSound returns the speed of sound that is used for the Doppler
+// effect.
+func (l *Listener) SpeedOfSound() float32 {
+	return l.settings.Load().speedOfSound
+}
+
+// SetSpeedOfSound specifies the speed of sound, in units per second. The
+// default is 343, which assumes that a unit is a meter.
+func (l *Listener) SetSpeedOfSound(speed float32) {
+	l.update(func(settings *listenerSettings) {
+		settings.speedOfSound = max(speed, spatialEpsilon)
+	})
+}
+
+// DopplerFactor returns the strength of the Doppler effect.
+func (l *Listener) DopplerFactor() float32 {
+	return l.settings.Load().dopplerFactor
+}
+
+// SetDopplerFactor specifies the strength of the Doppler effect. A value
+// of zero disables it and the default is 1.
+func (l *Listener) SetDopplerFactor(factor float32) {
+	l.update(func(settings *listenerSettings) {
+		settings.dopplerFactor = max(factor, 0.0)
+	})
+}
+
+func (l *Listener) update(change func(settings *listenerSettings)) {
+	l.player.controlMU.Lock()
+	defer l.player.controlMU.Unlock()
+	settings := *l.settings.Load()
+	change(&settings)
+	l.settings.Store(&settings)
+}
+
+// listenerState is a snapshot of the listener that is taken once per
+// device period.
+type listenerState struct {
+	inverse       sprec.Mat4
+	position      sprec.Vec3
+	velocity      sprec.Vec3
+	speedOfSound  float32
+	dopplerFactor float32
+}
+
+func (l *Listener) state() listenerState {
+	settings := l.settings.Load()
+	return listenerState{
+		inverse:       sprec.InverseMat4(settings.transform),
+		position:      settings.transform.Translation(),
+		velocity:      settings.velocity,
+		speedOfSound:  settings.speedOfSound,
+		dopplerFactor: settings.dopplerFactor,
+	}
+}
+
+// spatialState holds the parameters with which an emitter is mixed.
+type spatialState struct {
+	gain  float32
+	pan   float32
+	pitch float32
+}
+
+// noSpatialState is the state of playbacks without an emitter.
+var noSpatialState = spatialState{
+	gain:  1.0,
+	pan:   0.0,
+	pitch: 1.0,
+}
+
+// spatialize returns the parameters with which the specified emitter is
+// heard by the listener.
+func (l listenerState) spatialize(emitter Emitter) spatialState {
+	local := sprec.Mat4Vec3Transformation(l.inverse, emitter.Position)
+	distance := local.Length()
+	if distance < spatialEpsilon {
+		return spatialState{
+			gain:  emitter.attenuation(0.0),
+			pan:   0.0,
+			pitch: 1.0,
+		}
+	}
+
+	// The emitter is panned according to the sine of its azimuth.
+	result := spatialState{
+		gain:  emitter.attenuation(distance),
+		pan:   local.X / distance,
+		pitch: 1.0,
+	}
+
+	toListener := sprec.Vec3Quot(sprec.Vec3Diff(l.position, emitter.Position), distance)
+	if !emitter.Direction.IsZero() {
+		cos := sprec.Vec3Dot(sprec.UnitVec3(emitter.Direction), toListener)
+		result.gain *= emitter.coneGain(sprec.Acos(sprec.Clamp(cos, -1.0, 1.0)))
+	}
+
+	if l.dopplerFactor > 0.0 {
+		// Velocities are limited below the speed of sound, as is done by
+		// OpenAL, so that the pitch stays finite.
+		limit := l.speedOfSound / l.dopplerFactor
+		listenerSpeed := sprec.Clamp(sprec.Vec3Dot(l.velocity, toListener), -limit, limit)
+		emitterSpeed := sprec.Clamp(sprec.Vec3Dot(emitter.Velocity, toListener), -limit, limit)
+		numerator := l.speedOfSound - l.dopplerFactor*listenerSpeed
+		denominator := max(l.speedOfSound-l.dopplerFactor*emitterSpeed, spatialEpsilon)
+		result.pitch = sprec.Clamp(numerator/denominator, minDopplerPitch, maxDopplerPitch)
+	}
+	return result
+}
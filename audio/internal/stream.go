@@ -0,0 +1,254 @@
+package internal
+
+import (
+	"errors"
+	"io"
+	"sync/atomic"
+
+	"github.com/mokiat/lacking/debug/log"
+)
+
+const (
+	// streamBufferSize is the number of frames that are decoded ahead of
+	// playback. It needs to be a power of two.
+	streamBufferSize = 1 << 15
+
+	// streamChunkSize is the number of frames that are decoded at once.
+	streamChunkSize = 4096
+
+	// noSeek indicates that there is no pending seek request.
+	noSeek = -1
+)
+
+// newStream creates a stream that decodes the specified media on a
+// background goroutine using the specified decoder. A blocking stream
+// waits for the decoder instead of returning silence, which is needed for
+// offline rendering.
+func newStream(dec decoder, media *StreamingMedia, loop, blocking bool) *stream {
+	result := &stream{
+		decoder:    dec,
+		sampleRate: media.sampleRate,
+		length:     media.length,
+		region:     media.loop,
+		blocking:   blocking,
+		wake:       make(chan struct{}, 1),
+		ready:      make(chan struct{}, 1),
+	}
+	result.loop.Store(loop)
+	result.seekTarget.Store(noSeek)
+	go result.run()
+	return result
+}
+
+// stream plays back a StreamingMedia through a ring buffer. The decoding
+// goroutine is the only writer and the audio thread is the only reader,
+// which allows the buffer to be lock-free.
+//
+// Positions are monotonically increasing frame counters that are mapped
+// to buffer slots by masking.
+type stream struct {
+	decoder    decoder
+	sampleRate int
+	length     int
+	region     loopRegion
+	loop       atomic.Bool
+	blocking   bool
+
+	buffer [streamBufferSize]MediaFrame
+
+	// readPos is owned by the reader; writePos and flushPos by the writer.
+	readPos  atomic.Uint64
+	writePos atomic.Uint64
+	flushPos atomic.Uint64
+
+	// position is the frame within the media that is read next.
+	position   atomic.Int64
+	seekTarget atomic.Int64
+	finished   atomic.Bool
+	closed     atomic.Bool
+
+	// wake notifies the writer that there is work to do and ready notifies
+	// a blocking reader that the writer has made progress.
+	wake  chan struct{}
+	ready chan struct{}
+}
+
+// Frame returns the next frame of the stream. It returns false once the
+// stream has ended. A silent frame is returned if the decoder has not
+// caught up yet, unless the stream is blocking.
+func (s *stream) Frame() (MediaFrame, bool) {
+	for {
+		frame, ok, available := s.tryFrame()
+		if available || !s.blocking {
+			return frame, ok
+		}
+		<-s.ready
+	}
+}
+
+// tryFrame returns the next frame of the stream. The last result is false
+// if the decoder has not caught up yet.
+func (s *stream) tryFrame() (MediaFrame, bool, bool) {
+	if s.closed.Load() {
+		return MediaFrame{}, false, true
+	}
+	if s.seekTarget.Load() != noSeek {
+		// The buffered frames are about to be discarded.
+		return MediaFrame{}, true, false
+	}
+	read := max(s.readPos.Load(), s.flushPos.Load())
+	if read == s.writePos.Load() {
+		if s.finished.Load() {
+			return MediaFrame{}, false, true
+		}
+		return MediaFrame{}, true, false
+	}
+	frame := s.buffer[read&(streamBufferSize-1)]
+	s.readPos.Store(read + 1)
+	s.position.Store(s.nextPosition(s.position.Load()))
+	if (read+1)%streamChunkSize == 0 {
+		s.signal()
+	}
+	return frame, true, true
+}
+
+// Seek requests that playback continues from the specified frame. A
+// blocking stream waits until the decoder has moved there.
+func (s *stream) Seek(frame int) {
+	frame = max(min(frame, s.length), 0)
+	s.seekTarget.Store(int64(frame))
+	s.signal()
+	if s.blocking {
+		for s.seekTarget.Load() != noSeek && !s.closed.Load() {
+			<-s.ready
+		}
+	}
+}
+
+// SetLoop specifies whether the stream should continue from the start of
+// the loop region once it reaches the end of it.
+func (s *stream) SetLoop(loop bool) {
+	s.loop.Store(loop)
+	s.signal()
+}
+
+// Position returns the frame within the media that is played next.
+func (s *stream) Position() int {
+	return int(s.position.Load())
+}
+
+// Close stops the decoding goroutine.
+func (s *stream) Close() {
+	s.closed.Store(true)
+	s.signal()
+}
+
+// Done returns whether the stream has been closed or has ended.
+func (s *stream) Done() bool {
+	if s.closed.Load() {
+		return true
+	}
+	read := max(s.readPos.Load(), s.flushPos.Load())
+	return s.finished.Load() && read >= s.writePos.Load()
+}
+
+// nextPosition returns the media frame that follows the specified one.
+func (s *stream) nextPosition(position int64) int64 {
+	position++
+	if s.loop.Load() && position >= int64(s.region.end) {
+		return int64(s.region.start)
+	}
+	return min(position, int64(s.length))
+}
+
+func (s *stream) signal() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// notify wakes up a blocking reader.
+func (s *stream) notify() {
+	select {
+	case s.ready <- struct{}{}:
+	default:
+	}
+}
+
+func (s *stream) run() {
+	defer s.notify()
+
+	chunk := make([]MediaFrame, streamChunkSize)
+	rewound := false
+
+	// decodePos is the media frame that the decoder produces next.
+	decodePos := 0
+	for !s.closed.Load() {
+		if target := s.seekTarget.Load(); target != noSeek {
+			if err := s.decoder.Seek(int(target)); err != nil {
+				log.Error("Error seeking stream: %v", err)
+			}
+			s.finished.Store(false)
+			s.flushPos.Store(s.writePos.Load())
+			s.position.Store(target)
+			decodePos = int(target)
+			s.seekTarget.CompareAndSwap(target, noSeek)
+			s.notify()
+			continue
+		}
+
+		read := max(s.readPos.Load(), s.flushPos.Load())
+		space := streamBufferSize - int(s.writePos.Load()-read)
+		if s.finished.Load() || space < streamChunkSize {
+			<-s.wake
+			continue
+		}
+
+		limit := len(chunk)
+		if s.loop.Load() {
+			limit = max(min(limit, s.region.end-decodePos), 0)
+		}
+		var (
+			count int
+			err   error
+		)
+		if limit > 0 {
+			count, err = s.decoder.Read(chunk[:limit])
+		} else {
+			// The end of the loop region has been reached.
+			err = io.EOF
+		}
+		s.write(chunk[:count])
+		decodePos += count
+		if count > 0 {
+			rewound = false
+		}
+		switch {
+		case errors.Is(err, io.EOF):
+			// Rewinding an empty media would loop forever.
+			if s.loop.Load() && !rewound {
+				rewound = true
+				if err := s.decoder.Seek(s.region.start); err != nil {
+					log.Error("Error rewinding stream: %v", err)
+					s.finished.Store(true)
+				}
+				decodePos = s.region.start
+			} else {
+				s.finished.Store(true)
+			}
+		case err != nil:
+			log.Error("Error decoding stream: %v", err)
+			s.finished.Store(true)
+		}
+		s.notify()
+	}
+}
+
+func (s *stream) write(frames []MediaFrame) {
+	write := s.writePos.Load()
+	for i, frame := range frames {
+		s.buffer[(write+uint64(i))&(streamBufferSize-1)] = frame
+	}
+	s.writePos.Store(write + uint64(len(frames)))
+}
@@ -0,0 +1,133 @@
+package internal
+
+import (
+	"encoding/binary"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// loopRegion specifies the part of a media that is repeated when the media
+// is played in a loop.
+type loopRegion struct {
+	start int
+	end   int
+}
+
+// loopTagger can optionally be implemented by a decoder whose format can
+// specify a loop region.
+type loopTagger interface {
+
+	// LoopRegion returns the loop region of the media, if one has been
+	// specified.
+	LoopRegion() (loopRegion, bool)
+}
+
+// decoderLoopRegion returns the loop region that is specified by the
+// decoder. It defaults to the whole media.
+func decoderLoopRegion(dec decoder) loopRegion {
+	if tagger, ok := dec.(loopTagger); ok {
+		if region, ok := tagger.LoopRegion(); ok {
+			return region
+		}
+	}
+	return loopRegion{
+		start: 0,
+		end:   dec.Length(),
+	}
+}
+
+// clampLoopRegion limits the loop region to a media with the specified
+// length. It falls back to the whole media if the region ends up empty.
+func clampLoopRegion(region loopRegion, length int) loopRegion {
+	region.end = min(region.end, length)
+	if region.start >= region.end {
+		return loopRegion{start: 0, end: length}
+	}
+	return region
+}
+
+// parseLoopTags extracts a loop region from Vorbis comments. The LOOPSTART
+// tag specifies the first frame of the region and the optional LOOPLENGTH
+// or LOOPEND tags specify its extent, where LOOPEND is the frame at which
+// playback jumps back to LOOPSTART. The region extends to the end of the
+// media if neither is specified.
+func parseLoopTags(comments []string, length int) (loopRegion, bool) {
+	start, loopLen, end := -1, -1, -1
+	for _, comment := range comments {
+		key, value, ok := strings.Cut(comment, "=")
+		if !ok {
+			continue
+		}
+		switch strings.ToUpper(key) {
+		case "LOOPSTART":
+			start = parseLoopTag(value)
+		case "LOOPLENGTH":
+			loopLen = parseLoopTag(value)
+		case "LOOPEND":
+			end = parseLoopTag(value)
+		}
+	}
+	if start < 0 {
+		return loopRegion{}, false
+	}
+	switch {
+	case loopLen > 0:
+		end = start + loopLen
+	case end < 0:
+		end = length
+	}
+	end = min(end, length)
+	if start >= end {
+		return loopRegion{}, false
+	}
+	return loopRegion{
+		start: start,
+		end:   end,
+	}, true
+}
+
+// parseLoopTag parses a frame index. It returns -1 if the value is invalid.
+func parseLoopTag(value string) int {
+	result, err := strconv.Atoi(strings.TrimSpace(value))
+	if err != nil || result < 0 {
+		return -1
+	}
+	return result
+}
+
+// parseVorbisComments parses the body of a Vorbis comment block, as used
+// by Vorbis, Opus and FLAC streams.
+func parseVorbisComments(data []byte) ([]string, error) {
+	readString := func() (string, bool) {
+		if len(data) < 4 {
+			return "", false
+		}
+		size := int(binary.LittleEndian.Uint32(data))
+		if size > len(data)-4 {
+			return "", false
+		}
+		result := string(data[4 : 4+size])
+		data = data[4+size:]
+		return result, true
+	}
+
+	if _, ok := readString(); !ok { // vendor
+		return nil, fmt.Errorf("vorbis comment vendor is truncated")
+	}
+	if len(data) < 4 {
+		return nil, fmt.Errorf("vorbis comment count is truncated")
+	}
+	count := int(binary.LittleEndian.Uint32(data))
+	data = data[4:]
+
+	var result []string
+	for range count {
+		comment, ok := readString()
+		if !ok {
+			return nil, fmt.Errorf("vorbis comment is truncated")
+		}
+		result = append(result, comment)
+	}
+	return result, nil
+}
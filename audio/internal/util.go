@@ -1,6 +1,13 @@
 package internal
 
-import "math"
+import (
+	"math"
+	"time"
+)
+
+// ditherSeed is the initial state of every ditherSource. A fixed seed
+// keeps rendered output reproducible.
+const ditherSeed = 0x9E3779B9
 
 func int16ToFloat32(value int16) float32 {
 	if value >= 0 {
@@ -10,10 +17,55 @@ func int16ToFloat32(value int16) float32 {
 	}
 }
 
-func float32ToInt16(value float32) int16 {
+// float32ToInt16 converts a sample to 16 bits with TPDF dither, which
+// turns the quantization error into a low level of noise that does not
+// depend on the signal, instead of audible distortion in quiet passages.
+func float32ToInt16(value float32, dither *ditherSource) int16 {
+	scaled := math.Round(float64(value*float32(math.MaxInt16) + dither.next()))
+	return int16(max(min(scaled, math.MaxInt16), math.MinInt16))
+}
+
+// ditherSource generates dither noise with a xorshift generator. It is
+// cheap and needs no locking, which is why each audio thread owns its
+// own instance instead of sharing a global random source.
+type ditherSource struct {
+	state uint32
+}
+
+func newDitherSource() ditherSource {
+	return ditherSource{
+		state: ditherSeed,
+	}
+}
+
+// next returns a value with a triangular distribution in the range of
+// two least significant bits, as the difference of two uniform values.
+func (s *ditherSource) next() float32 {
+	return s.uniform() - s.uniform()
+}
+
+// uniform returns a value in the range [0.0, 1.0).
+func (s *ditherSource) uniform() float32 {
+	x := s.state
+	x ^= x << 13
+	x ^= x >> 17
+	x ^= x << 5
+	s.state = x
+	return float32(x>>8) / (1 << 24)
+}
+
+func durationToFrames(duration time.Duration, sampleRate int) int {
+	return int(duration * time.Duration(sampleRate) / time.Second)
+}
+
+func float32ToInt32(value float32) int32 {
 	if value >= 0.0 {
-		return int16(value * float32(math.MaxInt16))
+		return int32(float64(value) * float64(math.MaxInt32))
 	} else {
-		return -int16(value * float32(math.MinInt16))
+		return -int32(float64(value) * float64(math.MinInt32))
 	}
 }
+
+func framesToDuration(frames, sampleRate int) time.Duration {
+	return time.Duration(frames) * time.Second / time.Duration(sampleRate)
+}
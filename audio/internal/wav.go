@@ -0,0 +1,244 @@
+package internal
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+
+	"github.com/mokiat/gblob"
+)
+
+const (
+	wavFormatPCM        = 0x0001
+	wavFormatFloat      = 0x0003
+	wavFormatExtensible = 0xFFFE
+)
+
+// isWAV returns whether the data starts with a RIFF WAVE header.
+func isWAV(data []byte) bool {
+	return len(data) >= 12 && string(data[0:4]) == "RIFF" && string(data[8:12]) == "WAVE"
+}
+
+func newWAVDecoder(data []byte) (*wavDecoder, error) {
+	var (
+		hasFormat  bool
+		format     uint16
+		channels   int
+		sampleRate int
+		blockAlign int
+		bits       int
+		samples    []byte
+		hasData    bool
+	)
+	for offset := 12; offset+8 <= len(data); {
+		chunkID := string(data[offset : offset+4])
+		chunkSize := int(binary.LittleEndian.Uint32(data[offset+4:]))
+		chunkStart := offset + 8
+		chunkEnd := min(chunkStart+chunkSize, len(data))
+		chunk := data[chunkStart:chunkEnd]
+
+		switch chunkID {
+		case "fmt ":
+			if len(chunk) < 16 {
+				return nil, fmt.Errorf("wav format chunk is too short")
+			}
+			hasFormat = true
+			format = binary.LittleEndian.Uint16(chunk[0:])
+			channels = int(binary.LittleEndian.Uint16(chunk[2:]))
+			sampleRate = int(binary.LittleEndian.Uint32(chunk[4:]))
+			blockAlign = int(binary.LittleEndian.Uint16(chunk[12:]))
+			bits = int(binary.LittleEndian.Uint16(chunk[14:]))
+			if format == wavFormatExtensible {
+				if len(chunk) < 26 {
+					return nil, fmt.Errorf("wav extensible format chunk is too short")
+				}
+				// The first two bytes of the sub-format GUID hold the
+				// actual format code.
+				format = binary.LittleEndian.Uint16(chunk[24:])
+			}
+		case "data":
+			hasData = true
+			samples = chunk
+		}
+
+		// Chunks are padded to an even size.
+		offset = chunkStart + chunkSize + chunkSize%2
+	}
+	if !hasFormat {
+		return nil, fmt.Errorf("wav file is missing a format chunk")
+	}
+	if !hasData {
+		return nil, fmt.Errorf("wav file is missing a data chunk")
+	}
+	if channels < 1 {
+		return nil, fmt.Errorf("wav file has no channels")
+	}
+	if sampleRate <= 0 {
+		return nil, fmt.Errorf("wav file has invalid sample rate %d", sampleRate)
+	}
+
+	var sampleFunc func(data []byte) float32
+	switch {
+	case format == wavFormatPCM && bits == 8:
+		sampleFunc = wavSampleU8
+	case format == wavFormatPCM && bits == 16:
+		sampleFunc = wavSampleS16
+	case format == wavFormatPCM && bits == 24:
+		sampleFunc = wavSampleS24
+	case format == wavFormatPCM && bits == 32:
+		sampleFunc = wavSampleS32
+	case format == wavFormatFloat && bits == 32:
+		sampleFunc = wavSampleF32
+	case format == wavFormatFloat && bits == 64:
+		sampleFunc = wavSampleF64
+	default:
+		return nil, fmt.Errorf("unsupported wav format %#04x with %d bits", format, bits)
+	}
+
+	sampleSize := bits / 8
+	if blockAlign < channels*sampleSize {
+		blockAlign = channels * sampleSize
+	}
+	return &wavDecoder{
+		samples:    samples,
+		sampleRate: sampleRate,
+		channels:   channels,
+		sampleSize: sampleSize,
+		blockAlign: blockAlign,
+		length:     len(samples) / blockAlign,
+		sampleFunc: sampleFunc,
+	}, nil
+}
+
+// wavDecoder decodes uncompressed PCM and IEEE float WAV data. Mono data
+// is played on both channels and channels beyond the second are ignored.
+type wavDecoder struct {
+	samples    []byte
+	sampleRate int
+	channels   int
+	sampleSize int
+	blockAlign int
+	length     int
+	position   int
+	sampleFunc func(data []byte) float32
+}
+
+func (d *wavDecoder) SampleRate() int {
+	return d.sampleRate
+}
+
+func (d *wavDecoder) Length() int {
+	return d.length
+}
+
+func (d *wavDecoder) Read(frames []MediaFrame) (int, error) {
+	count := min(len(frames), d.length-d.position)
+	rightOffset := 0
+	if d.channels > 1 {
+		rightOffset = d.sampleSize
+	}
+	for i := range count {
+		block := d.samples[(d.position+i)*d.blockAlign:]
+		frames[i] = MediaFrame{
+			Left:  d.sampleFunc(block),
+			Right: d.sampleFunc(block[rightOffset:]),
+		}
+	}
+	d.position += count
+	if d.position >= d.length {
+		return count, io.EOF
+	}
+	return count, nil
+}
+
+func (d *wavDecoder) Seek(frame int) error {
+	d.position = max(min(frame, d.length), 0)
+	return nil
+}
+
+func wavSampleU8(data []byte) float32 {
+	return float32(int(data[0])-128) / 128.0
+}
+
+func wavSampleS16(data []byte) float32 {
+	return int16ToFloat32(int16(binary.LittleEndian.Uint16(data)))
+}
+
+func wavSampleS24(data []byte) float32 {
+	value := int32(data[0])<<8 | int32(data[1])<<16 | int32(data[2])<<24
+	return float32(value>>8) / float32(1<<23)
+}
+
+func wavSampleS32(data []byte) float32 {
+	return float32(float64(int32(binary.LittleEndian.Uint32(data))) / float64(1<<31))
+}
+
+func wavSampleF32(data []byte) float32 {
+	return math.Float32frombits(binary.LittleEndian.Uint32(data))
+}
+
+func wavSampleF64(data []byte) float32 {
+	return float32(math.Float64frombits(binary.LittleEndian.Uint64(data)))
+}
+
+// WriteWAV writes the frames to the writer as a WAV file, using the sample
+// rate, the channel count and the sample format of the specified config.
+// Each call dithers with a fresh ditherSource, which keeps the output
+// deterministic.
+func WriteWAV(w io.Writer, frames []MediaFrame, config DeviceConfig) error {
+	config = config.withDefaults()
+	sampleSize := config.Format.size()
+	blockAlign := config.Channels * sampleSize
+	dataSize := len(frames) * blockAlign
+
+	format := wavFormatPCM
+	formatSize := 16
+	if config.Format == SampleFormatF32 {
+		// Non-PCM formats have an extension size field and a fact chunk.
+		format = wavFormatFloat
+		formatSize = 18
+	}
+	riffSize := 4 + (8 + formatSize) + (8 + dataSize)
+	if format == wavFormatFloat {
+		riffSize += 8 + 4
+	}
+	if uint64(riffSize) > math.MaxUint32 {
+		return fmt.Errorf("wav data is too large")
+	}
+
+	header := make([]byte, 0, riffSize-dataSize+8)
+	header = append(header, "RIFF"...)
+	header = binary.LittleEndian.AppendUint32(header, uint32(riffSize))
+	header = append(header, "WAVE"...)
+	header = append(header, "fmt "...)
+	header = binary.LittleEndian.AppendUint32(header, uint32(formatSize))
+	header = binary.LittleEndian.AppendUint16(header, uint16(format))
+	header = binary.LittleEndian.AppendUint16(header, uint16(config.Channels))
+	header = binary.LittleEndian.AppendUint32(header, uint32(config.SampleRate))
+	header = binary.LittleEndian.AppendUint32(header, uint32(config.SampleRate*blockAlign))
+	header = binary.LittleEndian.AppendUint16(header, uint16(blockAlign))
+	header = binary.LittleEndian.AppendUint16(header, uint16(sampleSize*8))
+	if format == wavFormatFloat {
+		header = binary.LittleEndian.AppendUint16(header, 0)
+		header = append(header, "fact"...)
+		header = binary.LittleEndian.AppendUint32(header, 4)
+		header = binary.LittleEndian.AppendUint32(header, uint32(len(frames)))
+	}
+	header = append(header, "data"...)
+	header = binary.LittleEndian.AppendUint32(header, uint32(dataSize))
+
+	data := make(gblob.LittleEndianBlock, dataSize)
+	dither := newDitherSource()
+	for i, frame := range frames {
+		writeFrame(data, i, frame, config, &dither)
+	}
+
+	if _, err := w.Write(header); err != nil {
+		return fmt.Errorf("error writing wav header: %w", err)
+	}
+	if _, err := w.Write(data); err != nil {
+		return fmt.Errorf("error writing wav data: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+package audio
+
+import (
+	"io"
+	"time"
+
+	"github.com/mokiat/lacking-native/audio/internal"
+)
+
+// NewOfflineAPI creates an API that is not connected to an audio device.
+// It mixes audio with the same mixer as a device-backed API but only on
+// demand, against a virtual clock that is moved by Advance. This allows
+// audio logic to be tested without a device and audio to be rendered in
+// perfect sync with recorded video.
+//
+// The device ID of the config is ignored. The sample rate, channel count
+// and sample format are used for WAV output and the period size specifies
+// the number of frames that are mixed at once.
+func NewOfflineAPI(config DeviceConfig) *OfflineAPI {
+	return &OfflineAPI{
+		API: &API{
+			player: internal.NewOfflinePlayer(config),
+		},
+	}
+}
+
+// OfflineAPI is an API that renders audio on demand instead of playing it
+// through a device. All methods of API are available.
+type OfflineAPI struct {
+	*API
+}
+
+// Time returns the virtual clock, which is the total duration by which the
+// API has been advanced.
+func (a *OfflineAPI) Time() time.Duration {
+	return a.player.Time()
+}
+
+// Advance moves the virtual clock forward by the specified duration and
+// returns the mixed audio of that period. Changes that are made between
+// calls, including new playbacks, take effect at the start of the next
+// call.
+//
+// The output lags behind the virtual clock by the look-ahead of the
+// limiter, which is 5 ms, exactly as it would on a device.
+func (a *OfflineAPI) Advance(duration time.Duration) []MediaFrame {
+	return a.player.Advance(duration)
+}
+
+// AdvanceWAV moves the virtual clock forward like Advance and writes the
+// mixed audio to the writer as a WAV file.
+func (a *OfflineAPI) AdvanceWAV(w io.Writer, duration time.Duration) error {
+	return WriteWAV(w, a.Advance(duration), a.DeviceConfig())
+}
+
+// WriteWAV writes the frames to the writer as a WAV file, using the sample
+// rate, the channel count and the sample format of the specified config.
+// It can be used to save audio that was collected over multiple calls to
+// Advance.
+//
+// The dither of 16-bit samples always starts from the same seed, so the
+// same frames produce identical files.
+func WriteWAV(w io.Writer, frames []MediaFrame, config DeviceConfig) error {
+	return internal.WriteWAV(w, frames, config)
+}
@@ -0,0 +1,48 @@
+package audio
+
+import (
+	"time"
+
+	"github.com/mokiat/lacking-native/audio/internal"
+	"github.com/mokiat/lacking/audio"
+)
+
+// Playback is the playback that is returned by the play methods of API. It
+// allows the gain, pan and looping of a playback to be changed while it is
+// playing, as well as pausing, resuming, seeking and fading.
+type Playback = internal.Playback
+
+// PlaybackState specifies whether a Playback is playing, paused or
+// stopped.
+type PlaybackState = internal.PlaybackState
+
+const (
+	PlaybackStatePlaying = internal.PlaybackStatePlaying
+	PlaybackStatePaused  = internal.PlaybackStatePaused
+	PlaybackStateStopped = internal.PlaybackStateStopped
+)
+
+// FadeCurve specifies the shape of a fade.
+type FadeCurve = internal.FadeCurve
+
+const (
+	FadeCurveLinear      = internal.FadeCurveLinear
+	FadeCurveExponential = internal.FadeCurveExponential
+	FadeCurveSCurve      = internal.FadeCurveSCurve
+	FadeCurveEqualPower  = internal.FadeCurveEqualPower
+)
+
+// PlayWithFadeIn starts a playback of the specified media that routes to
+// the specified bus and fades in from silence over the specified duration.
+// A nil bus routes to the master bus.
+func (a *API) PlayWithFadeIn(bus *Bus, media audio.Media, info audio.PlayInfo, duration time.Duration, curve FadeCurve) audio.Playback {
+	return a.player.PlayWithFadeIn(bus, media, info, duration, curve)
+}
+
+// Crossfade starts a playback of the specified media on the bus of the
+// from playback and performs an equal-power crossfade between the two over
+// the specified duration, after which the from playback is stopped. This
+// is suitable for switching between music tracks.
+func (a *API) Crossfade(from *Playback, media audio.Media, info audio.PlayInfo, duration time.Duration) audio.Playback {
+	return a.player.Crossfade(from, media, info, duration)
+}
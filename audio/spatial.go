@@ -0,0 +1,32 @@
+package audio
+
+import "github.com/mokiat/lacking-native/audio/internal"
+
+// AttenuationModel specifies how the volume of an Emitter decreases with
+// the distance to the Listener.
+type AttenuationModel = internal.AttenuationModel
+
+const (
+	AttenuationInverse     = internal.AttenuationInverse
+	AttenuationLinear      = internal.AttenuationLinear
+	AttenuationExponential = internal.AttenuationExponential
+)
+
+// Emitter positions a Playback in 3D space. It is applied with
+// Playback.SetEmitter.
+type Emitter = internal.Emitter
+
+// DefaultEmitter returns an omnidirectional Emitter at the origin that uses
+// inverse distance attenuation.
+func DefaultEmitter() Emitter {
+	return internal.DefaultEmitter()
+}
+
+// Listener is the point in 3D space from which emitters are heard. It is
+// usually updated from the camera transform every frame.
+type Listener = internal.Listener
+
+// Listener returns the listener of the API.
+func (a *API) Listener() *Listener {
+	return a.player.Listener()
+}
@@ -1,20 +1,24 @@
 module github.com/mokiat/lacking-native
 
-go 1.23
+go 1.24.0
 
 require (
 	github.com/gen2brain/malgo v0.11.22
 	github.com/go-gl/gl v0.0.0-20231021071112-07e5d0ea2e71
 	github.com/go-gl/glfw/v3.3/glfw v0.0.0-20240506104042-037f3cc74f2a
 	github.com/hajimehoshi/go-mp3 v0.3.4
+	github.com/jfreymuth/oggvorbis v1.0.5
 	github.com/mokiat/gblob v0.3.0
 	github.com/mokiat/gog v0.13.1
 	github.com/mokiat/gomath v0.9.0
 	github.com/mokiat/lacking v0.21.0
+	github.com/pion/opus v0.1.0
+	github.com/veandco/go-sdl2 v0.4.40
 	golang.org/x/image v0.21.0
 )
 
 require (
+	github.com/jfreymuth/vorbis v1.0.2 // indirect
 	github.com/x448/float16 v0.8.4 // indirect
 	golang.org/x/exp v0.0.0-20241004190924-225e2abe05e6 // indirect
 	golang.org/x/text v0.19.0 // indirect
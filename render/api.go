@@ -102,3 +102,18 @@ func (a *API) CreateCommandBuffer(initialCapacity uint) render.CommandBuffer {
 func (a *API) Queue() render.Queue {
 	return a.queue
 }
+
+// SetCurrentContext notifies the API that the OpenGL context with the
+// specified index has been made current on the render thread. All
+// contexts need to share objects with the primary one, which has index
+// zero.
+func (a *API) SetCurrentContext(index int) {
+	internal.SetCurrentContext(index)
+	a.queue.Invalidate()
+}
+
+// ReleaseContext notifies the API that the OpenGL context with the
+// specified index is about to be destroyed.
+func (a *API) ReleaseContext(index int) {
+	internal.ReleaseContext(index)
+}
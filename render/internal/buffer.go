@@ -35,6 +35,7 @@ func NewPixelTransferBuffer(info render.BufferInfo) render.Buffer {
 		kind:  gl.PIXEL_PACK_BUFFER,
 	}
 	buffers.Track(id, result)
+	labelObject(gl.BUFFER, id, info.Label)
 	return result
 }
 
@@ -61,6 +62,7 @@ func newBuffer(info render.BufferInfo, kind uint32) *Buffer {
 		kind:  kind,
 	}
 	buffers.Track(id, result)
+	labelObject(gl.BUFFER, id, info.Label)
 	return result
 }
 
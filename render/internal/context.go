@@ -0,0 +1,88 @@
+package internal
+
+import "github.com/go-gl/gl/v4.1-core/gl"
+
+// PrimaryContext is the index of the OpenGL context that was current when
+// the render API was created.
+const PrimaryContext = 0
+
+// currentContext holds the index of the OpenGL context that is current
+// on the render thread.
+//
+// Container objects (framebuffers and vertex arrays) are not shared
+// between OpenGL contexts, even when these are part of the same share
+// group. Such objects are created on the primary context and are lazily
+// recreated on secondary contexts when first used there.
+var currentContext = PrimaryContext
+
+// pendingReleases holds the container objects that were released while
+// the OpenGL context that owns them was not current. These are deleted
+// once the context is made current again.
+var pendingReleases = make(map[int]*contextObjects)
+
+type contextObjects struct {
+	framebuffers []uint32
+	vertexArrays []uint32
+}
+
+// SetCurrentContext records that the OpenGL context with the specified
+// index has been made current. Container objects that were released while
+// the context was not current are deleted at this point.
+func SetCurrentContext(index int) {
+	currentContext = index
+	if pending, ok := pendingReleases[index]; ok {
+		delete(pendingReleases, index)
+		if len(pending.framebuffers) > 0 {
+			gl.DeleteFramebuffers(int32(len(pending.framebuffers)), &pending.framebuffers[0])
+		}
+		if len(pending.vertexArrays) > 0 {
+			gl.DeleteVertexArrays(int32(len(pending.vertexArrays)), &pending.vertexArrays[0])
+		}
+	}
+}
+
+// ReleaseContext forgets all container objects that were created for the
+// OpenGL context with the specified index. The objects themselves are
+// destroyed together with the context.
+func ReleaseContext(index int) {
+	for _, framebuffer := range framebuffers.mapping {
+		delete(framebuffer.contextIDs, index)
+	}
+	for _, vertexArray := range vertexArrays.mapping {
+		delete(vertexArray.contextIDs, index)
+	}
+	delete(pendingReleases, index)
+}
+
+// releaseFramebuffer deletes the framebuffer object that belongs to the
+// OpenGL context with the specified index, deferring the deletion if
+// that context is not current.
+func releaseFramebuffer(context int, id uint32) {
+	if context == currentContext {
+		gl.DeleteFramebuffers(1, &id)
+		return
+	}
+	pending := pendingContextObjects(context)
+	pending.framebuffers = append(pending.framebuffers, id)
+}
+
+// releaseVertexArray deletes the vertex array object that belongs to the
+// OpenGL context with the specified index, deferring the deletion if
+// that context is not current.
+func releaseVertexArray(context int, id uint32) {
+	if context == currentContext {
+		gl.DeleteVertexArrays(1, &id)
+		return
+	}
+	pending := pendingContextObjects(context)
+	pending.vertexArrays = append(pending.vertexArrays, id)
+}
+
+func pendingContextObjects(context int) *contextObjects {
+	pending, ok := pendingReleases[context]
+	if !ok {
+		pending = &contextObjects{}
+		pendingReleases[context] = pending
+	}
+	return pending
+}
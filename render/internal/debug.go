@@ -0,0 +1,29 @@
+package internal
+
+import "github.com/go-gl/gl/v4.1-core/gl"
+
+// labelObject attaches the specified label to an OpenGL object, so that
+// debug messages produced by the driver can refer to it by name.
+func labelObject(identifier, id uint32, label string) {
+	if !glLogger.IsDebugEnabled() || label == "" {
+		return
+	}
+	gl.ObjectLabel(identifier, id, int32(len(label)), StrPtr(label))
+}
+
+// pushDebugGroup starts a named debug group. Debug messages that are
+// produced until the matching popDebugGroup call are attributed to it.
+func pushDebugGroup(name string) {
+	if !glLogger.IsDebugEnabled() {
+		return
+	}
+	gl.PushDebugGroup(gl.DEBUG_SOURCE_APPLICATION, 0, int32(len(name)), StrPtr(name))
+}
+
+// popDebugGroup ends the most recent debug group.
+func popDebugGroup() {
+	if !glLogger.IsDebugEnabled() {
+		return
+	}
+	gl.PopDebugGroup()
+}
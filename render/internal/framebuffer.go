@@ -13,63 +13,7 @@ func NewFramebuffer(info render.FramebufferInfo) *Framebuffer {
 	var id uint32
 	gl.GenFramebuffers(1, &id)
 	gl.BindFramebuffer(gl.FRAMEBUFFER, id)
-
-	var activeDrawBuffers [4]bool
-	var drawBuffers []uint32
-	for i, colorAttachment := range info.ColorAttachments {
-		if !colorAttachment.Specified {
-			continue
-		}
-		attachment := colorAttachment.Value
-		texture := attachment.Texture.(*Texture)
-		attachmentID := gl.COLOR_ATTACHMENT0 + uint32(i)
-		switch texture.kind {
-		case gl.TEXTURE_2D_ARRAY:
-			gl.FramebufferTextureLayer(gl.FRAMEBUFFER, attachmentID, texture.id, int32(attachment.MipmapLayer), int32(attachment.Depth))
-		default:
-			gl.FramebufferTexture2D(gl.FRAMEBUFFER, attachmentID, gl.TEXTURE_2D, texture.id, int32(attachment.MipmapLayer))
-		}
-		drawBuffers = append(drawBuffers, attachmentID)
-		activeDrawBuffers[i] = true
-	}
-
-	if info.DepthStencilAttachment.Specified {
-		attachment := info.DepthStencilAttachment.Value
-		texture := attachment.Texture.(*Texture)
-		switch texture.kind {
-		case gl.TEXTURE_2D_ARRAY:
-			gl.FramebufferTextureLayer(gl.FRAMEBUFFER, gl.DEPTH_STENCIL_ATTACHMENT, texture.id, int32(attachment.MipmapLayer), int32(attachment.Depth))
-		default:
-			gl.FramebufferTexture2D(gl.FRAMEBUFFER, gl.DEPTH_STENCIL_ATTACHMENT, gl.TEXTURE_2D, texture.id, int32(attachment.MipmapLayer))
-		}
-	} else {
-		if info.DepthAttachment.Specified {
-			attachment := info.DepthAttachment.Value
-			texture := attachment.Texture.(*Texture)
-			switch texture.kind {
-			case gl.TEXTURE_2D_ARRAY:
-				gl.FramebufferTextureLayer(gl.FRAMEBUFFER, gl.DEPTH_ATTACHMENT, texture.id, int32(attachment.MipmapLayer), int32(attachment.Depth))
-			default:
-				gl.FramebufferTexture2D(gl.FRAMEBUFFER, gl.DEPTH_ATTACHMENT, gl.TEXTURE_2D, texture.id, int32(attachment.MipmapLayer))
-			}
-		}
-		if info.StencilAttachment.Specified {
-			attachment := info.StencilAttachment.Value
-			texture := attachment.Texture.(*Texture)
-			switch texture.kind {
-			case gl.TEXTURE_2D_ARRAY:
-				gl.FramebufferTextureLayer(gl.FRAMEBUFFER, gl.STENCIL_ATTACHMENT, texture.id, int32(attachment.MipmapLayer), int32(attachment.Depth))
-			default:
-				gl.FramebufferTexture2D(gl.FRAMEBUFFER, gl.STENCIL_ATTACHMENT, gl.TEXTURE_2D, texture.id, int32(attachment.MipmapLayer))
-			}
-		}
-	}
-
-	if len(drawBuffers) > 0 {
-		gl.DrawBuffers(int32(len(drawBuffers)), &drawBuffers[0])
-	} else {
-		gl.DrawBuffers(int32(len(drawBuffers)), nil)
-	}
+	activeDrawBuffers := attachFramebufferTextures(info)
 
 	status := gl.CheckFramebufferStatus(gl.FRAMEBUFFER)
 	if status != gl.FRAMEBUFFER_COMPLETE {
@@ -77,11 +21,14 @@ func NewFramebuffer(info render.FramebufferInfo) *Framebuffer {
 	}
 
 	result := &Framebuffer{
+		info:              info,
 		label:             info.Label,
 		id:                id,
+		contextIDs:        make(map[int]uint32),
 		activeDrawBuffers: activeDrawBuffers,
 	}
 	framebuffers.Track(result.id, result)
+	labelObject(gl.FRAMEBUFFER, result.id, info.Label)
 	return result
 }
 
@@ -98,8 +45,10 @@ var DefaultFramebuffer = func() *Framebuffer {
 type Framebuffer struct {
 	render.FramebufferMarker
 
+	info              render.FramebufferInfo
 	label             string
 	id                uint32
+	contextIDs        map[int]uint32
 	activeDrawBuffers [4]bool
 }
 
@@ -107,16 +56,37 @@ func (f *Framebuffer) Label() string {
 	return f.label
 }
 
+// contextID returns the framebuffer object that represents this
+// Framebuffer in the current OpenGL context.
+func (f *Framebuffer) contextID() uint32 {
+	if currentContext == PrimaryContext || f.id == 0 {
+		return f.id
+	}
+	if id, ok := f.contextIDs[currentContext]; ok {
+		return id
+	}
+	var id uint32
+	gl.GenFramebuffers(1, &id)
+	gl.BindFramebuffer(gl.FRAMEBUFFER, id)
+	attachFramebufferTextures(f.info)
+	f.contextIDs[currentContext] = id
+	return id
+}
+
 func (f *Framebuffer) Release() {
 	framebuffers.Release(f.id)
-	gl.DeleteFramebuffers(1, &f.id)
+	releaseFramebuffer(PrimaryContext, f.id)
+	for context, id := range f.contextIDs {
+		releaseFramebuffer(context, id)
+	}
+	clear(f.contextIDs)
 	f.id = 0
 	f.activeDrawBuffers = [4]bool{}
 }
 
 func DetermineContentFormat(framebuffer render.Framebuffer) render.DataFormat {
 	fb := framebuffer.(*Framebuffer)
-	gl.BindFramebuffer(gl.FRAMEBUFFER, fb.id)
+	gl.BindFramebuffer(gl.FRAMEBUFFER, fb.contextID())
 	defer gl.BindFramebuffer(gl.FRAMEBUFFER, 0)
 
 	var glFormat int32
@@ -143,3 +113,65 @@ func DetermineContentFormat(framebuffer render.Framebuffer) render.DataFormat {
 		return render.DataFormatUnsupported
 	}
 }
+
+// attachFramebufferTextures configures the attachments of the currently
+// bound framebuffer and returns which draw buffers are active.
+func attachFramebufferTextures(info render.FramebufferInfo) [4]bool {
+	var activeDrawBuffers [4]bool
+	var drawBuffers []uint32
+	for i, colorAttachment := range info.ColorAttachments {
+		if !colorAttachment.Specified {
+			continue
+		}
+		attachment := colorAttachment.Value
+		texture := attachment.Texture.(*Texture)
+		attachmentID := gl.COLOR_ATTACHMENT0 + uint32(i)
+		switch texture.kind {
+		case gl.TEXTURE_2D_ARRAY:
+			gl.FramebufferTextureLayer(gl.FRAMEBUFFER, attachmentID, texture.id, int32(attachment.MipmapLayer), int32(attachment.Depth))
+		default:
+			gl.FramebufferTexture2D(gl.FRAMEBUFFER, attachmentID, gl.TEXTURE_2D, texture.id, int32(attachment.MipmapLayer))
+		}
+		drawBuffers = append(drawBuffers, attachmentID)
+		activeDrawBuffers[i] = true
+	}
+
+	if info.DepthStencilAttachment.Specified {
+		attachment := info.DepthStencilAttachment.Value
+		texture := attachment.Texture.(*Texture)
+		switch texture.kind {
+		case gl.TEXTURE_2D_ARRAY:
+			gl.FramebufferTextureLayer(gl.FRAMEBUFFER, gl.DEPTH_STENCIL_ATTACHMENT, texture.id, int32(attachment.MipmapLayer), int32(attachment.Depth))
+		default:
+			gl.FramebufferTexture2D(gl.FRAMEBUFFER, gl.DEPTH_STENCIL_ATTACHMENT, gl.TEXTURE_2D, texture.id, int32(attachment.MipmapLayer))
+		}
+	} else {
+		if info.DepthAttachment.Specified {
+			attachment := info.DepthAttachment.Value
+			texture := attachment.Texture.(*Texture)
+			switch texture.kind {
+			case gl.TEXTURE_2D_ARRAY:
+				gl.FramebufferTextureLayer(gl.FRAMEBUFFER, gl.DEPTH_ATTACHMENT, texture.id, int32(attachment.MipmapLayer), int32(attachment.Depth))
+			default:
+				gl.FramebufferTexture2D(gl.FRAMEBUFFER, gl.DEPTH_ATTACHMENT, gl.TEXTURE_2D, texture.id, int32(attachment.MipmapLayer))
+			}
+		}
+		if info.StencilAttachment.Specified {
+			attachment := info.StencilAttachment.Value
+			texture := attachment.Texture.(*Texture)
+			switch texture.kind {
+			case gl.TEXTURE_2D_ARRAY:
+				gl.FramebufferTextureLayer(gl.FRAMEBUFFER, gl.STENCIL_ATTACHMENT, texture.id, int32(attachment.MipmapLayer), int32(attachment.Depth))
+			default:
+				gl.FramebufferTexture2D(gl.FRAMEBUFFER, gl.STENCIL_ATTACHMENT, gl.TEXTURE_2D, texture.id, int32(attachment.MipmapLayer))
+			}
+		}
+	}
+
+	if len(drawBuffers) > 0 {
+		gl.DrawBuffers(int32(len(drawBuffers)), &drawBuffers[0])
+	} else {
+		gl.DrawBuffers(int32(len(drawBuffers)), nil)
+	}
+	return activeDrawBuffers
+}
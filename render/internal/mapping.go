@@ -27,4 +27,5 @@ var (
 	textures     = newMapper[*Texture]()
 	samplers     = newMapper[*Sampler]()
 	buffers      = newMapper[*Buffer]()
+	vertexArrays = newMapper[*VertexArray]()
 )
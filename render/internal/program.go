@@ -32,6 +32,7 @@ func NewProgram(info ProgramInfo) *Program {
 		label: info.Label,
 		id:    gl.CreateProgram(),
 	}
+	labelObject(gl.PROGRAM, program.id, info.Label)
 
 	gl.AttachShader(program.id, vertexShader.id)
 	defer gl.DetachShader(program.id, vertexShader.id)
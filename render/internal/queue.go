@@ -200,8 +200,9 @@ func (q *Queue) executeCommandCopyFramebufferToTexture(command CommandCopyFrameb
 
 func (q *Queue) executeCommandBeginRenderPass(command CommandBeginRenderPass) {
 	intFramebuffer := framebuffers.Get(command.FramebufferID)
+	pushDebugGroup("render pass " + intFramebuffer.label)
 
-	gl.BindFramebuffer(gl.FRAMEBUFFER, intFramebuffer.id)
+	gl.BindFramebuffer(gl.FRAMEBUFFER, intFramebuffer.contextID())
 	gl.Viewport(
 		command.ViewportX,
 		command.ViewportY,
@@ -268,8 +269,9 @@ func (q *Queue) executeCommandBeginRenderPass(command CommandBeginRenderPass) {
 }
 
 func (q *Queue) executeCommandEndRenderPass(command CommandEndRenderPass) {
-	// Nothing to do here currently. Newer OpenGL versions can invalid the
-	// framebuffer attachments, but we don't support that yet.
+	// Newer OpenGL versions can invalid the framebuffer attachments,
+	// but we don't support that yet.
+	popDebugGroup()
 }
 
 func (q *Queue) executeCommandSetViewport(command CommandSetViewport) {
@@ -583,7 +585,11 @@ func (q *Queue) executeCommandBlendFunc(command CommandBlendFunc) {
 func (q *Queue) executeCommandBindVertexArray(command CommandBindVertexArray) {
 	// NOTE: We don't cache the array since there is a risk that during creation
 	// the array has been changed without the queue knowing about it.
-	gl.BindVertexArray(command.VertexArrayID)
+	if vertexArray := vertexArrays.Get(command.VertexArrayID); vertexArray != nil {
+		gl.BindVertexArray(vertexArray.contextID())
+	} else {
+		gl.BindVertexArray(0)
+	}
 	q.currentIndexType = opt.V(command.IndexFormat)
 }
 
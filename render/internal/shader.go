@@ -12,6 +12,7 @@ func newVertexShader(programLabel, sourceCode string) *Shader {
 	shader := &Shader{
 		id: gl.CreateShader(gl.VERTEX_SHADER),
 	}
+	labelObject(gl.SHADER, shader.id, programLabel+" (vertex)")
 	shader.setSourceCode(sourceCode)
 	if err := shader.compile(); err != nil {
 		logger.Error("Vertex shader (%v) compilation error: %v", programLabel, err)
@@ -23,6 +24,7 @@ func newFragmentShader(programLabel, sourceCode string) *Shader {
 	shader := &Shader{
 		id: gl.CreateShader(gl.FRAGMENT_SHADER),
 	}
+	labelObject(gl.SHADER, shader.id, programLabel+" (fragment)")
 	shader.setSourceCode(sourceCode)
 	if err := shader.compile(); err != nil {
 		logger.Error("Fragment shader (%v) compilation error: %v", programLabel, err)
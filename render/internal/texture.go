@@ -49,6 +49,7 @@ func NewColorTexture2D(info render.ColorTexture2DInfo) *Texture {
 		height: height,
 	}
 	textures.Track(id, result)
+	labelObject(gl.TEXTURE, id, info.Label)
 	return result
 }
 
@@ -79,6 +80,7 @@ func NewDepthTexture2D(info render.DepthTexture2DInfo) *Texture {
 		height: info.Height,
 	}
 	textures.Track(id, result)
+	labelObject(gl.TEXTURE, id, info.Label)
 	return result
 }
 
@@ -110,6 +112,7 @@ func NewDepthTexture2DArray(info render.DepthTexture2DArrayInfo) *Texture {
 		depth:  info.Layers,
 	}
 	textures.Track(id, result)
+	labelObject(gl.TEXTURE, id, info.Label)
 	return result
 }
 
@@ -135,6 +138,7 @@ func NewStencilTexture2D(info render.StencilTexture2DInfo) *Texture {
 		height: info.Height,
 	}
 	textures.Track(id, result)
+	labelObject(gl.TEXTURE, id, info.Label)
 	return result
 }
 
@@ -160,6 +164,7 @@ func NewDepthStencilTexture2D(info render.DepthStencilTexture2DInfo) *Texture {
 		height: info.Height,
 	}
 	textures.Track(id, result)
+	labelObject(gl.TEXTURE, id, info.Label)
 	return result
 }
 
@@ -223,6 +228,7 @@ func NewColorTextureCube(info render.ColorTextureCubeInfo) *Texture {
 		depth:  dimension,
 	}
 	textures.Track(id, result)
+	labelObject(gl.TEXTURE, id, info.Label)
 	return result
 }
 
@@ -282,6 +288,7 @@ func NewSampler(info render.SamplerInfo) *Sampler {
 		id:    id,
 	}
 	samplers.Track(result.id, result)
+	labelObject(gl.SAMPLER, result.id, info.Label)
 	return result
 }
 
@@ -15,37 +15,28 @@ func NewVertexArray(info render.VertexArrayInfo) *VertexArray {
 	var id uint32
 	gl.GenVertexArrays(1, &id)
 	gl.BindVertexArray(id)
-
-	for _, attribute := range info.Attributes {
-		binding := info.Bindings[attribute.Binding]
-		if vertexBuffer, ok := binding.VertexBuffer.(*Buffer); ok {
-			gl.BindBuffer(vertexBuffer.kind, vertexBuffer.id)
-		}
-		gl.EnableVertexAttribArray(uint32(attribute.Location))
-		count, compType, normalized, integer := glAttribParams(attribute.Format)
-		if integer {
-			gl.VertexAttribIPointerWithOffset(uint32(attribute.Location), count, compType, int32(binding.Stride), uintptr(attribute.Offset))
-		} else {
-			gl.VertexAttribPointerWithOffset(uint32(attribute.Location), count, compType, normalized, int32(binding.Stride), uintptr(attribute.Offset))
-		}
-	}
-	if indexBuffer, ok := info.IndexBuffer.(*Buffer); ok {
-		gl.BindBuffer(indexBuffer.kind, indexBuffer.id)
-	}
+	labelObject(gl.VERTEX_ARRAY, id, info.Label)
+	configureVertexArray(info)
 	gl.BindVertexArray(0)
 
-	return &VertexArray{
+	result := &VertexArray{
+		info:        info,
 		label:       info.Label,
 		id:          id,
+		contextIDs:  make(map[int]uint32),
 		indexFormat: glIndexFormat(info.IndexFormat),
 	}
+	vertexArrays.Track(id, result)
+	return result
 }
 
 type VertexArray struct {
 	render.VertexArrayMarker
 
+	info        render.VertexArrayInfo
 	label       string
 	id          uint32
+	contextIDs  map[int]uint32
 	indexFormat uint32
 }
 
@@ -53,11 +44,54 @@ func (a *VertexArray) Label() string {
 	return a.label
 }
 
+// contextID returns the vertex array object that represents this
+// VertexArray in the current OpenGL context.
+func (a *VertexArray) contextID() uint32 {
+	if currentContext == PrimaryContext {
+		return a.id
+	}
+	if id, ok := a.contextIDs[currentContext]; ok {
+		return id
+	}
+	var id uint32
+	gl.GenVertexArrays(1, &id)
+	gl.BindVertexArray(id)
+	configureVertexArray(a.info)
+	a.contextIDs[currentContext] = id
+	return id
+}
+
 func (a *VertexArray) Release() {
-	gl.DeleteVertexArrays(1, &a.id)
+	vertexArrays.Release(a.id)
+	releaseVertexArray(PrimaryContext, a.id)
+	for context, id := range a.contextIDs {
+		releaseVertexArray(context, id)
+	}
+	clear(a.contextIDs)
 	a.id = 0
 }
 
+// configureVertexArray sets up the attributes and index buffer of the
+// currently bound vertex array.
+func configureVertexArray(info render.VertexArrayInfo) {
+	for _, attribute := range info.Attributes {
+		binding := info.Bindings[attribute.Binding]
+		if vertexBuffer, ok := binding.VertexBuffer.(*Buffer); ok {
+			gl.BindBuffer(vertexBuffer.kind, vertexBuffer.id)
+		}
+		gl.EnableVertexAttribArray(uint32(attribute.Location))
+		count, compType, normalized, integer := glAttribParams(attribute.Format)
+		if integer {
+			gl.VertexAttribIPointerWithOffset(uint32(attribute.Location), count, compType, int32(binding.Stride), uintptr(attribute.Offset))
+		} else {
+			gl.VertexAttribPointerWithOffset(uint32(attribute.Location), count, compType, normalized, int32(binding.Stride), uintptr(attribute.Offset))
+		}
+	}
+	if indexBuffer, ok := info.IndexBuffer.(*Buffer); ok {
+		gl.BindBuffer(indexBuffer.kind, indexBuffer.id)
+	}
+}
+
 func glAttribParams(format render.VertexAttributeFormat) (int32, uint32, bool, bool) {
 	switch format {
 	case render.VertexAttributeFormatR32F: